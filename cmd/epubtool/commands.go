@@ -0,0 +1,789 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/weiweimhy/go-utils/epub"
+)
+
+func runInfo(args []string) error {
+	fs := newFlagSet("info", "<file|glob>...")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	files, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	return forEachFile(files, *asJSON, func(file string) (any, error) {
+		book, err := epub.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		info := book.Info()
+		if !*asJSON {
+			fmt.Printf("%s\n", file)
+			fmt.Printf("  Title:      %s\n", info.Title)
+			fmt.Printf("  Creators:   %s\n", strings.Join(info.Creators, ", "))
+			fmt.Printf("  Language:   %s\n", info.Language)
+			fmt.Printf("  Identifier: %s\n", info.Identifier)
+			fmt.Printf("  Version:    %s\n", info.Version)
+			fmt.Printf("  Chapters:   %d\n", info.Chapters)
+			fmt.Printf("  Files:      %d\n", info.Files)
+			if info.Protection != "" {
+				fmt.Printf("  Protection: %s\n", info.Protection)
+			}
+		}
+		return info, nil
+	})
+}
+
+// modifyOpenOptions 用于会写回文件的子命令，受 DRM 保护的书籍直接报错而不是写出损坏的文件
+var modifyOpenOptions = epub.OpenOptions{RejectProtected: true}
+
+func runStats(args []string) error {
+	fs := newFlagSet("stats", "<file|glob>...")
+	asJSON := fs.Bool("json", false, "print JSON")
+	chapters := fs.Bool("chapters", false, "also print per-chapter statistics")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	files, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	return forEachFile(files, *asJSON, func(file string) (any, error) {
+		book, err := epub.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		stats := book.Stats()
+		if !*asJSON {
+			fmt.Printf("%s\n", file)
+			fmt.Printf("  Words:        %d (CJK %d, Latin %d)\n", stats.Words, stats.CJKChars, stats.LatinWords)
+			fmt.Printf("  Reading time: %.0f min\n", stats.ReadingMinutes)
+			fmt.Printf("  Images:       %d (%d bytes)\n", stats.Images, stats.ImageBytes)
+			fmt.Printf("  Stylesheets:  %d (%d bytes)\n", stats.Stylesheets, stats.StylesheetBytes)
+			fmt.Printf("  Fonts:        %d (%d bytes)\n", stats.Fonts, stats.FontBytes)
+			fmt.Printf("  Size:         %d bytes (%d compressed)\n", stats.Size, stats.CompressedSize)
+			if *chapters {
+				for _, chapter := range stats.Chapters {
+					fmt.Printf("  %s\t%s\t%d words\t%.1f min\t%d images\n",
+						chapter.Name, chapter.Title, chapter.Words, chapter.ReadingMinutes, chapter.Images)
+				}
+			}
+		}
+		return stats, nil
+	})
+}
+
+func runLs(args []string) error {
+	fs := newFlagSet("ls", "<file|glob>...")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	files, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	return forEachFile(files, *asJSON, func(file string) (any, error) {
+		book, err := epub.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		entries := book.Files()
+		if !*asJSON {
+			if len(files) > 1 {
+				fmt.Printf("%s:\n", file)
+			}
+			for _, entry := range entries {
+				spine := " "
+				if entry.InSpine {
+					spine = "*"
+				}
+				fmt.Printf("%s %10d  %-28s %s\n", spine, entry.Size, entry.MediaType, entry.Name)
+			}
+		}
+		return entries, nil
+	})
+}
+
+func runGrep(args []string) error {
+	fs := newFlagSet("grep", "<text> <file|glob>...")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return fmt.Errorf("text and at least one file are required")
+	}
+	text := fs.Arg(0)
+	files, err := expandInputs(fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+
+	return forEachFile(files, *asJSON, func(file string) (any, error) {
+		book, err := epub.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		matches, err := book.FindHTMLByText(text)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		if !*asJSON {
+			for _, match := range matches {
+				fmt.Printf("%s:%s\n", file, match)
+			}
+		}
+		return matches, nil
+	})
+}
+
+func runReplace(args []string) error {
+	fs := newFlagSet("replace", "-old <text> -new <text> <file|glob>...")
+	asJSON := fs.Bool("json", false, "print JSON")
+	oldText := fs.String("old", "", "text to replace (required)")
+	newText := fs.String("new", "", "replacement text")
+	var out outputOptions
+	out.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *oldText == "" {
+		return fmt.Errorf("-old is required")
+	}
+	files, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+	if err := out.validate(len(files)); err != nil {
+		return err
+	}
+
+	return forEachFile(files, *asJSON, func(file string) (any, error) {
+		book, err := epub.OpenWithOptions(file, modifyOpenOptions)
+		if err != nil {
+			return nil, err
+		}
+		count, err := book.ReplaceAllHTML(*oldText, *newText)
+		if err != nil {
+			return nil, err
+		}
+		if err := out.save(book, file); err != nil {
+			return nil, err
+		}
+		if !*asJSON {
+			fmt.Printf("%s: %d replacements\n", file, count)
+		}
+		return map[string]int{"replacements": count}, nil
+	})
+}
+
+func runRmChapters(args []string) error {
+	fs := newFlagSet("rm-chapters", "-keyword <text> [-keyword <text>...] <file|glob>...")
+	asJSON := fs.Bool("json", false, "print JSON")
+	var keywords stringList
+	fs.Var(&keywords, "keyword", "remove chapters containing this text (repeatable)")
+	var out outputOptions
+	out.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(keywords) == 0 {
+		return fmt.Errorf("at least one -keyword is required")
+	}
+	files, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+	if err := out.validate(len(files)); err != nil {
+		return err
+	}
+
+	return forEachFile(files, *asJSON, func(file string) (any, error) {
+		book, err := epub.OpenWithOptions(file, modifyOpenOptions)
+		if err != nil {
+			return nil, err
+		}
+		removed, err := book.RemoveHTMLContaining(keywords)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(removed)
+		if err := out.save(book, file); err != nil {
+			return nil, err
+		}
+		if !*asJSON {
+			for _, name := range removed {
+				fmt.Printf("%s: removed %s\n", file, name)
+			}
+		}
+		return map[string]any{"removed": removed}, nil
+	})
+}
+
+func runClean(args []string) error {
+	fs := newFlagSet("clean", "-rules <rules.yaml> <file|glob>...")
+	asJSON := fs.Bool("json", false, "print JSON")
+	rulesPath := fs.String("rules", "", "YAML or JSON rule file (required)")
+	var out outputOptions
+	out.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *rulesPath == "" {
+		return fmt.Errorf("-rules is required")
+	}
+	rules, err := epub.LoadRules(*rulesPath)
+	if err != nil {
+		return err
+	}
+	files, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+	if err := out.validate(len(files)); err != nil {
+		return err
+	}
+
+	return forEachFile(files, *asJSON, func(file string) (any, error) {
+		book, err := epub.OpenWithOptions(file, modifyOpenOptions)
+		if err != nil {
+			return nil, err
+		}
+		report, err := book.ApplyRules(rules)
+		if err != nil {
+			return nil, err
+		}
+		if err := out.save(book, file); err != nil {
+			return nil, err
+		}
+		if !*asJSON {
+			for _, rule := range report.Rules {
+				fmt.Printf("%s: %s (%s) %d hits\n", file, rule.Name, rule.Action, rule.Hits)
+			}
+			for _, name := range report.RemovedChapters {
+				fmt.Printf("%s: removed %s\n", file, name)
+			}
+		}
+		return report, nil
+	})
+}
+
+func runTOC(args []string) error {
+	fs := newFlagSet("toc", "[-selector <css>...] [-pattern <regex>...] <file|glob>...")
+	asJSON := fs.Bool("json", false, "print JSON")
+	var selectors, patterns stringList
+	fs.Var(&selectors, "selector", "heading selector, the n-th flag is level n (repeatable)")
+	fs.Var(&patterns, "pattern", "heading text regex, the n-th flag is level n (repeatable)")
+	depth := fs.Int("depth", 0, "maximum TOC depth (0 = unlimited)")
+	var out outputOptions
+	out.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	files, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+	if err := out.validate(len(files)); err != nil {
+		return err
+	}
+
+	return forEachFile(files, *asJSON, func(file string) (any, error) {
+		book, err := epub.OpenWithOptions(file, modifyOpenOptions)
+		if err != nil {
+			return nil, err
+		}
+		toc, err := book.GenerateTOC(epub.TOCOptions{Selectors: selectors, Patterns: patterns, MaxDepth: *depth})
+		if err != nil {
+			return nil, err
+		}
+		if err := out.save(book, file); err != nil {
+			return nil, err
+		}
+		if !*asJSON {
+			printTOC(file, toc, 0)
+		}
+		return toc, nil
+	})
+}
+
+func printTOC(file string, entries []*epub.TOCEntry, depth int) {
+	for _, entry := range entries {
+		fmt.Printf("%s: %s%s -> %s\n", file, strings.Repeat("  ", depth), entry.Title, entry.Href)
+		printTOC(file, entry.Children, depth+1)
+	}
+}
+
+func runChinese(args []string) error {
+	fs := newFlagSet("zh", "-mode s2t|s2tw|s2twp|s2hk|t2s <file|glob>...")
+	asJSON := fs.Bool("json", false, "print JSON")
+	mode := fs.String("mode", "", "conversion: s2t, s2tw, s2twp, s2hk or t2s (required)")
+	language := fs.String("lang", "", "language tag to write (default depends on -mode)")
+	var out outputOptions
+	out.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *mode == "" {
+		return fmt.Errorf("-mode is required")
+	}
+	files, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+	if err := out.validate(len(files)); err != nil {
+		return err
+	}
+
+	return forEachFile(files, *asJSON, func(file string) (any, error) {
+		book, err := epub.OpenWithOptions(file, modifyOpenOptions)
+		if err != nil {
+			return nil, err
+		}
+		changed, err := book.ConvertChinese(epub.ChineseConvertOptions{Mode: *mode, Language: *language})
+		if err != nil {
+			return nil, err
+		}
+		if err := out.save(book, file); err != nil {
+			return nil, err
+		}
+		if !*asJSON {
+			fmt.Printf("%s: %d files converted -> %s\n", file, len(changed), book.Info().Title)
+		}
+		return changed, nil
+	})
+}
+
+func runLayout(args []string) error {
+	fs := newFlagSet("layout", "-mode vertical|horizontal [-direction rtl|ltr] <file|glob>...")
+	asJSON := fs.Bool("json", false, "print JSON")
+	mode := fs.String("mode", "", "vertical or horizontal (required)")
+	direction := fs.String("direction", "", "page progression direction (default: rtl for vertical, unset for horizontal)")
+	var out outputOptions
+	out.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var opts epub.LayoutOptions
+	switch *mode {
+	case "vertical":
+		opts.WritingMode = epub.WritingVerticalRL
+	case "horizontal":
+		opts.WritingMode = epub.WritingHorizontal
+	default:
+		return fmt.Errorf("-mode must be vertical or horizontal")
+	}
+	opts.PageProgressionDirection = *direction
+	files, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+	if err := out.validate(len(files)); err != nil {
+		return err
+	}
+
+	return forEachFile(files, *asJSON, func(file string) (any, error) {
+		book, err := epub.OpenWithOptions(file, modifyOpenOptions)
+		if err != nil {
+			return nil, err
+		}
+		if err := book.SetLayout(opts); err != nil {
+			return nil, err
+		}
+		if err := out.save(book, file); err != nil {
+			return nil, err
+		}
+		layout := book.Layout()
+		if !*asJSON {
+			fmt.Printf("%s: %s, page progression %q\n", file, layout.WritingMode, layout.PageProgressionDirection)
+		}
+		return layout, nil
+	})
+}
+
+func runAccessibility(args []string) error {
+	fs := newFlagSet("a11y", "[-mode <mode>...] [-feature <feature>...] [-hazard <hazard>...] [-summary <text>] <file|glob>...")
+	asJSON := fs.Bool("json", false, "print JSON")
+	var modes, sufficient, features, hazards stringList
+	fs.Var(&modes, "mode", "schema:accessMode, e.g. textual or visual (repeatable)")
+	fs.Var(&sufficient, "sufficient", "schema:accessModeSufficient, e.g. textual,visual (repeatable)")
+	fs.Var(&features, "feature", "schema:accessibilityFeature, e.g. structuralNavigation (repeatable)")
+	fs.Var(&hazards, "hazard", "schema:accessibilityHazard, e.g. none (repeatable)")
+	summary := fs.String("summary", "", "schema:accessibilitySummary")
+	conformsTo := fs.String("conforms-to", "", "dcterms:conformsTo (EPUB 3 only)")
+	certifiedBy := fs.String("certified-by", "", "a11y:certifiedBy (EPUB 3 only)")
+	var out outputOptions
+	out.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	files, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+	if err := out.validate(len(files)); err != nil {
+		return err
+	}
+
+	return forEachFile(files, *asJSON, func(file string) (any, error) {
+		book, err := epub.OpenWithOptions(file, modifyOpenOptions)
+		if err != nil {
+			return nil, err
+		}
+		// 只替换命令行给出的字段，其余沿用书中已有的元数据
+		meta := book.Accessibility()
+		if len(modes) > 0 {
+			meta.AccessModes = modes
+		}
+		if len(sufficient) > 0 {
+			meta.AccessModesSufficient = sufficient
+		}
+		if len(features) > 0 {
+			meta.Features = features
+		}
+		if len(hazards) > 0 {
+			meta.Hazards = hazards
+		}
+		if *summary != "" {
+			meta.Summary = *summary
+		}
+		if *conformsTo != "" {
+			meta.ConformsTo = *conformsTo
+		}
+		if *certifiedBy != "" {
+			meta.CertifiedBy = *certifiedBy
+		}
+		if err := book.SetAccessibility(meta); err != nil {
+			return nil, err
+		}
+		if err := out.save(book, file); err != nil {
+			return nil, err
+		}
+		if !*asJSON {
+			fmt.Printf("%s: modes=%s features=%s hazards=%s\n", file,
+				strings.Join(meta.AccessModes, ","), strings.Join(meta.Features, ","), strings.Join(meta.Hazards, ","))
+		}
+		return meta, nil
+	})
+}
+
+func runAccessibilityCheck(args []string) error {
+	fs := newFlagSet("a11y-check", "<file|glob>...")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	files, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	return forEachFile(files, *asJSON, func(file string) (any, error) {
+		book, err := epub.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		report, err := book.CheckAccessibility()
+		if err != nil {
+			return nil, err
+		}
+		if !*asJSON {
+			for _, issue := range report.Issues {
+				location := issue.File
+				if issue.Element != "" {
+					location += " " + issue.Element
+				}
+				fmt.Printf("%s: [%s] %s: %s\n", file, issue.Rule, location, issue.Message)
+			}
+			if report.Conformant {
+				fmt.Printf("%s: all accessibility checks passed\n", file)
+			} else {
+				fmt.Printf("%s: %d accessibility issues\n", file, len(report.Issues))
+			}
+		}
+		return report, nil
+	})
+}
+
+func runAddChapter(args []string) error {
+	fs := newFlagSet("add-chapter", "-path <zip path> -html <local file> <file|glob>...")
+	asJSON := fs.Bool("json", false, "print JSON")
+	chapterPath := fs.String("path", "", "chapter path inside the EPUB, e.g. OEBPS/Text/extra.xhtml (required)")
+	htmlFile := fs.String("html", "", "local HTML file to add (required)")
+	index := fs.Int("index", -1, "spine position to insert at, -1 appends")
+	var out outputOptions
+	out.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *chapterPath == "" || *htmlFile == "" {
+		return fmt.Errorf("-path and -html are required")
+	}
+	files, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+	if err := out.validate(len(files)); err != nil {
+		return err
+	}
+
+	return forEachFile(files, *asJSON, func(file string) (any, error) {
+		book, err := epub.OpenWithOptions(file, modifyOpenOptions)
+		if err != nil {
+			return nil, err
+		}
+		if err := book.AddChapterFromFile(*chapterPath, *htmlFile, *index); err != nil {
+			return nil, err
+		}
+		if err := out.save(book, file); err != nil {
+			return nil, err
+		}
+		if !*asJSON {
+			fmt.Printf("%s: added %s\n", file, *chapterPath)
+		}
+		return map[string]string{"added": *chapterPath}, nil
+	})
+}
+
+func runDiff(args []string) error {
+	fs := newFlagSet("diff", "<a.epub> <b.epub>")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("exactly two files are required")
+	}
+	a, err := epub.Open(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("%s: %w", fs.Arg(0), err)
+	}
+	b, err := epub.Open(fs.Arg(1))
+	if err != nil {
+		return fmt.Errorf("%s: %w", fs.Arg(1), err)
+	}
+	result, err := epub.Diff(a, b)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(result)
+	}
+	return result.WriteReport(os.Stdout)
+}
+
+func runImportTxt(args []string) error {
+	fs := newFlagSet("import-txt", "[-o <file.epub>] <file.txt|glob>...")
+	asJSON := fs.Bool("json", false, "print JSON")
+	output := fs.String("o", "", "output EPUB file (only with a single input); default is the input name with .epub")
+	title := fs.String("title", "", "book title (default: parsed from the file)")
+	author := fs.String("author", "", "book author (default: parsed from the file)")
+	encoding := fs.String("encoding", "", "source encoding (default: detected)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	files, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+	if *output != "" && len(files) != 1 {
+		return fmt.Errorf("-o can only be used with a single input")
+	}
+
+	return forEachFile(files, *asJSON, func(file string) (any, error) {
+		book, err := epub.ImportText(file, epub.TextImportOptions{Title: *title, Author: *author, Encoding: *encoding})
+		if err != nil {
+			return nil, err
+		}
+		target := *output
+		if target == "" {
+			target = strings.TrimSuffix(file, filepath.Ext(file)) + ".epub"
+		}
+		if err := book.Save(target); err != nil {
+			return nil, err
+		}
+		info := book.Info()
+		if !*asJSON {
+			fmt.Printf("%s: %s (%s), %d chapters -> %s\n", file, info.Title, strings.Join(info.Creators, ", "), info.Chapters, target)
+		}
+		return map[string]any{"output": target, "info": info}, nil
+	})
+}
+
+func runImportMarkdown(args []string) error {
+	fs := newFlagSet("import-md", "-o <file.epub> <dir|file.md>...")
+	asJSON := fs.Bool("json", false, "print JSON")
+	output := fs.String("o", "", "output EPUB file (required)")
+	title := fs.String("title", "", "book title (default: from front matter)")
+	author := fs.String("author", "", "book author (default: from front matter)")
+	cover := fs.String("cover", "", "cover image (default: from front matter)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output == "" || fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("-o and at least one input are required")
+	}
+
+	opts := epub.MarkdownImportOptions{Title: *title, Author: *author, Cover: *cover}
+	var book *epub.Epub
+	if st, err := os.Stat(fs.Arg(0)); err == nil && st.IsDir() && fs.NArg() == 1 {
+		book, err = epub.ImportMarkdownDir(fs.Arg(0), opts)
+		if err != nil {
+			return err
+		}
+	} else {
+		files, err := expandInputs(fs.Args())
+		if err != nil {
+			return err
+		}
+		if book, err = epub.ImportMarkdown(files, opts); err != nil {
+			return err
+		}
+	}
+	if err := book.Save(*output); err != nil {
+		return err
+	}
+	info := book.Info()
+	if *asJSON {
+		return printJSON(map[string]any{"output": *output, "info": info})
+	}
+	fmt.Printf("%s (%s), %d chapters -> %s\n", info.Title, strings.Join(info.Creators, ", "), info.Chapters, *output)
+	return nil
+}
+
+func runExportHTML(args []string) error {
+	fs := newFlagSet("export-html", "[-o <file.html>] [-external] [-no-sidebar] <file|glob>...")
+	asJSON := fs.Bool("json", false, "print JSON")
+	output := fs.String("o", "", "output HTML file (only with a single input); default is the input name with .html")
+	external := fs.Bool("external", false, "write images and fonts into a <name>_files directory instead of embedding them")
+	noSidebar := fs.Bool("no-sidebar", false, "do not add the table of contents sidebar")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	files, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+	if *output != "" && len(files) != 1 {
+		return fmt.Errorf("-o can only be used with a single input")
+	}
+
+	return forEachFile(files, *asJSON, func(file string) (any, error) {
+		// 加密的章节无法导出为可读的 HTML
+		book, err := epub.OpenWithOptions(file, modifyOpenOptions)
+		if err != nil {
+			return nil, err
+		}
+		target := *output
+		if target == "" {
+			target = strings.TrimSuffix(file, filepath.Ext(file)) + ".html"
+		}
+		if err := book.ExportHTML(target, epub.HTMLExportOptions{ExternalAssets: *external, NoSidebar: *noSidebar}); err != nil {
+			return nil, err
+		}
+		if !*asJSON {
+			fmt.Printf("%s: exported to %s\n", file, target)
+		}
+		return map[string]string{"output": target}, nil
+	})
+}
+
+func runExtract(args []string) error {
+	fs := newFlagSet("extract", "-d <dir> <file|glob>...")
+	asJSON := fs.Bool("json", false, "print JSON")
+	dir := fs.String("d", "", "target directory; with several inputs each book goes into a sub directory (required)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *dir == "" {
+		return fmt.Errorf("-d is required")
+	}
+	files, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	return forEachFile(files, *asJSON, func(file string) (any, error) {
+		target := *dir
+		if len(files) > 1 {
+			target = filepath.Join(*dir, strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)))
+		}
+		book, err := epub.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := book.Extract(target); err != nil {
+			return nil, err
+		}
+		count := len(book.Files())
+		if !*asJSON {
+			fmt.Printf("%s: extracted %d files to %s\n", file, count, target)
+		}
+		return map[string]any{"dir": target, "files": count}, nil
+	})
+}
+
+func runPack(args []string) error {
+	fs := newFlagSet("pack", "-o <file.epub> <dir>")
+	asJSON := fs.Bool("json", false, "print JSON")
+	output := fs.String("o", "", "output EPUB file (required)")
+	watch := fs.Bool("watch", false, "keep running and repack whenever the directory changes")
+	interval := fs.Duration("interval", time.Second, "polling interval for -watch")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output == "" || fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("-o and exactly one directory are required")
+	}
+	dir := fs.Arg(0)
+
+	if *watch {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return epub.WatchDir(ctx, dir, *output, epub.WatchOptions{
+			Interval: *interval,
+			OnBuild: func(err error) {
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%s: %v\n", dir, err)
+					return
+				}
+				fmt.Printf("%s: packed into %s\n", dir, *output)
+			},
+		})
+	}
+
+	book, err := epub.OpenDir(dir)
+	if err != nil {
+		return err
+	}
+	if err := book.Save(*output); err != nil {
+		return err
+	}
+	count := len(book.Files())
+	if *asJSON {
+		return printJSON(map[string]any{"dir": dir, "output": *output, "files": count})
+	}
+	fmt.Printf("%s: packed %d files into %s\n", dir, count, *output)
+	return nil
+}
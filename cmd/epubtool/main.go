@@ -0,0 +1,262 @@
+// epubtool 是 epub 包的命令行封装，方便不写 Go 代码直接查看与修改 EPUB
+//
+// 用法：
+//
+//	epubtool <command> [flags] <file|glob>...
+//
+// 所有命令都支持 -json 以输出机器可读的结果，文件参数支持通配符（如 "books/*.epub"）
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/weiweimhy/go-utils/epub"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"info", "show title, author and basic counts", runInfo},
+		{"stats", "show word counts, reading time and size breakdown", runStats},
+		{"ls", "list files inside the EPUB", runLs},
+		{"grep", "find chapters containing a text", runGrep},
+		{"replace", "replace a text in all chapters", runReplace},
+		{"rm-chapters", "remove chapters containing any keyword", runRmChapters},
+		{"clean", "apply a YAML/JSON cleanup rule file", runClean},
+		{"toc", "generate the table of contents from chapter headings", runTOC},
+		{"zh", "convert between Simplified and Traditional Chinese", runChinese},
+		{"layout", "switch between vertical (CJK) and horizontal layout", runLayout},
+		{"a11y", "set schema.org accessibility metadata", runAccessibility},
+		{"a11y-check", "check accessibility metadata, image alt text, lang, headings and tables", runAccessibilityCheck},
+		{"add-chapter", "add a chapter from a local HTML file", runAddChapter},
+		{"diff", "show structural and text differences between two EPUBs", runDiff},
+		{"import-txt", "convert a TXT novel into an EPUB", runImportTxt},
+		{"import-md", "build an EPUB from Markdown files or a directory", runImportMarkdown},
+		{"export-html", "export the book as a single HTML page", runExportHTML},
+		{"extract", "unpack an EPUB into a directory", runExtract},
+		{"pack", "pack a directory into an EPUB", runPack},
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	if name == "-h" || name == "--help" || name == "help" {
+		usage(os.Stdout)
+		return
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		if err := cmd.run(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "epubtool %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "epubtool: unknown command %q\n\n", name)
+	usage(os.Stderr)
+	os.Exit(2)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: epubtool <command> [flags] <file|glob>...")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "epubtool <command> -h" for the flags of a command.`)
+}
+
+// newFlagSet 创建子命令的 FlagSet，统一 usage 输出格式
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: epubtool %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// expandInputs 展开通配符并去重，未匹配到任何文件的模式视为错误
+func expandInputs(patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("no input files")
+	}
+	var files []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %q", pattern)
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				files = append(files, match)
+			}
+		}
+	}
+	return files, nil
+}
+
+// outputOptions 描述修改类命令的输出位置
+type outputOptions struct {
+	output  string
+	outDir  string
+	inPlace bool
+}
+
+func (o *outputOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.output, "o", "", "output file (only with a single input)")
+	fs.StringVar(&o.outDir, "outdir", "", "write results into this directory, keeping file names")
+	fs.BoolVar(&o.inPlace, "inplace", false, "overwrite the input files")
+}
+
+func (o *outputOptions) validate(inputs int) error {
+	set := 0
+	for _, on := range []bool{o.output != "", o.outDir != "", o.inPlace} {
+		if on {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("exactly one of -o, -outdir or -inplace is required")
+	}
+	if o.output != "" && inputs != 1 {
+		return fmt.Errorf("-o can only be used with a single input, use -outdir instead")
+	}
+	if o.outDir != "" {
+		if err := os.MkdirAll(o.outDir, os.ModePerm); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+	return nil
+}
+
+func (o *outputOptions) pathFor(input string) string {
+	switch {
+	case o.output != "":
+		return o.output
+	case o.outDir != "":
+		return filepath.Join(o.outDir, filepath.Base(input))
+	default:
+		return input
+	}
+}
+
+// save 保存 input 处理后的结果：先写入目标目录中的临时文件再重命名覆盖，
+// 保存失败时目标文件（-inplace 时即输入文件）保持原样
+func (o *outputOptions) save(book *epub.Epub, input string) error {
+	target := o.pathFor(input)
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	_ = tmp.Close()
+
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(target); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := book.Save(tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
+	if err := os.Rename(tmpPath, target); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace %s: %w", target, err)
+	}
+	return nil
+}
+
+// printJSON 以缩进格式输出 JSON
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(v)
+}
+
+// fileResult 是批量命令中单个文件的 JSON 结果
+type fileResult struct {
+	File  string `json:"file"`
+	Error string `json:"error,omitempty"`
+	Data  any    `json:"data,omitempty"`
+}
+
+// forEachFile 依次处理每个输入文件；单个文件失败不会中断其余文件，但最终会返回错误
+func forEachFile(files []string, asJSON bool, fn func(file string) (any, error)) error {
+	var results []fileResult
+	failed := 0
+	for _, file := range files {
+		data, err := fn(file)
+		result := fileResult{File: file, Data: data}
+		if err != nil {
+			failed++
+			result.Error = err.Error()
+			if !asJSON {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+			}
+		}
+		results = append(results, result)
+	}
+
+	if asJSON {
+		if err := printJSON(results); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed", failed, len(files))
+	}
+	return nil
+}
+
+// stringList 支持重复出现的字符串参数，如 -keyword a -keyword b
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
@@ -0,0 +1,299 @@
+package epub
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 无障碍检查的规则名
+const (
+	A11yRuleMetadata     = "metadata"      // 缺少无障碍元数据
+	A11yRuleImageAlt     = "img-alt"       // 图片缺少 alt 属性
+	A11yRuleLang         = "lang"          // 缺少语言声明
+	A11yRuleHeadingOrder = "heading-order" // 标题层级跳跃，如 h1 之后直接是 h3
+	A11yRuleTableHeader  = "table-header"  // 表格没有表头单元格
+)
+
+// AccessibilityMetadata 为 EPUB Accessibility 1.1 使用的 schema.org 无障碍元数据
+type AccessibilityMetadata struct {
+	// AccessModes 为 schema:accessMode，如 textual、visual
+	AccessModes []string `json:"accessModes,omitempty"`
+	// AccessModesSufficient 为 schema:accessModeSufficient，每项为以逗号分隔的组合，如 "textual" 或 "textual,visual"
+	AccessModesSufficient []string `json:"accessModesSufficient,omitempty"`
+	// Features 为 schema:accessibilityFeature，如 structuralNavigation、alternativeText、tableOfContents
+	Features []string `json:"features,omitempty"`
+	// Hazards 为 schema:accessibilityHazard，如 none、noFlashingHazard
+	Hazards []string `json:"hazards,omitempty"`
+	// Summary 为 schema:accessibilitySummary
+	Summary string `json:"summary,omitempty"`
+	// ConformsTo 为 dcterms:conformsTo，如 "EPUB Accessibility 1.1 - WCAG 2.1 Level AA"（仅 EPUB3）
+	ConformsTo string `json:"conformsTo,omitempty"`
+	// CertifiedBy 为 a11y:certifiedBy，即作出符合性声明的机构（仅 EPUB3）
+	CertifiedBy string `json:"certifiedBy,omitempty"`
+}
+
+// AccessibilityIssue 为无障碍检查发现的一个问题
+type AccessibilityIssue struct {
+	Rule    string `json:"rule"`
+	File    string `json:"file"`
+	Element string `json:"element,omitempty"` // 出问题的元素，如 img[src="a.png"]
+	Message string `json:"message"`
+}
+
+// AccessibilityReport 为 CheckAccessibility 的结果
+type AccessibilityReport struct {
+	Metadata AccessibilityMetadata `json:"metadata"`
+	Issues   []AccessibilityIssue  `json:"issues,omitempty"`
+	Counts   map[string]int        `json:"counts,omitempty"` // 规则 -> 问题数
+	// Conformant 为 true 表示所有检查都通过；这些检查只覆盖常见问题，不能代替完整的 WCAG 评估
+	Conformant bool `json:"conformant"`
+}
+
+// accessModes 与 accessibilityHazards 为 schema.org 定义的取值
+var (
+	accessModes = []string{"auditory", "chartOnVisual", "chemOnVisual", "colorDependent", "diagramOnTactile",
+		"diagramOnVisual", "mathOnVisual", "musicOnVisual", "tactile", "textOnVisual", "textual", "visual"}
+	accessibilityHazards = []string{"flashing", "motionSimulation", "sound", "noFlashingHazard", "noMotionSimulationHazard",
+		"noSoundHazard", "unknownFlashingHazard", "unknownMotionSimulationHazard", "unknownSoundHazard", "none", "unknown"}
+)
+
+// accessibilityProperties 为 SetAccessibility 管理的元数据属性，按写入顺序排列
+var accessibilityProperties = []string{
+	"schema:accessMode", "schema:accessModeSufficient", "schema:accessibilityFeature",
+	"schema:accessibilityHazard", "schema:accessibilitySummary", "dcterms:conformsTo", "a11y:certifiedBy",
+}
+
+// Accessibility 读取 OPF 中的无障碍元数据，支持 EPUB3 的 <meta property="..."> 与 EPUB2 的 <meta name="..." content="...">
+func (p *Epub) Accessibility() AccessibilityMetadata {
+	var meta AccessibilityMetadata
+	if p.opfDoc == nil {
+		return meta
+	}
+	nodes, err := parseXMLFragment(p.opfDoc.Metadata.InnerXML)
+	if err != nil {
+		return meta
+	}
+	for _, n := range nodes {
+		property, value, ok := accessibilityMeta(n)
+		if !ok || value == "" {
+			continue
+		}
+		switch property {
+		case "schema:accessMode":
+			meta.AccessModes = append(meta.AccessModes, value)
+		case "schema:accessModeSufficient":
+			meta.AccessModesSufficient = append(meta.AccessModesSufficient, value)
+		case "schema:accessibilityFeature":
+			meta.Features = append(meta.Features, value)
+		case "schema:accessibilityHazard":
+			meta.Hazards = append(meta.Hazards, value)
+		case "schema:accessibilitySummary":
+			meta.Summary = value
+		case "dcterms:conformsTo":
+			meta.ConformsTo = value
+		case "a11y:certifiedBy":
+			meta.CertifiedBy = value
+		}
+	}
+	return meta
+}
+
+// SetAccessibility 用 meta 替换 OPF 中全部的无障碍元数据，为空的字段会删除对应的元数据。
+// EPUB3 写入 <meta property="schema:...">，EPUB2 写入 <meta name="schema:..." content="...">；
+// accessMode 与 accessibilityHazard 只接受 schema.org 定义的取值
+func (p *Epub) SetAccessibility(meta AccessibilityMetadata) error {
+	if p.opfDoc == nil {
+		return fmt.Errorf("content.opf not loaded")
+	}
+	for _, mode := range meta.AccessModes {
+		if !slices.Contains(accessModes, mode) {
+			return fmt.Errorf("unsupported access mode: %s", mode)
+		}
+	}
+	for _, sufficient := range meta.AccessModesSufficient {
+		for _, mode := range strings.Split(sufficient, ",") {
+			if !slices.Contains(accessModes, strings.TrimSpace(mode)) {
+				return fmt.Errorf("unsupported access mode: %s", mode)
+			}
+		}
+	}
+	for _, hazard := range meta.Hazards {
+		if !slices.Contains(accessibilityHazards, hazard) {
+			return fmt.Errorf("unsupported accessibility hazard: %s", hazard)
+		}
+	}
+	epub3 := strings.HasPrefix(p.opfDoc.Version, "3")
+	if !epub3 && (meta.ConformsTo != "" || meta.CertifiedBy != "") {
+		return fmt.Errorf("conformsTo and certifiedBy require EPUB 3")
+	}
+
+	nodes, err := parseXMLFragment(p.opfDoc.Metadata.InnerXML)
+	if err != nil {
+		return fmt.Errorf("failed to parse metadata: %w", err)
+	}
+	// 删除已有的无障碍元数据及其前面的缩进
+	var kept []*xmlNode
+	for _, n := range nodes {
+		if _, _, ok := accessibilityMeta(n); ok {
+			for len(kept) > 0 && kept[len(kept)-1].kind == xmlTextNode && strings.TrimSpace(kept[len(kept)-1].data) == "" {
+				kept = kept[:len(kept)-1]
+			}
+			continue
+		}
+		kept = append(kept, n)
+	}
+	p.opfDoc.Metadata.InnerXML = (&xmlNode{kind: xmlElementNode, children: kept}).innerBytes()
+
+	values := map[string][]string{
+		"schema:accessMode":           meta.AccessModes,
+		"schema:accessModeSufficient": meta.AccessModesSufficient,
+		"schema:accessibilityFeature": meta.Features,
+		"schema:accessibilityHazard":  meta.Hazards,
+	}
+	for property, value := range map[string]string{
+		"schema:accessibilitySummary": meta.Summary,
+		"dcterms:conformsTo":          meta.ConformsTo,
+		"a11y:certifiedBy":            meta.CertifiedBy,
+	} {
+		if value = strings.TrimSpace(value); value != "" {
+			values[property] = []string{value}
+		}
+	}
+	for _, property := range accessibilityProperties {
+		for _, value := range values[property] {
+			if epub3 {
+				p.appendMetadata(`<meta property="` + property + `">` + html.EscapeString(value) + `</meta>`)
+			} else {
+				p.appendMetadata(`<meta name="` + property + `" content="` + html.EscapeString(value) + `"/>`)
+			}
+		}
+	}
+	return nil
+}
+
+// accessibilityMeta 判断元数据元素是否为无障碍元数据，返回属性名与值
+func accessibilityMeta(n *xmlNode) (string, string, bool) {
+	if n.kind != xmlElementNode || n.localName() != "meta" {
+		return "", "", false
+	}
+	if property, ok := n.attr("property"); ok && slices.Contains(accessibilityProperties, property) {
+		return property, strings.TrimSpace(n.text()), true
+	}
+	if name, ok := n.attr("name"); ok && slices.Contains(accessibilityProperties, name) {
+		content, _ := n.attr("content")
+		return name, strings.TrimSpace(content), true
+	}
+	return "", "", false
+}
+
+// CheckAccessibility 检查无障碍元数据是否齐全（accessMode、accessibilityFeature、accessibilityHazard、
+// accessibilitySummary），以及所有 HTML 文件中缺少 alt 的图片、缺少 lang 的文档、跳级的标题与没有表头的表格
+func (p *Epub) CheckAccessibility() (*AccessibilityReport, error) {
+	if p.opfDoc == nil {
+		return nil, fmt.Errorf("content.opf not loaded")
+	}
+	report := &AccessibilityReport{Metadata: p.Accessibility()}
+	addIssue := func(rule, file, element, message string) {
+		report.Issues = append(report.Issues, AccessibilityIssue{Rule: rule, File: file, Element: element, Message: message})
+	}
+
+	meta := report.Metadata
+	for _, required := range []struct {
+		property string
+		missing  bool
+	}{
+		{"schema:accessMode", len(meta.AccessModes) == 0},
+		{"schema:accessibilityFeature", len(meta.Features) == 0},
+		{"schema:accessibilityHazard", len(meta.Hazards) == 0},
+		{"schema:accessibilitySummary", meta.Summary == ""},
+	} {
+		if required.missing {
+			addIssue(A11yRuleMetadata, p.opfPath, "", "missing "+required.property+" metadata")
+		}
+	}
+	if p.Info().Language == "" {
+		addIssue(A11yRuleLang, p.opfPath, "", "missing dc:language metadata")
+	}
+
+	for _, entry := range p.htmlEntriesInOrder() {
+		name := entry.header.Name
+		doc, err := html.Parse(strings.NewReader(string(entry.data)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse HTML (%s): %w", name, err)
+		}
+		if root := findElement(doc, atom.Html); root != nil && attrValue(root, "lang") == "" && attrValue(root, "xml:lang") == "" {
+			addIssue(A11yRuleLang, name, "html", "missing lang attribute on the html element")
+		}
+		lastLevel := 0
+		walkElements(doc, func(n *html.Node) {
+			switch n.DataAtom {
+			case atom.Img:
+				if !hasAttr(n, "alt") && attrValue(n, "role") != "presentation" {
+					addIssue(A11yRuleImageAlt, name, describeElement(n), "image has no alt attribute")
+				}
+			case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+				level, _ := strconv.Atoi(n.Data[1:])
+				if lastLevel > 0 && level > lastLevel+1 {
+					addIssue(A11yRuleHeadingOrder, name, describeElement(n),
+						fmt.Sprintf("heading level jumps from h%d to h%d", lastLevel, level))
+				}
+				lastLevel = level
+			case atom.Table:
+				if role := attrValue(n, "role"); role == "presentation" || role == "none" {
+					return
+				}
+				hasHeader := false
+				walkElements(n, func(c *html.Node) {
+					if c.DataAtom == atom.Th {
+						hasHeader = true
+					}
+				})
+				if !hasHeader {
+					addIssue(A11yRuleTableHeader, name, describeElement(n), "table has no header cells (th)")
+				}
+			}
+		})
+	}
+
+	if len(report.Issues) > 0 {
+		report.Counts = make(map[string]int)
+		for _, issue := range report.Issues {
+			report.Counts[issue.Rule]++
+		}
+	}
+	report.Conformant = len(report.Issues) == 0
+	return report, nil
+}
+
+// describeElement 以 tag#id 或 tag[src="..."] 的形式描述元素，标题附带文本
+func describeElement(n *html.Node) string {
+	desc := n.Data
+	if id := attrValue(n, "id"); id != "" {
+		desc += "#" + id
+	} else if src := attrValue(n, "src"); src != "" {
+		desc += `[src="` + src + `"]`
+	}
+	if n.DataAtom != atom.Img && n.DataAtom != atom.Table {
+		if text := strings.Join(strings.Fields(nodeText(n)), " "); text != "" {
+			if runes := []rune(text); len(runes) > 30 {
+				text = string(runes[:30]) + "…"
+			}
+			desc += ` "` + text + `"`
+		}
+	}
+	return desc
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == key && attr.Namespace == "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package epub
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSetAccessibility(t *testing.T) {
+	meta := AccessibilityMetadata{
+		AccessModes:           []string{"textual", "visual"},
+		AccessModesSufficient: []string{"textual", "textual,visual"},
+		Features:              []string{"structuralNavigation", "alternativeText"},
+		Hazards:               []string{"none"},
+		Summary:               "Images & tables are described.",
+		ConformsTo:            "EPUB Accessibility 1.1 - WCAG 2.1 Level AA",
+		CertifiedBy:           "Tester",
+	}
+	book, err := Open(writeTestEPUB(t, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := book.SetAccessibility(meta); err != nil {
+			t.Fatalf("SetAccessibility() error = %v", err)
+		}
+	}
+	inner := string(book.opfDoc.Metadata.InnerXML)
+	if strings.Count(inner, `property="schema:accessMode"`) != 2 ||
+		!strings.Contains(inner, `<meta property="schema:accessibilitySummary">Images &amp; tables are described.</meta>`) {
+		t.Errorf("metadata = %s, want each value written once as EPUB3 meta", inner)
+	}
+
+	output := filepath.Join(t.TempDir(), "a11y.epub")
+	if err := book.Save(output); err != nil {
+		t.Fatal(err)
+	}
+	if book, err = Open(output); err != nil {
+		t.Fatal(err)
+	}
+	if got := book.Accessibility(); !reflect.DeepEqual(got, meta) {
+		t.Errorf("Accessibility() = %+v, want %+v", got, meta)
+	}
+
+	// 未设置的字段会删除对应的元数据
+	if err := book.SetAccessibility(AccessibilityMetadata{Hazards: []string{"noFlashingHazard"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := book.Accessibility(); !reflect.DeepEqual(got, AccessibilityMetadata{Hazards: []string{"noFlashingHazard"}}) {
+		t.Errorf("Accessibility() = %+v, want only the hazard", got)
+	}
+	if info := book.Info(); info.Title != "Test" {
+		t.Errorf("Info().Title = %q, other metadata must be kept", info.Title)
+	}
+
+	for _, invalid := range []AccessibilityMetadata{
+		{AccessModes: []string{"visible"}},
+		{AccessModesSufficient: []string{"textual, smell"}},
+		{Hazards: []string{"dangerous"}},
+	} {
+		if err := book.SetAccessibility(invalid); err == nil {
+			t.Errorf("SetAccessibility(%+v) succeeded, want error", invalid)
+		}
+	}
+}
+
+func TestSetAccessibilityEPUB2(t *testing.T) {
+	book, err := Open(writeTestEPUB(t, strings.Replace(testOPF, `version="3.0"`, `version="2.0"`, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := AccessibilityMetadata{AccessModes: []string{"textual"}, Summary: "Text only."}
+	if err := book.SetAccessibility(meta); err != nil {
+		t.Fatalf("SetAccessibility() error = %v", err)
+	}
+	if inner := string(book.opfDoc.Metadata.InnerXML); !strings.Contains(inner, `<meta name="schema:accessMode" content="textual"/>`) {
+		t.Errorf("metadata = %s, want EPUB2 name/content meta", inner)
+	}
+	if got := book.Accessibility(); !reflect.DeepEqual(got, meta) {
+		t.Errorf("Accessibility() = %+v, want %+v", got, meta)
+	}
+	if err := book.SetAccessibility(AccessibilityMetadata{ConformsTo: "EPUB Accessibility 1.1"}); err == nil {
+		t.Error("SetAccessibility() with conformsTo on EPUB2 succeeded")
+	}
+}
+
+func TestCheckAccessibility(t *testing.T) {
+	book := openChapters(t, 2)
+	ch1 := strings.Replace(string(chapterWithBody(`<h1>One</h1><h3 id="jump">Three</h3><h4>Four</h4><h2>Two</h2>`+
+		`<img src="a.png"/><img src="b.png" alt=""/><img src="c.png" role="presentation"/>`+
+		`<table><tr><td>x</td></tr></table><table role="presentation"><tr><td>y</td></tr></table>`+
+		`<table><thead><tr><th>h</th></tr></thead></table>`)), "<html ", `<html xml:lang="en" `, 1)
+	if err := book.writeEntry("OEBPS/Text/ch1.xhtml", []byte(ch1)); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := book.CheckAccessibility()
+	if err != nil {
+		t.Fatalf("CheckAccessibility() error = %v", err)
+	}
+	want := map[string]int{A11yRuleMetadata: 4, A11yRuleImageAlt: 1, A11yRuleLang: 1, A11yRuleHeadingOrder: 1, A11yRuleTableHeader: 1}
+	if !reflect.DeepEqual(report.Counts, want) || report.Conformant {
+		t.Errorf("CheckAccessibility() counts = %v, conformant = %v, want %v", report.Counts, report.Conformant, want)
+	}
+	var elements []string
+	for _, issue := range report.Issues {
+		if issue.Rule != A11yRuleMetadata {
+			elements = append(elements, issue.Rule+" "+issue.File+" "+issue.Element)
+		}
+	}
+	wantElements := []string{
+		`heading-order OEBPS/Text/ch1.xhtml h3#jump "Three"`,
+		`img-alt OEBPS/Text/ch1.xhtml img[src="a.png"]`,
+		`table-header OEBPS/Text/ch1.xhtml table`,
+		`lang OEBPS/Text/ch2.xhtml html`,
+	}
+	if !reflect.DeepEqual(elements, wantElements) {
+		t.Errorf("issues = %q, want %q", elements, wantElements)
+	}
+
+	err = book.SetAccessibility(AccessibilityMetadata{
+		AccessModes: []string{"textual"},
+		Features:    []string{"structuralNavigation"},
+		Hazards:     []string{"none"},
+		Summary:     "Text only.",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, norm := range book.SpinePaths() {
+		if err := book.writeEntry(norm, []byte(strings.Replace(testChapter, "<html ", `<html lang="en" `, 1))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if report, err := book.CheckAccessibility(); err != nil || !report.Conformant || len(report.Issues) != 0 {
+		t.Errorf("CheckAccessibility() = %+v, %v, want conformant", report, err)
+	}
+}
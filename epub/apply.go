@@ -0,0 +1,154 @@
+package epub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ApplyOptions 控制 ApplyHTMLWithOptions 的执行方式
+type ApplyOptions struct {
+	// Concurrency 为并发处理的章节数，<=1 时串行执行
+	Concurrency int
+	// OnProgress 在每个章节处理完成后调用，current 从 1 开始；调用是串行的
+	OnProgress func(current, total int, name string)
+	// ContinueOnError 为 true 时遇到错误继续处理其余章节并汇总所有错误，
+	// 否则在第一个错误处停止
+	ContinueOnError bool
+}
+
+// HTMLError 记录单个章节的处理错误
+type HTMLError struct {
+	Name string
+	Err  error
+}
+
+func (e *HTMLError) Error() string {
+	return fmt.Sprintf("failed to process HTML (%s): %v", e.Name, e.Err)
+}
+
+func (e *HTMLError) Unwrap() error {
+	return e.Err
+}
+
+// ApplyHTMLWithOptions 对所有 HTML 执行自定义函数，返回被修改的章节数
+// fn 可以并发执行，但结果总是按 spine 顺序写回：
+//   - 停止模式下，出错后不再把新的章节交给 fn，只写回第一个失败章节之前的结果，返回该章节的 *HTMLError
+//   - ContinueOnError 模式下，写回所有成功的结果，返回 errors.Join 汇总的 *HTMLError
+//   - ctx 被调用方取消时不写回任何结果，返回 ctx.Err()
+func (p *Epub) ApplyHTMLWithOptions(ctx context.Context, fn func(name string, html string) (string, error), opts ApplyOptions) (int, error) {
+	if fn == nil {
+		return 0, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	entries := p.htmlEntriesInOrder()
+	total := len(entries)
+	results := make([]string, total)
+	errs := make([]error, total)
+	done := make([]bool, total)
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	current := 0
+	finish := func(i int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs[i] = &HTMLError{Name: entries[i].header.Name, Err: err}
+			if !opts.ContinueOnError {
+				cancel()
+			}
+		}
+		done[i] = true
+		current++
+		if opts.OnProgress != nil {
+			opts.OnProgress(current, total, entries[i].header.Name)
+		}
+	}
+
+	workers := opts.Concurrency
+	if workers <= 1 {
+		workers = 1
+	}
+	if workers > total {
+		workers = total
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				// 已停止时丢弃排队中的章节
+				if ctx.Err() != nil {
+					continue
+				}
+				updated, err := fn(entries[i].header.Name, string(entries[i].data))
+				if err == nil {
+					results[i] = updated
+				}
+				finish(i, err)
+			}
+		}()
+	}
+
+feed:
+	for i := range entries {
+		// select 在两个分支都就绪时随机选择，先检查 ctx 以免停止后继续派发
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := parent.Err(); err != nil {
+		return 0, err
+	}
+
+	// 按 spine 顺序写回结果
+	var firstErr error
+	for _, err := range errs {
+		if err != nil {
+			firstErr = err
+			break
+		}
+	}
+	modified := 0
+	var collected []error
+	for i := range entries {
+		if errs[i] != nil {
+			if !opts.ContinueOnError {
+				return modified, errs[i]
+			}
+			collected = append(collected, errs[i])
+			continue
+		}
+		if !done[i] {
+			// 只有停止模式出错后才会有未处理的章节
+			return modified, firstErr
+		}
+		if results[i] != string(entries[i].data) {
+			entries[i].data = []byte(results[i])
+			modified++
+		}
+	}
+	if len(collected) > 0 {
+		return modified, errors.Join(collected...)
+	}
+	return modified, nil
+}
@@ -0,0 +1,167 @@
+package epub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// openChapters 打开一个 spine 中依次有 ch1 到 chN 的测试 EPUB
+func openChapters(t *testing.T, n int) *Epub {
+	t.Helper()
+	var items, refs strings.Builder
+	var extra []testFile
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&items, `<item id="ch%d" href="Text/ch%d.xhtml" media-type="application/xhtml+xml"/>`, i, i)
+		fmt.Fprintf(&refs, `<itemref idref="ch%d"/>`, i)
+		if i > 1 {
+			extra = append(extra, testFile{fmt.Sprintf("OEBPS/Text/ch%d.xhtml", i), testChapter})
+		}
+	}
+	opf := strings.NewReplacer(
+		`<item id="ch1" href="Text/ch1.xhtml" media-type="application/xhtml+xml"/>`, items.String(),
+		`<itemref idref="ch1"/>`, refs.String(),
+	).Replace(testOPF)
+	book, err := Open(writeTestEPUB(t, opf, extra...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return book
+}
+
+// modifiedChapters 返回内容与 testChapter 不同的章节序号
+func modifiedChapters(book *Epub) []int {
+	var modified []int
+	for i, norm := range book.SpinePaths() {
+		if data, _ := book.ReadFile(norm); string(data) != testChapter {
+			modified = append(modified, i+1)
+		}
+	}
+	return modified
+}
+
+func TestApplyHTMLWithOptions(t *testing.T) {
+	errFailed := errors.New("failed")
+	// failing 对 fail 中的章节返回错误，其余章节追加注释
+	failing := func(fail ...string) func(name, html string) (string, error) {
+		return func(name, html string) (string, error) {
+			for _, f := range fail {
+				if strings.HasSuffix(name, "/"+f+".xhtml") {
+					return "", errFailed
+				}
+			}
+			return html + "<!-- applied -->", nil
+		}
+	}
+	tests := []struct {
+		name         string
+		fn           func(name, html string) (string, error)
+		opts         ApplyOptions
+		wantCount    int
+		wantModified []int
+		wantFailed   []string // 错误中应包含的失败章节
+		// racy 为 true 时为并发的停止模式，先失败的章节取决于调度：
+		// 写回的只能是 wantModified 的前缀，错误只报告 wantFailed 中的一个
+		racy bool
+	}{
+		{name: "all", fn: failing(), wantCount: 4, wantModified: []int{1, 2, 3, 4}},
+		{name: "unchanged", fn: func(name, html string) (string, error) { return html, nil }},
+		{name: "stop", fn: failing("ch3"), wantCount: 2, wantModified: []int{1, 2}, wantFailed: []string{"ch3"}},
+		{name: "stop at first", fn: failing("ch2", "ch3"), wantCount: 1, wantModified: []int{1}, wantFailed: []string{"ch2"}},
+		{name: "stop concurrent", fn: failing("ch3"), opts: ApplyOptions{Concurrency: 4},
+			wantCount: 2, wantModified: []int{1, 2}, wantFailed: []string{"ch3"}, racy: true},
+		{name: "stop concurrent at first", fn: failing("ch2", "ch3"), opts: ApplyOptions{Concurrency: 4},
+			wantCount: 1, wantModified: []int{1}, wantFailed: []string{"ch2", "ch3"}, racy: true},
+		{name: "continue", fn: failing("ch2", "ch3"), opts: ApplyOptions{ContinueOnError: true},
+			wantCount: 2, wantModified: []int{1, 4}, wantFailed: []string{"ch2", "ch3"}},
+		{name: "continue concurrent", fn: failing("ch2", "ch3"), opts: ApplyOptions{ContinueOnError: true, Concurrency: 3},
+			wantCount: 2, wantModified: []int{1, 4}, wantFailed: []string{"ch2", "ch3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := openChapters(t, 4)
+			count, err := book.ApplyHTMLWithOptions(context.Background(), tt.fn, tt.opts)
+			modified := modifiedChapters(book)
+			if tt.racy {
+				if count != len(modified) || len(modified) > len(tt.wantModified) ||
+					fmt.Sprint(modified) != fmt.Sprint(tt.wantModified[:len(modified)]) {
+					t.Errorf("count = %d, modified chapters = %v, want a prefix of %v", count, modified, tt.wantModified)
+				}
+			} else {
+				if count != tt.wantCount {
+					t.Errorf("count = %d, want %d", count, tt.wantCount)
+				}
+				if fmt.Sprint(modified) != fmt.Sprint(tt.wantModified) {
+					t.Errorf("modified chapters = %v, want %v", modified, tt.wantModified)
+				}
+			}
+			if len(tt.wantFailed) == 0 {
+				if err != nil {
+					t.Fatalf("error = %v", err)
+				}
+				return
+			}
+			var htmlErr *HTMLError
+			if !errors.Is(err, errFailed) || !errors.As(err, &htmlErr) {
+				t.Fatalf("error = %v, want *HTMLError wrapping %v", err, errFailed)
+			}
+			if !tt.opts.ContinueOnError {
+				reported := false
+				for _, name := range tt.wantFailed {
+					reported = reported || htmlErr.Name == "OEBPS/Text/"+name+".xhtml"
+				}
+				if !reported || !tt.racy && htmlErr.Name != "OEBPS/Text/"+tt.wantFailed[0]+".xhtml" ||
+					strings.Count(err.Error(), "failed to process HTML") != 1 {
+					t.Errorf("error = %v, want only the failure of %v", err, tt.wantFailed)
+				}
+				return
+			}
+			for _, name := range tt.wantFailed {
+				if !strings.Contains(err.Error(), "/"+name+".xhtml") {
+					t.Errorf("error = %v, want %s reported", err, name)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyHTMLWithOptionsCanceled(t *testing.T) {
+	for _, concurrency := range []int{1, 4} {
+		book := openChapters(t, 4)
+		ctx, cancel := context.WithCancel(context.Background())
+		count, err := book.ApplyHTMLWithOptions(ctx, func(name, html string) (string, error) {
+			if strings.HasSuffix(name, "/ch2.xhtml") {
+				cancel()
+			}
+			return html + "<!-- applied -->", nil
+		}, ApplyOptions{Concurrency: concurrency})
+		cancel()
+		if !errors.Is(err, context.Canceled) || count != 0 {
+			t.Errorf("concurrency %d: ApplyHTMLWithOptions() = %d, %v, want 0, context.Canceled", concurrency, count, err)
+		}
+		if modified := modifiedChapters(book); len(modified) != 0 {
+			t.Errorf("concurrency %d: modified chapters = %v after cancellation", concurrency, modified)
+		}
+	}
+}
+
+func TestApplyHTMLWithOptionsProgress(t *testing.T) {
+	book := openChapters(t, 3)
+	var calls []string
+	_, err := book.ApplyHTMLWithOptions(context.Background(), func(name, html string) (string, error) {
+		return html, nil
+	}, ApplyOptions{
+		Concurrency: 2,
+		OnProgress: func(current, total int, name string) {
+			calls = append(calls, fmt.Sprintf("%d/%d", current, total))
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(calls, " "); got != "1/3 2/3 3/3" {
+		t.Errorf("progress = %s, want 1/3 2/3 3/3", got)
+	}
+}
@@ -0,0 +1,403 @@
+package epub
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchOptions 描述对目录中 EPUB 的批量处理
+type BatchOptions struct {
+	Dir       string   // 待处理的目录（递归遍历）
+	OutputDir string   // 输出目录，保持相对路径
+	InPlace   bool     // 覆盖原文件；与 OutputDir 必须且只能设置一个
+	Exts      []string // 需要处理的扩展名，默认 [".epub"]
+	Workers   int      // 并发数，默认 runtime.NumCPU()
+
+	// Open 为打开每个文件时使用的选项，如拒绝受 DRM 保护的书籍
+	Open OpenOptions
+
+	// Process 与 Options 至少设置一个；同时设置时先执行 Options 再执行 Process
+	Process func(p *Epub) error
+	Options *ProcessOptions
+
+	// Processed 记录已处理文件的内容哈希，命中的文件会被跳过；为空时不跳过
+	Processed HashStore
+
+	// OnProgress 在每个文件处理结束后调用，调用是串行的
+	OnProgress func(progress BatchProgress)
+}
+
+// BatchProgress 单个文件的处理进度
+type BatchProgress struct {
+	Path     string
+	Done     int
+	Total    int
+	Skipped  bool
+	Err      error
+	Duration time.Duration
+}
+
+// BatchError 记录单个文件的处理错误
+type BatchError struct {
+	Path string
+	Err  error
+}
+
+func (e BatchError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e BatchError) Unwrap() error {
+	return e.Err
+}
+
+// BatchResult 批量处理的汇总结果
+type BatchResult struct {
+	Total     int
+	Processed int
+	Skipped   int
+	Failed    int
+	Errors    []BatchError
+	Duration  time.Duration
+}
+
+// HashStore 记录已处理文件的内容哈希
+type HashStore interface {
+	Has(hash string) (bool, error)
+	Add(hash string) error
+}
+
+// ProcessDir 使用有界协程池批量处理目录下的 EPUB
+// 单个文件失败不会中断其余文件，错误记录在 BatchResult.Errors 中；
+// ctx 取消后不再开始新的文件，并返回 ctx.Err()
+func ProcessDir(ctx context.Context, opts BatchOptions) (*BatchResult, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("batch directory cannot be empty")
+	}
+	if opts.Process == nil && opts.Options == nil {
+		return nil, fmt.Errorf("batch process function cannot be empty")
+	}
+	if opts.OutputDir == "" && !opts.InPlace {
+		return nil, fmt.Errorf("batch output directory cannot be empty unless InPlace is set")
+	}
+	if opts.OutputDir != "" && opts.InPlace {
+		return nil, fmt.Errorf("batch OutputDir and InPlace cannot both be set")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	files, err := collectBatchFiles(opts.Dir, opts.Exts)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	start := time.Now()
+	result := &BatchResult{Total: len(files)}
+
+	claims := &hashClaims{store: opts.Processed, pending: make(map[string]bool)}
+	var mu sync.Mutex
+	report := func(progress BatchProgress) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case progress.Err != nil:
+			result.Failed++
+			result.Errors = append(result.Errors, BatchError{Path: progress.Path, Err: progress.Err})
+		case progress.Skipped:
+			result.Skipped++
+		default:
+			result.Processed++
+		}
+		progress.Done = result.Processed + result.Skipped + result.Failed
+		progress.Total = result.Total
+		if opts.OnProgress != nil {
+			opts.OnProgress(progress)
+		}
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				fileStart := time.Now()
+				skipped, err := processBatchFile(file, &opts, claims)
+				report(BatchProgress{
+					Path:     file,
+					Skipped:  skipped,
+					Err:      err,
+					Duration: time.Since(fileStart),
+				})
+			}
+		}()
+	}
+
+feed:
+	for _, file := range files {
+		// select 在两个分支都就绪时随机选择，先检查 ctx 以免取消后继续派发
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case jobs <- file:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	result.Duration = time.Since(start)
+	sort.Slice(result.Errors, func(i, j int) bool {
+		return result.Errors[i].Path < result.Errors[j].Path
+	})
+	return result, ctx.Err()
+}
+
+// processBatchFile 处理单个文件，返回是否因已处理而跳过
+func processBatchFile(file string, opts *BatchOptions, claims *hashClaims) (bool, error) {
+	var hash string
+	if opts.Processed != nil {
+		h, err := fileHash(file)
+		if err != nil {
+			return false, err
+		}
+		claimed, err := claims.claim(h)
+		if err != nil {
+			return false, err
+		}
+		if !claimed {
+			return true, nil
+		}
+		defer claims.release(h)
+		hash = h
+	}
+
+	p, err := OpenWithOptions(file, opts.Open)
+	if err != nil {
+		return false, err
+	}
+	if opts.Options != nil {
+		if err := p.Apply(*opts.Options); err != nil {
+			return false, err
+		}
+	}
+	if opts.Process != nil {
+		if err := opts.Process(p); err != nil {
+			return false, err
+		}
+	}
+
+	outputPath := file
+	if opts.OutputDir != "" {
+		rel, err := filepath.Rel(opts.Dir, file)
+		if err != nil {
+			return false, err
+		}
+		outputPath = filepath.Join(opts.OutputDir, rel)
+		if err := os.MkdirAll(filepath.Dir(outputPath), os.ModePerm); err != nil {
+			return false, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+	if err := saveAtomic(p, outputPath); err != nil {
+		return false, err
+	}
+
+	if opts.Processed != nil {
+		if err := opts.Processed.Add(hash); err != nil {
+			return false, err
+		}
+		// 原地覆盖时新文件内容已变化，同样记录，避免下次重复处理
+		outHash, err := fileHash(outputPath)
+		if err != nil {
+			return false, err
+		}
+		if outHash != hash {
+			if err := opts.Processed.Add(outHash); err != nil {
+				return false, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// hashClaims 让检查与占用哈希成为原子操作：内容相同的文件同时进入不同协程时只处理一次
+type hashClaims struct {
+	mu      sync.Mutex
+	store   HashStore
+	pending map[string]bool // 正在处理的哈希
+}
+
+// claim 在哈希未处理且未被其它协程占用时占用它并返回 true
+func (c *hashClaims) claim(hash string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pending[hash] {
+		return false, nil
+	}
+	done, err := c.store.Has(hash)
+	if err != nil || done {
+		return false, err
+	}
+	c.pending[hash] = true
+	return true, nil
+}
+
+// release 在处理结束后释放占用；成功时哈希已写入 store，之后的 claim 会由 Has 拒绝
+func (c *hashClaims) release(hash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, hash)
+}
+
+// saveAtomic 先写入临时文件再重命名，避免中途失败破坏原文件
+func saveAtomic(p *Epub, outputPath string) error {
+	tmp := outputPath + ".tmp"
+	if err := p.Save(tmp); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, outputPath); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to replace output file: %w", err)
+	}
+	return nil
+}
+
+func collectBatchFiles(dir string, exts []string) ([]string, error) {
+	if len(exts) == 0 {
+		exts = []string{".epub"}
+	}
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(p))
+		for _, e := range exts {
+			if ext == strings.ToLower(e) {
+				files = append(files, p)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func fileHash(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash file (%s): %w", file, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// MemoryHashStore 仅保存在内存中的 HashStore
+type MemoryHashStore struct {
+	mu     sync.RWMutex
+	hashes map[string]struct{}
+}
+
+// NewMemoryHashStore 创建内存 HashStore
+func NewMemoryHashStore() *MemoryHashStore {
+	return &MemoryHashStore{hashes: make(map[string]struct{})}
+}
+
+func (s *MemoryHashStore) Has(hash string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.hashes[hash]
+	return ok, nil
+}
+
+func (s *MemoryHashStore) Add(hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hashes[hash] = struct{}{}
+	return nil
+}
+
+// FileHashStore 将哈希逐行追加到文本文件中，可在多次运行之间复用
+type FileHashStore struct {
+	mem  *MemoryHashStore
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenFileHashStore 打开（或创建）哈希记录文件并加载已有记录
+func OpenFileHashStore(path string) (*FileHashStore, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open hash store: %w", err)
+	}
+
+	store := &FileHashStore{mem: NewMemoryHashStore(), file: file}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			_ = store.mem.Add(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to read hash store: %w", err)
+	}
+	return store, nil
+}
+
+func (s *FileHashStore) Has(hash string) (bool, error) {
+	return s.mem.Has(hash)
+}
+
+func (s *FileHashStore) Add(hash string) error {
+	if ok, _ := s.mem.Has(hash); ok {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.WriteString(hash + "\n"); err != nil {
+		return fmt.Errorf("failed to write hash store: %w", err)
+	}
+	return s.mem.Add(hash)
+}
+
+// Close 关闭底层文件
+func (s *FileHashStore) Close() error {
+	if s == nil || s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
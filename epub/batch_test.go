@@ -0,0 +1,186 @@
+package epub
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeBatchDir 在临时目录中写入待批量处理的文件：a、sub/b 与 a 内容相同的 dup，以及无法打开的 bad
+func writeBatchDir(t *testing.T) string {
+	t.Helper()
+	book, err := os.ReadFile(writeTestEPUB(t, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := os.ReadFile(writeTestEPUB(t, "", testFile{"OEBPS/other.txt", "other"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for name, data := range map[string][]byte{
+		"a.epub":     book,
+		"dup.EPUB":   book,
+		"sub/b.epub": other,
+		"bad.epub":   []byte("not a zip"),
+		"notes.txt":  []byte("skip me"),
+	} {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// markChapter 为批量处理的函数，改写第一章
+func markChapter(p *Epub) error {
+	return p.writeEntry("OEBPS/Text/ch1.xhtml", []byte("processed"))
+}
+
+func readChapter(t *testing.T, file string) string {
+	t.Helper()
+	book, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := book.ReadFile("OEBPS/Text/ch1.xhtml")
+	return string(data)
+}
+
+func TestProcessDirValidation(t *testing.T) {
+	for _, opts := range []BatchOptions{
+		{OutputDir: "out", Process: markChapter},
+		{Dir: "in", OutputDir: "out"},
+		{Dir: "in", Process: markChapter},
+		{Dir: "in", OutputDir: "out", InPlace: true, Process: markChapter},
+	} {
+		if _, err := ProcessDir(context.Background(), opts); err == nil {
+			t.Errorf("ProcessDir(%+v) succeeded, want error", opts)
+		}
+	}
+}
+
+func TestProcessDir(t *testing.T) {
+	dir := writeBatchDir(t)
+	out := t.TempDir()
+	store := NewMemoryHashStore()
+	var done []int
+	result, err := ProcessDir(context.Background(), BatchOptions{
+		Dir:        dir,
+		OutputDir:  out,
+		Exts:       []string{".epub"},
+		Workers:    4,
+		Process:    markChapter,
+		Processed:  store,
+		OnProgress: func(progress BatchProgress) { done = append(done, progress.Done) },
+	})
+	if err != nil {
+		t.Fatalf("ProcessDir() error = %v", err)
+	}
+	// a 与 dup 内容相同，只处理其中一个
+	if result.Total != 4 || result.Processed != 2 || result.Skipped != 1 || result.Failed != 1 {
+		t.Errorf("ProcessDir() = %+v, want 4 total, 2 processed, 1 skipped, 1 failed", result)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Path != filepath.Join(dir, "bad.epub") {
+		t.Errorf("Errors = %v, want bad.epub", result.Errors)
+	}
+	if len(done) != 4 || done[0] != 1 || done[3] != 4 {
+		t.Errorf("progress Done = %v, want 1 to 4", done)
+	}
+	if got := readChapter(t, filepath.Join(out, "sub", "b.epub")); got != "processed" {
+		t.Errorf("sub/b.epub chapter = %q, want it processed into the output directory", got)
+	}
+	outputs := 0
+	for _, name := range []string{"a.epub", "dup.EPUB"} {
+		if _, err := os.Stat(filepath.Join(out, name)); err == nil {
+			outputs++
+		}
+	}
+	if outputs != 1 {
+		t.Errorf("%d outputs for a.epub and dup.EPUB, want 1", outputs)
+	}
+	if got := readChapter(t, filepath.Join(dir, "a.epub")); got == "processed" {
+		t.Error("input modified without InPlace")
+	}
+
+	// 再次运行时已处理的文件全部跳过
+	result, err = ProcessDir(context.Background(), BatchOptions{Dir: dir, OutputDir: out, Process: markChapter, Processed: store})
+	if err != nil || result.Processed != 0 || result.Skipped != 3 || result.Failed != 1 {
+		t.Errorf("second ProcessDir() = %+v, %v, want 3 skipped and bad.epub failed", result, err)
+	}
+}
+
+func TestProcessDirInPlace(t *testing.T) {
+	dir := writeBatchDir(t)
+	if err := os.Remove(filepath.Join(dir, "bad.epub")); err != nil {
+		t.Fatal(err)
+	}
+	storePath := filepath.Join(t.TempDir(), "hashes.txt")
+	run := func() *BatchResult {
+		t.Helper()
+		store, err := OpenFileHashStore(storePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+		result, err := ProcessDir(context.Background(), BatchOptions{
+			Dir:       dir,
+			InPlace:   true,
+			Workers:   1,
+			Processed: store,
+			Options: &ProcessOptions{ReplaceHTML: func(name, html string) (string, error) {
+				return strings.Replace(html, "Hello", "Hello again", 1), nil
+			}},
+		})
+		if err != nil {
+			t.Fatalf("ProcessDir() error = %v", err)
+		}
+		return result
+	}
+
+	if result := run(); result.Processed != 2 || result.Skipped != 1 || result.Failed != 0 {
+		t.Errorf("ProcessDir() = %+v, want 2 processed and the duplicate skipped", result)
+	}
+	if got := readChapter(t, filepath.Join(dir, "sub", "b.epub")); !strings.Contains(got, "Hello again") {
+		t.Errorf("sub/b.epub chapter = %q, want it overwritten", got)
+	}
+	for _, entry := range mustReadDir(t, dir) {
+		if strings.HasSuffix(entry, ".tmp") {
+			t.Errorf("temporary file %s left behind", entry)
+		}
+	}
+	// 覆盖后的文件也记录在哈希文件中，下次运行不会重复处理
+	if result := run(); result.Processed != 0 || result.Skipped != 3 {
+		t.Errorf("second ProcessDir() = %+v, want every file skipped", result)
+	}
+}
+
+func TestProcessDirCanceled(t *testing.T) {
+	dir := writeBatchDir(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err := ProcessDir(ctx, BatchOptions{Dir: dir, OutputDir: t.TempDir(), Process: markChapter})
+	if !errors.Is(err, context.Canceled) || result.Processed+result.Skipped+result.Failed != 0 {
+		t.Errorf("ProcessDir() = %+v, %v, want nothing processed and context.Canceled", result, err)
+	}
+}
+
+func mustReadDir(t *testing.T, dir string) []string {
+	t.Helper()
+	var names []string
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		names = append(names, p)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
@@ -0,0 +1,356 @@
+package epub
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 简繁转换方向，命名与 OpenCC 的配置一致
+const (
+	ConvertS2T   = "s2t"   // 简体到繁体
+	ConvertS2TW  = "s2tw"  // 简体到台湾正体
+	ConvertS2TWP = "s2twp" // 简体到台湾正体，并转换台湾惯用词（如 软件→軟體）
+	ConvertS2HK  = "s2hk"  // 简体到香港繁体
+	ConvertT2S   = "t2s"   // 繁体（含台湾、香港异体字）到简体
+)
+
+// 内置词典是 OpenCC 格式的精简样本，只覆盖常用字与常见的一简对多繁词组（见 dict/NOTICE），
+// 范围之外的歧义字按默认候选转换；运行 go generate 可替换为 OpenCC 的完整词典（Apache-2.0）
+//
+//go:generate sh dict/update.sh
+//go:embed dict/*.txt
+var chineseDictFS embed.FS
+
+// chineseModes 为每种转换依次使用的词典；同一组内的词典合并后按最长匹配转换
+var chineseModes = map[string]struct {
+	language string
+	groups   [][]string
+}{
+	ConvertS2T:   {"zh-Hant", [][]string{{"STPhrases", "STCharacters"}}},
+	ConvertS2TW:  {"zh-TW", [][]string{{"STPhrases", "STCharacters"}, {"TWVariants"}}},
+	ConvertS2TWP: {"zh-TW", [][]string{{"STPhrases", "STCharacters"}, {"TWPhrases"}, {"TWVariants"}}},
+	ConvertS2HK:  {"zh-HK", [][]string{{"STPhrases", "STCharacters"}, {"HKVariants"}}},
+	ConvertT2S:   {"zh-CN", [][]string{{"TSPhrases", "TSCharacters"}}},
+}
+
+// chineseDict 为合并后的词典，转换时从左到右取最长匹配的词条
+type chineseDict struct {
+	entries map[string]string
+	maxLen  int // 最长词条的字数
+}
+
+var (
+	chineseConvertersMu sync.Mutex
+	chineseConverters   = make(map[string][]*chineseDict)
+)
+
+// ChineseConvertOptions 控制 ConvertChinese
+type ChineseConvertOptions struct {
+	// Mode 为 ConvertS2T、ConvertS2TW、ConvertS2TWP、ConvertS2HK 或 ConvertT2S
+	Mode string
+	// Language 为转换后的 dc:language 与 xml:lang，默认 s2t 为 zh-Hant，s2tw/s2twp 为 zh-TW，
+	// s2hk 为 zh-HK，t2s 为 zh-CN
+	Language string
+}
+
+// ConvertChineseText 按 mode 转换一段文本
+func ConvertChineseText(text, mode string) (string, error) {
+	dicts, err := chineseConverter(mode)
+	if err != nil {
+		return "", err
+	}
+	return convertChinese(text, dicts), nil
+}
+
+// ConvertChinese 对全书做简繁转换：HTML 中的文本节点与 alt/title 属性（不含 script/style 与标签本身），
+// 导航文档与 NCX 中的目录标题，以及 OPF 中的 dc:title 与 dc:creator；
+// 同时把 dc:language 与各文件中以 zh 开头的 lang/xml:lang 改为目标语言。返回被修改的文件
+func (p *Epub) ConvertChinese(opts ChineseConvertOptions) ([]string, error) {
+	if p.opfDoc == nil {
+		return nil, fmt.Errorf("content.opf not loaded")
+	}
+	dicts, err := chineseConverter(opts.Mode)
+	if err != nil {
+		return nil, err
+	}
+	language := opts.Language
+	if language == "" {
+		language = chineseModes[opts.Mode].language
+	}
+
+	var changed []string
+	for _, entry := range p.htmlEntriesInOrder() {
+		doc, err := html.Parse(strings.NewReader(string(entry.data)))
+		if err != nil {
+			return changed, fmt.Errorf("failed to parse HTML (%s): %w", entry.header.Name, err)
+		}
+		if !convertHTMLChinese(doc, dicts, language) {
+			continue
+		}
+		rendered, err := renderXHTMLDocument(doc, p.defaultDoctype())
+		if err != nil {
+			return changed, fmt.Errorf("failed to render HTML (%s): %w", entry.header.Name, err)
+		}
+		entry.data = []byte(rendered)
+		changed = append(changed, entry.header.Name)
+	}
+
+	if ncxPath := p.ncxPath(); ncxPath != "" {
+		tree, err := p.parseXMLEntry(ncxPath)
+		if err != nil {
+			return changed, err
+		}
+		modified := convertXMLChinese(tree, dicts, language, func(*xmlNode) bool { return true })
+		if root := tree.root(); root != nil {
+			if lang, ok := root.attr("xml:lang"); !ok || isChineseLang(lang) && lang != language {
+				root.setAttr("xml:lang", language)
+				modified = true
+			}
+		}
+		if modified {
+			if err := p.writeEntry(ncxPath, tree.bytes()); err != nil {
+				return changed, err
+			}
+			changed = append(changed, ncxPath)
+		}
+	}
+
+	metadataChanged, err := p.convertMetadataChinese(dicts, language)
+	if err != nil {
+		return changed, err
+	}
+	if metadataChanged {
+		changed = append(changed, p.opfPath)
+	}
+	return changed, nil
+}
+
+// convertHTMLChinese 转换文本节点与 alt/title 属性，并更新 lang/xml:lang，返回是否有改动
+func convertHTMLChinese(doc *html.Node, dicts []*chineseDict, language string) bool {
+	changed := false
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			if converted := convertChinese(n.Data, dicts); converted != n.Data {
+				n.Data = converted
+				changed = true
+			}
+			return
+		case html.ElementNode:
+			if n.DataAtom == atom.Script || n.DataAtom == atom.Style {
+				return
+			}
+			for i, attr := range n.Attr {
+				key := attr.Key
+				if attr.Namespace != "" {
+					key = attr.Namespace + ":" + attr.Key
+				}
+				var converted string
+				switch key {
+				case "alt", "title":
+					converted = convertChinese(attr.Val, dicts)
+				case "lang", "xml:lang":
+					converted = attr.Val
+					if isChineseLang(attr.Val) {
+						converted = language
+					}
+				default:
+					continue
+				}
+				if converted != attr.Val {
+					n.Attr[i].Val = converted
+					changed = true
+				}
+			}
+			if n.DataAtom == atom.Html {
+				for _, key := range []string{"xml:lang", "lang"} {
+					if attrValue(n, key) == "" {
+						setAttr(n, key, language)
+						changed = true
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return changed
+}
+
+// convertXMLChinese 转换 match 为 true 的元素内的文本，并更新以 zh 开头的 xml:lang，返回是否有改动
+func convertXMLChinese(n *xmlNode, dicts []*chineseDict, language string, match func(*xmlNode) bool) bool {
+	changed := false
+	for _, c := range n.children {
+		switch c.kind {
+		case xmlTextNode:
+			if !match(n) {
+				continue
+			}
+			if converted := convertChinese(c.data, dicts); converted != c.data {
+				c.data = converted
+				changed = true
+			}
+		case xmlElementNode:
+			if lang, ok := c.attr("xml:lang"); ok && isChineseLang(lang) && lang != language {
+				c.setAttr("xml:lang", language)
+				changed = true
+			}
+			if convertXMLChinese(c, dicts, language, match) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// convertMetadataChinese 转换 dc:title 与 dc:creator，设置 dc:language 与 package 的 xml:lang
+func (p *Epub) convertMetadataChinese(dicts []*chineseDict, language string) (bool, error) {
+	nodes, err := parseXMLFragment(p.opfDoc.Metadata.InnerXML)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse metadata: %w", err)
+	}
+	metadata := &xmlNode{kind: xmlElementNode, children: nodes}
+	changed := convertXMLChinese(metadata, dicts, language, func(n *xmlNode) bool {
+		local := n.localName()
+		return local == "title" || local == "creator"
+	})
+
+	hasLanguage := false
+	for _, c := range metadata.children {
+		if c.kind != xmlElementNode || c.localName() != "language" {
+			continue
+		}
+		if !hasLanguage {
+			hasLanguage = true
+			if strings.TrimSpace(c.text()) != language {
+				c.children = []*xmlNode{{kind: xmlTextNode, data: language}}
+				changed = true
+			}
+		}
+	}
+	if changed {
+		p.opfDoc.Metadata.InnerXML = metadata.innerBytes()
+	}
+	if !hasLanguage {
+		p.appendMetadata("<dc:language>" + html.EscapeString(language) + "</dc:language>")
+		changed = true
+	}
+	if p.opfDoc.XMLLang != "" && isChineseLang(p.opfDoc.XMLLang) && p.opfDoc.XMLLang != language {
+		p.opfDoc.XMLLang = language
+		changed = true
+	}
+	return changed, nil
+}
+
+// isChineseLang 判断语言标签是否为中文（zh、zh-CN、zh-Hant 等）
+func isChineseLang(lang string) bool {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	return lang == "zh" || strings.HasPrefix(lang, "zh-") || strings.HasPrefix(lang, "zh_")
+}
+
+// chineseConverter 返回 mode 对应的词典链，首次使用时从内嵌文件加载
+func chineseConverter(mode string) ([]*chineseDict, error) {
+	config, ok := chineseModes[mode]
+	if !ok {
+		return nil, fmt.Errorf("unsupported chinese conversion: %q", mode)
+	}
+	chineseConvertersMu.Lock()
+	defer chineseConvertersMu.Unlock()
+	if dicts, ok := chineseConverters[mode]; ok {
+		return dicts, nil
+	}
+	dicts := make([]*chineseDict, 0, len(config.groups))
+	for _, group := range config.groups {
+		dict := &chineseDict{entries: make(map[string]string)}
+		for _, name := range group {
+			if err := dict.load("dict/" + name + ".txt"); err != nil {
+				return nil, err
+			}
+		}
+		dicts = append(dicts, dict)
+	}
+	chineseConverters[mode] = dicts
+	return dicts, nil
+}
+
+// load 读取 OpenCC 格式的词典：键与候选以 Tab 分隔，多个候选以空格分隔，取第一个候选；
+// 已存在的键不会被覆盖，因此同一组中先加载的词组优先于单字
+func (d *chineseDict) load(name string) error {
+	data, err := chineseDictFS.ReadFile(name)
+	if err != nil {
+		return fmt.Errorf("failed to load dictionary %s: %w", name, err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, values, ok := strings.Cut(line, "\t")
+		fields := strings.Fields(values)
+		if !ok || key == "" || len(fields) == 0 {
+			return fmt.Errorf("invalid dictionary line in %s: %q", name, line)
+		}
+		if _, exists := d.entries[key]; exists {
+			continue
+		}
+		d.entries[key] = fields[0]
+		if n := utf8.RuneCountInString(key); n > d.maxLen {
+			d.maxLen = n
+		}
+	}
+	return scanner.Err()
+}
+
+// convert 从左到右按最长匹配替换词条
+func (d *chineseDict) convert(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(runes); {
+		matched := false
+		for n := min(d.maxLen, len(runes)-i); n > 0; n-- {
+			if v, ok := d.entries[string(runes[i:i+n])]; ok {
+				b.WriteString(v)
+				i += n
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteRune(runes[i])
+			i++
+		}
+	}
+	return b.String()
+}
+
+func convertChinese(s string, dicts []*chineseDict) string {
+	if !hasHan(s) {
+		return s
+	}
+	for _, d := range dicts {
+		s = d.convert(s)
+	}
+	return s
+}
+
+func hasHan(s string) bool {
+	for _, r := range s {
+		if r >= 0x2E80 {
+			return true
+		}
+	}
+	return false
+}
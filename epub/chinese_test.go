@@ -0,0 +1,104 @@
+package epub
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// 内置词典的最少词条数，词典被截断或误删时测试失败；替换为 OpenCC 完整词典后只会更多
+func TestChineseDictionaries(t *testing.T) {
+	tests := []struct {
+		name       string
+		minEntries int
+	}{
+		{"STCharacters", 1296},
+		{"STPhrases", 300},
+		{"TSCharacters", 1332},
+		{"TSPhrases", 11},
+		{"TWPhrases", 30},
+		{"TWVariants", 2},
+		{"HKVariants", 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dict := &chineseDict{entries: make(map[string]string)}
+			if err := dict.load("dict/" + tt.name + ".txt"); err != nil {
+				t.Fatal(err)
+			}
+			if len(dict.entries) < tt.minEntries {
+				t.Errorf("%s has %d entries, want at least %d", tt.name, len(dict.entries), tt.minEntries)
+			}
+		})
+	}
+}
+
+func TestConvertChineseText(t *testing.T) {
+	tests := []struct {
+		mode string
+		in   string
+		want string
+	}{
+		{ConvertS2T, "头发发展", "頭髮發展"},
+		{ConvertS2T, "干净的干部", "乾淨的幹部"},
+		{ConvertS2T, "皇后之后", "皇后之後"},
+		{ConvertS2T, "面条方面", "麵條方面"},
+		{ConvertS2T, "台风过后的台湾", "颱風過後的臺灣"},
+		{ConvertS2T, "上瘾的鳄鱼干瘪了", "上癮的鱷魚乾癟了"},
+		{ConvertS2T, "abc 123", "abc 123"},
+		{ConvertS2TW, "这里", "這裡"},
+		{ConvertS2TWP, "软件", "軟體"},
+		{ConvertS2HK, "线", "綫"},
+		{ConvertT2S, "頭髮發展乾淨幹部", "头发发展干净干部"},
+		{ConvertT2S, "這裡上癮", "这里上瘾"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode+" "+tt.in, func(t *testing.T) {
+			got, err := ConvertChineseText(tt.in, tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ConvertChineseText(%q, %s) = %q, want %q", tt.in, tt.mode, got, tt.want)
+			}
+		})
+	}
+	if _, err := ConvertChineseText("x", "s2x"); err == nil {
+		t.Error("ConvertChineseText with an unknown mode succeeded")
+	}
+}
+
+func TestConvertChinese(t *testing.T) {
+	opf := strings.NewReplacer("<dc:title>Test</dc:title>", "<dc:title>头发的故事</dc:title>",
+		"<dc:language>en</dc:language>", "<dc:language>zh-CN</dc:language>").Replace(testOPF)
+	chapter := `<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="zh-CN"><head><title>第一章</title></head>` +
+		`<body><p title="后面">发展之后</p><img src="a.png" alt="面条"/><script>var s = "头发";</script></body></html>`
+
+	book, err := Open(writeTestEPUB(t, opf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := book.writeEntry("OEBPS/Text/ch1.xhtml", []byte(chapter)); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := book.ConvertChinese(ChineseConvertOptions{Mode: ConvertS2T})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"OEBPS/Text/ch1.xhtml", "OEBPS/content.opf"} {
+		if !slices.Contains(changed, name) {
+			t.Errorf("changed files %v do not include %s", changed, name)
+		}
+	}
+
+	data, _ := book.ReadFile("OEBPS/Text/ch1.xhtml")
+	for _, want := range []string{`發展之後`, `title="後面"`, `alt="麵條"`, `var s = "头发";`, `xml:lang="zh-Hant"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("chapter missing %q:\n%s", want, data)
+		}
+	}
+	if info := book.Info(); info.Title != "頭髮的故事" || info.Language != "zh-Hant" {
+		t.Errorf("metadata = (%q, %q), want (頭髮的故事, zh-Hant)", info.Title, info.Language)
+	}
+}
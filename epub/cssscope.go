@@ -0,0 +1,247 @@
+package epub
+
+import (
+	"strings"
+)
+
+// scopeCSS 将样式表中的选择器限定在 scope（如 "#chapter-1"、".epub-css-1"）之内：
+// html、body 与 :root 对应 scope 本身，其余选择器成为 scope 的后代，#id 由 id 改写为导出后的选择器；
+// @media、@supports 等条件规则递归处理，@font-face、@keyframes、@page 等保持不变
+func scopeCSS(text, scope string, id func(string) string) string {
+	var out strings.Builder
+	for i := 0; i < len(text); {
+		if isCSSSpace(text[i]) {
+			out.WriteByte(text[i])
+			i++
+			continue
+		}
+		if strings.HasPrefix(text[i:], "/*") {
+			end := strings.Index(text[i+2:], "*/")
+			if end < 0 {
+				out.WriteString(text[i:])
+				break
+			}
+			out.WriteString(text[i : i+end+4])
+			i += end + 4
+			continue
+		}
+		stop := scanCSS(text, i, "{;}")
+		if stop >= len(text) {
+			out.WriteString(text[i:])
+			break
+		}
+		if text[stop] != '{' {
+			out.WriteString(text[i : stop+1])
+			i = stop + 1
+			continue
+		}
+		prelude := text[i:stop]
+		end := matchingBrace(text, stop)
+		block := text[stop+1 : end]
+		switch {
+		case isConditionalAtRule(prelude):
+			block = scopeCSS(block, scope, id)
+		case !strings.HasPrefix(prelude, "@"):
+			trimmed := strings.TrimRight(prelude, " \t\r\n\f")
+			prelude = scopeSelectorList(trimmed, scope, id) + prelude[len(trimmed):]
+		}
+		out.WriteString(prelude + "{" + block + "}")
+		i = end + 1
+	}
+	return out.String()
+}
+
+// scopeSelectorList 对逗号分隔的每个选择器执行 scopeSelector
+func scopeSelectorList(list, scope string, id func(string) string) string {
+	var parts []string
+	for start := 0; start <= len(list); {
+		end := scanCSS(list, start, ",")
+		if sel := strings.TrimSpace(list[start:end]); sel != "" {
+			parts = append(parts, scopeSelector(sel, scope, id))
+		}
+		start = end + 1
+	}
+	return strings.Join(parts, ", ")
+}
+
+// scopeSelector 去掉选择器开头的 html、body 与 :root（保留其上的 class 等条件）后接在 scope 之后
+func scopeSelector(sel, scope string, id func(string) string) string {
+	sel = rewriteCSSIDs(sel, id)
+	rest, suffix, combinator := sel, "", ""
+	stripped := false
+	for rest != "" {
+		end := scanCSS(rest, 0, " \t\r\n\f>+~")
+		typ, tail := splitTypeSelector(rest[:end])
+		if !strings.EqualFold(typ, "html") && !strings.EqualFold(typ, "body") && !strings.EqualFold(typ, ":root") {
+			break
+		}
+		stripped = true
+		suffix += tail
+		rest = strings.TrimLeft(rest[end:], " \t\r\n\f")
+		combinator = ""
+		if rest != "" && strings.IndexByte(">+~", rest[0]) >= 0 {
+			combinator = rest[:1]
+			rest = strings.TrimLeft(rest[1:], " \t\r\n\f")
+		}
+	}
+	switch {
+	case !stripped:
+		return scope + " " + sel
+	case rest == "":
+		return scope + suffix
+	case combinator == "":
+		return scope + suffix + " " + rest
+	}
+	return scope + suffix + " " + combinator + " " + rest
+}
+
+// splitTypeSelector 将复合选择器拆分为开头的类型选择器（或 :root）与其后的部分
+func splitTypeSelector(compound string) (string, string) {
+	if len(compound) >= 5 && strings.EqualFold(compound[:5], ":root") && (len(compound) == 5 || !isCSSNameByte(compound[5])) {
+		return compound[:5], compound[5:]
+	}
+	i := 0
+	for i < len(compound) && isCSSNameByte(compound[i]) {
+		i++
+	}
+	return compound[:i], compound[i:]
+}
+
+// rewriteCSSIDs 将选择器中的 #id（方括号与引号内的除外）替换为 id 的返回值
+func rewriteCSSIDs(sel string, id func(string) string) string {
+	var out strings.Builder
+	var quote byte
+	depth := 0
+	for i := 0; i < len(sel); i++ {
+		c := sel[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && i+1 < len(sel) {
+				out.WriteByte(c)
+				i++
+				c = sel[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']' && depth > 0:
+			depth--
+		case c == '#' && depth == 0:
+			end := cssIdentEnd(sel, i+1)
+			if end > i+1 {
+				out.WriteString(id(sel[i+1 : end]))
+				i = end - 1
+				continue
+			}
+		}
+		out.WriteByte(c)
+	}
+	return out.String()
+}
+
+// cssIdentEnd 返回从 start 开始的 CSS 标识符（含转义）的结束位置
+func cssIdentEnd(s string, start int) int {
+	i := start
+	for i < len(s) {
+		switch {
+		case isCSSNameByte(s[i]):
+			i++
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+			hex := 0
+			for hex < 6 && i < len(s) && isHexDigit(s[i]) {
+				i++
+				hex++
+			}
+			if hex == 0 {
+				i++
+			} else if i < len(s) && isCSSSpace(s[i]) {
+				i++
+			}
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// scanCSS 返回从 start 开始第一个位于括号、引号与注释之外且属于 stops 的字节的位置，没有时返回 len(s)
+func scanCSS(s string, start int, stops string) int {
+	var quote byte
+	depth := 0
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '/' && i+1 < len(s) && s[i+1] == '*':
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return len(s)
+			}
+			i += end + 3
+		case c == '(' || c == '[':
+			depth++
+		case (c == ')' || c == ']') && depth > 0:
+			depth--
+		case depth == 0 && strings.IndexByte(stops, c) >= 0:
+			return i
+		}
+	}
+	return len(s)
+}
+
+// matchingBrace 返回与 s[open] 处的 '{' 匹配的 '}' 的位置，未闭合时返回 len(s)
+func matchingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); {
+		end := scanCSS(s, i, "{}")
+		if end >= len(s) {
+			return len(s)
+		}
+		if s[end] == '{' {
+			depth++
+		} else if depth--; depth == 0 {
+			return end
+		}
+		i = end + 1
+	}
+	return len(s)
+}
+
+// isConditionalAtRule 判断 at 规则的块中是否为普通的样式规则
+func isConditionalAtRule(prelude string) bool {
+	if !strings.HasPrefix(prelude, "@") {
+		return false
+	}
+	name := prelude[1:]
+	if end := strings.IndexFunc(name, func(r rune) bool { return r >= 0x80 || !isCSSNameByte(byte(r)) }); end >= 0 {
+		name = name[:end]
+	}
+	switch strings.ToLower(name) {
+	case "media", "supports", "document", "-moz-document", "layer", "container":
+		return true
+	}
+	return false
+}
+
+func isCSSNameByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c >= 0x80
+}
+
+func isCSSSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
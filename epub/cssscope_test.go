@@ -0,0 +1,36 @@
+package epub
+
+import "testing"
+
+func TestScopeCSS(t *testing.T) {
+	tests := []struct {
+		name     string
+		css      string
+		chapters []int
+		want     string
+	}{
+		{"descendant", "p { color: red }", []int{1}, ".s p { color: red }"},
+		{"selector list", "h1, h2>a{}", []int{1}, ".s h1, .s h2>a{}"},
+		{"body", "body { margin: 0 }", []int{1}, ".s { margin: 0 }"},
+		{"body class", "body.x p {}", []int{1}, ".s.x p {}"},
+		{"html body child", "html > body > div {}", []int{1}, ".s > div {}"},
+		{"root", ":root { --a: 1 }", []int{1}, ".s { --a: 1 }"},
+		{"id", "#note {}", []int{2}, ".s #c2-note {}"},
+		{"shared id", "a:not(#x) {}", []int{1, 3}, ".s a:not(:is(#c1-x, #c3-x)) {}"},
+		{"escaped id", `#\31 23 {}`, []int{1}, `.s #c1-\31 23 {}`},
+		{"quoted comma", `a[title="a, #b {"] {}`, []int{1}, `.s a[title="a, #b {"] {}`},
+		{"media", "@media print { body p { x: y } }", []int{1}, "@media print { .s p { x: y } }"},
+		{"font face", "@font-face { src: url(a.ttf) }", []int{1}, "@font-face { src: url(a.ttf) }"},
+		{"keyframes", "@keyframes k { from { a: b } }", []int{1}, "@keyframes k { from { a: b } }"},
+		{"statement", `@import "a.css"; p {}`, []int{1}, `@import "a.css"; .s p {}`},
+		{"comment", "/* p {} */ p {}", []int{1}, "/* p {} */ .s p {}"},
+		{"string with brace", `p::before { content: "}" } i {}`, []int{1}, `.s p::before { content: "}" } .s i {}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scopeCSS(tt.css, ".s", chapterIDSelector(tt.chapters)); got != tt.want {
+				t.Errorf("scopeCSS(%q) = %q, want %q", tt.css, got, tt.want)
+			}
+		})
+	}
+}
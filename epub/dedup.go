@@ -0,0 +1,183 @@
+package epub
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/weiweimhy/go-utils/customUtils"
+)
+
+var coverMetaRegex = regexp.MustCompile(`(?is)<meta\s[^>]*name\s*=\s*["']cover["'][^>]*>`)
+var metaContentRegex = regexp.MustCompile(`(?is)\scontent\s*=\s*["']([^"']*)["']`)
+
+// DuplicateGroup 描述一组内容相同的资源
+type DuplicateGroup struct {
+	Hash    string   `json:"hash"`
+	Kept    string   `json:"kept"`    // 保留的文件（zip 内路径）
+	Removed []string `json:"removed"` // 被删除并指向 Kept 的文件
+	Size    int      `json:"size"`    // 单个文件的大小
+}
+
+// DedupResult 为 Deduplicate 的结果
+type DedupResult struct {
+	Groups     []DuplicateGroup `json:"groups"`
+	SavedBytes int64            `json:"savedBytes"` // 删除的未压缩字节数
+}
+
+// Deduplicate 按内容哈希合并重复的资源文件（图片、字体、样式等，不含章节与导航文件）：
+// 每组保留一个文件，把 XHTML、CSS、NCX 与 OPF 中的引用改写为保留的文件后删除其余副本；
+// 含相对 url()/@import 的样式表只在同一目录内合并
+func (p *Epub) Deduplicate() (*DedupResult, error) {
+	result := &DedupResult{}
+	if p.opfDoc == nil {
+		return result, nil
+	}
+
+	manifest := make(map[string]*opfManifestItem)
+	for i := range p.opfDoc.Manifest.Items {
+		item := &p.opfDoc.Manifest.Items[i]
+		manifest[p.pathFromHref(item.Href)] = item
+	}
+	protected := make(map[string]bool)
+	for _, name := range p.protection.EncryptedFiles {
+		protected[name] = true
+	}
+	for _, name := range p.protection.ObfuscatedFonts {
+		protected[name] = true
+	}
+	coverID := p.coverMetaID()
+
+	groups := make(map[string][]*zipEntry)
+	var keys []string
+	for _, entry := range p.entries {
+		if entry.removed || entry.isDir || len(entry.data) == 0 {
+			continue
+		}
+		norm := normalizeZipPath(entry.header.Name)
+		if !p.isDedupCandidate(entry, norm) || protected[norm] {
+			continue
+		}
+		hash := customUtils.BytesToHash(entry.data)
+		// 含相对引用的样式表只与同目录的副本合并，否则其中的 url()/@import 会指向别处
+		key := hash
+		if strings.EqualFold(path.Ext(norm), ".css") && hasRelativeCSSRefs(entry.data) {
+			key = hash + "\x00" + path.Dir(norm)
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], entry)
+	}
+
+	mapping := make(map[string]string)
+	for _, key := range keys {
+		entries := groups[key]
+		if len(entries) < 2 {
+			continue
+		}
+		// 优先保留带 properties（如 cover-image）或被 <meta name="cover"> 引用的文件
+		keep := 0
+		for i, entry := range entries {
+			if item := manifest[normalizeZipPath(entry.header.Name)]; item != nil && (item.Properties != "" || item.ID == coverID) {
+				keep = i
+				break
+			}
+		}
+		group := DuplicateGroup{
+			Hash: customUtils.BytesToHash(entries[keep].data),
+			Kept: normalizeZipPath(entries[keep].header.Name),
+			Size: len(entries[keep].data),
+		}
+		for i, entry := range entries {
+			if i == keep {
+				continue
+			}
+			norm := normalizeZipPath(entry.header.Name)
+			group.Removed = append(group.Removed, norm)
+			mapping[norm] = group.Kept
+			result.SavedBytes += int64(len(entry.data))
+		}
+		sort.Strings(group.Removed)
+		result.Groups = append(result.Groups, group)
+	}
+	if len(mapping) == 0 {
+		return result, nil
+	}
+
+	if err := p.rewriteReferences(mapping); err != nil {
+		return nil, err
+	}
+	p.mergeManifestItems(mapping, manifest)
+
+	for _, group := range result.Groups {
+		for _, name := range group.Removed {
+			if err := p.removeEntry(name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// isDedupCandidate 判断文件是否参与去重：排除章节、导航、OPF 与 META-INF 下的文件
+func (p *Epub) isDedupCandidate(entry *zipEntry, norm string) bool {
+	if norm == "mimetype" || strings.HasPrefix(norm, "META-INF/") || p.isOPFEntry(entry) || isHTMLEntry(entry) {
+		return false
+	}
+	return !strings.EqualFold(path.Ext(norm), ".ncx")
+}
+
+// hasRelativeCSSRefs 判断样式表中是否有相对路径的 url() 或 @import
+func hasRelativeCSSRefs(data []byte) bool {
+	for _, re := range []*regexp.Regexp{cssURLRegex, cssImportRegex} {
+		for _, m := range re.FindAllSubmatch(data, -1) {
+			ref := strings.TrimSpace(string(m[2]))
+			if ref != "" && !strings.HasPrefix(ref, "#") && !isExternalRef(ref) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mergeManifestItems 把被删除条目的 properties 合并到保留的条目，并改写指向被删除条目的 fallback
+func (p *Epub) mergeManifestItems(mapping map[string]string, manifest map[string]*opfManifestItem) {
+	ids := make(map[string]string)
+	for oldNorm, newNorm := range mapping {
+		removed, kept := manifest[oldNorm], manifest[newNorm]
+		if removed == nil || kept == nil {
+			continue
+		}
+		if removed.ID != "" {
+			ids[removed.ID] = kept.ID
+		}
+		for _, prop := range strings.Fields(removed.Properties) {
+			if !hasProperty(kept.Properties, prop) {
+				kept.Properties = strings.TrimSpace(kept.Properties + " " + prop)
+			}
+		}
+	}
+	for i := range p.opfDoc.Manifest.Items {
+		item := &p.opfDoc.Manifest.Items[i]
+		if id, ok := ids[item.Fallback]; ok {
+			item.Fallback = id
+		}
+	}
+	if id, ok := ids[p.coverMetaID()]; ok {
+		inner := string(p.opfDoc.Metadata.InnerXML)
+		loc := coverMetaRegex.FindStringIndex(inner)
+		meta := metaContentRegex.ReplaceAllString(inner[loc[0]:loc[1]], ` content="`+id+`"`)
+		p.opfDoc.Metadata.InnerXML = []byte(inner[:loc[0]] + meta + inner[loc[1]:])
+	}
+}
+
+// coverMetaID 返回 EPUB2 <meta name="cover" content="..."> 指向的 manifest id
+func (p *Epub) coverMetaID() string {
+	meta := coverMetaRegex.Find(p.opfDoc.Metadata.InnerXML)
+	if m := metaContentRegex.FindSubmatch(meta); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
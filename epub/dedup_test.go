@@ -0,0 +1,88 @@
+package epub
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDeduplicate(t *testing.T) {
+	const (
+		png     = "\x89PNG\r\n\x1a\nsame image"
+		fontCSS = "@font-face { src: url(../Fonts/f.ttf); }"
+		plain   = "p { margin: 0; }"
+	)
+	opf := strings.Replace(testOPF, `<item id="ch1" href="Text/ch1.xhtml" media-type="application/xhtml+xml"/>`,
+		`<item id="ch1" href="Text/ch1.xhtml" media-type="application/xhtml+xml"/>
+    <item id="img-a" href="Images/a.png" media-type="image/png"/>
+    <item id="img-b" href="Images/b.png" media-type="image/png" properties="cover-image"/>
+    <item id="img-c" href="Images/c.png" media-type="image/png"/>
+    <item id="font-1" href="Styles/font.css" media-type="text/css"/>
+    <item id="font-2" href="Extra/font.css" media-type="text/css"/>
+    <item id="plain-1" href="Styles/plain.css" media-type="text/css"/>
+    <item id="plain-2" href="Extra/plain.css" media-type="text/css"/>
+    <item id="fallback" href="Images/x.svg" media-type="image/svg+xml" fallback="img-a"/>`, 1)
+	chapter := `<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml"><head><title>Chapter</title>` +
+		`<link rel="stylesheet" href="../Styles/plain.css"/><link rel="stylesheet" href="../Extra/plain.css"/></head>` +
+		`<body><img src="../Images/a.png"/><img src="../Images/b.png"/><img src="../Images/c.png"/></body></html>`
+	book, err := Open(writeTestEPUB(t, opf,
+		testFile{"OEBPS/Images/a.png", png},
+		testFile{"OEBPS/Images/b.png", png},
+		testFile{"OEBPS/Images/c.png", png + " but different"},
+		testFile{"OEBPS/Images/x.svg", "<svg/>"},
+		testFile{"OEBPS/Styles/font.css", fontCSS},
+		testFile{"OEBPS/Extra/font.css", fontCSS},
+		testFile{"OEBPS/Styles/plain.css", plain},
+		testFile{"OEBPS/Extra/plain.css", plain},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := book.writeEntry("OEBPS/Text/ch1.xhtml", []byte(chapter)); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := book.Deduplicate()
+	if err != nil {
+		t.Fatalf("Deduplicate() error = %v", err)
+	}
+	var groups []string
+	for _, g := range result.Groups {
+		groups = append(groups, g.Kept+" <- "+strings.Join(g.Removed, ","))
+	}
+	// 带 cover-image 的图片被保留；含相对 url() 的样式表不跨目录合并
+	want := []string{"OEBPS/Images/b.png <- OEBPS/Images/a.png", "OEBPS/Styles/plain.css <- OEBPS/Extra/plain.css"}
+	if !slices.Equal(groups, want) {
+		t.Errorf("Deduplicate() groups = %q, want %q", groups, want)
+	}
+	if result.SavedBytes != int64(len(png)+len(plain)) {
+		t.Errorf("SavedBytes = %d, want %d", result.SavedBytes, len(png)+len(plain))
+	}
+
+	for _, removed := range []string{"OEBPS/Images/a.png", "OEBPS/Extra/plain.css"} {
+		if _, ok := book.ReadFile(removed); ok {
+			t.Errorf("%s was not removed", removed)
+		}
+	}
+	data, _ := book.ReadFile("OEBPS/Text/ch1.xhtml")
+	if strings.Contains(string(data), "a.png") || strings.Count(string(data), `src="../Images/b.png"`) != 2 ||
+		strings.Count(string(data), `href="../Styles/plain.css"`) != 2 {
+		t.Errorf("chapter references not rewritten:\n%s", data)
+	}
+
+	var items []string
+	for _, item := range book.opfDoc.Manifest.Items {
+		items = append(items, item.ID)
+		if item.ID == "fallback" && item.Fallback != "img-b" {
+			t.Errorf("fallback = %q, want it pointed at the kept image", item.Fallback)
+		}
+	}
+	if slices.Contains(items, "img-a") || slices.Contains(items, "plain-2") || !slices.Contains(items, "font-2") {
+		t.Errorf("manifest = %q, want only the duplicates removed", items)
+	}
+
+	if result, err := book.Deduplicate(); err != nil || len(result.Groups) != 0 {
+		t.Errorf("second Deduplicate() = %+v, %v, want nothing to merge", result, err)
+	}
+}
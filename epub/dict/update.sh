@@ -0,0 +1,13 @@
+#!/bin/sh
+# 从 OpenCC 下载固定版本的词典替换本目录下的文件，用法：sh update.sh [版本标签]
+set -eu
+
+version="${1:-ver.1.1.9}"
+base="https://raw.githubusercontent.com/BYVoid/OpenCC/${version}"
+cd "$(dirname "$0")"
+
+for name in STCharacters STPhrases TSCharacters TSPhrases TWVariants TWPhrases HKVariants; do
+	curl -fsSL "${base}/data/dictionary/${name}.txt" -o "${name}.txt.tmp"
+	mv "${name}.txt.tmp" "${name}.txt"
+done
+curl -fsSL "${base}/LICENSE" -o LICENSE
@@ -0,0 +1,284 @@
+package epub
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// 变更类型
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// EntryChange 描述单个文件的变化；章节的文本差异以 unified diff 给出
+type EntryChange struct {
+	Name       string `json:"name"`
+	Change     string `json:"change"`
+	SizeA      int    `json:"sizeA"`
+	SizeB      int    `json:"sizeB"`
+	TextDiff   string `json:"textDiff,omitempty"`
+	MarkupOnly bool   `json:"markupOnly,omitempty"` // 章节文本相同，只有标记变化
+}
+
+// ManifestChange 描述 manifest 条目的变化，按 id 对应
+type ManifestChange struct {
+	ID     string   `json:"id"`
+	Change string   `json:"change"`
+	Href   string   `json:"href"`
+	Fields []string `json:"fields,omitempty"` // modified 时变化的属性
+}
+
+// FieldChange 描述元数据字段的变化，多值字段以 "; " 连接
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// DiffResult 为 Diff 的结果
+type DiffResult struct {
+	Entries  []EntryChange    `json:"entries,omitempty"`
+	Manifest []ManifestChange `json:"manifest,omitempty"`
+	SpineA   []string         `json:"spineA,omitempty"` // spine 有变化时的新旧 idref 列表
+	SpineB   []string         `json:"spineB,omitempty"`
+	Metadata []FieldChange    `json:"metadata,omitempty"`
+}
+
+// Empty 返回两本书是否没有差异
+func (d *DiffResult) Empty() bool {
+	return len(d.Entries) == 0 && len(d.Manifest) == 0 && d.SpineA == nil && len(d.Metadata) == 0
+}
+
+// Diff 比较两本书的结构差异：增删改的文件、manifest 与 spine 的变化、元数据的变化。
+// OPF 本身不作为文件比较，其内容体现在 manifest、spine 与元数据的差异中
+func Diff(a, b *Epub) (*DiffResult, error) {
+	if a == nil || b == nil || a.opfDoc == nil || b.opfDoc == nil {
+		return nil, fmt.Errorf("both books must be opened")
+	}
+	result := &DiffResult{}
+	result.Entries = diffEntries(a, b)
+	result.Manifest = diffManifest(a, b)
+
+	spineA, spineB := spineIDRefs(a), spineIDRefs(b)
+	if strings.Join(spineA, "\x00") != strings.Join(spineB, "\x00") {
+		result.SpineA, result.SpineB = spineA, spineB
+	}
+
+	metaA, err := metadataFields(a)
+	if err != nil {
+		return nil, err
+	}
+	metaB, err := metadataFields(b)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool)
+	for key := range metaA {
+		keys[key] = true
+	}
+	for key := range metaB {
+		keys[key] = true
+	}
+	for _, key := range sortedKeys(keys) {
+		oldValue, newValue := strings.Join(metaA[key], "; "), strings.Join(metaB[key], "; ")
+		if oldValue != newValue {
+			result.Metadata = append(result.Metadata, FieldChange{Field: key, Old: oldValue, New: newValue})
+		}
+	}
+	return result, nil
+}
+
+func diffEntries(a, b *Epub) []EntryChange {
+	filesA, filesB := liveEntries(a), liveEntries(b)
+	names := make(map[string]bool)
+	for name := range filesA {
+		names[name] = true
+	}
+	for name := range filesB {
+		names[name] = true
+	}
+
+	var changes []EntryChange
+	for _, name := range sortedKeys(names) {
+		entryA, entryB := filesA[name], filesB[name]
+		switch {
+		case entryA == nil:
+			changes = append(changes, EntryChange{Name: name, Change: ChangeAdded, SizeB: len(entryB.data)})
+		case entryB == nil:
+			changes = append(changes, EntryChange{Name: name, Change: ChangeRemoved, SizeA: len(entryA.data)})
+		case !bytes.Equal(entryA.data, entryB.data):
+			change := EntryChange{Name: name, Change: ChangeModified, SizeA: len(entryA.data), SizeB: len(entryB.data)}
+			if isHTMLEntry(entryA) {
+				change.TextDiff = UnifiedDiff("a/"+name, "b/"+name,
+					HTMLToText(string(entryA.data)), HTMLToText(string(entryB.data)), 3)
+				change.MarkupOnly = change.TextDiff == ""
+			}
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+func liveEntries(p *Epub) map[string]*zipEntry {
+	files := make(map[string]*zipEntry)
+	for _, entry := range p.entries {
+		if entry.removed || entry.isDir {
+			continue
+		}
+		norm := normalizeZipPath(entry.header.Name)
+		if norm != p.opfPath {
+			files[norm] = entry
+		}
+	}
+	return files
+}
+
+func diffManifest(a, b *Epub) []ManifestChange {
+	itemsA := make(map[string]opfManifestItem)
+	for _, item := range a.opfDoc.Manifest.Items {
+		itemsA[item.ID] = item
+	}
+	itemsB := make(map[string]opfManifestItem)
+	for _, item := range b.opfDoc.Manifest.Items {
+		itemsB[item.ID] = item
+	}
+	ids := make(map[string]bool)
+	for id := range itemsA {
+		ids[id] = true
+	}
+	for id := range itemsB {
+		ids[id] = true
+	}
+
+	var changes []ManifestChange
+	for _, id := range sortedKeys(ids) {
+		itemA, okA := itemsA[id]
+		itemB, okB := itemsB[id]
+		switch {
+		case !okA:
+			changes = append(changes, ManifestChange{ID: id, Change: ChangeAdded, Href: itemB.Href})
+		case !okB:
+			changes = append(changes, ManifestChange{ID: id, Change: ChangeRemoved, Href: itemA.Href})
+		default:
+			var fields []string
+			for _, field := range []struct{ name, a, b string }{
+				{"href", itemA.Href, itemB.Href},
+				{"media-type", itemA.MediaType, itemB.MediaType},
+				{"properties", itemA.Properties, itemB.Properties},
+				{"fallback", itemA.Fallback, itemB.Fallback},
+			} {
+				if field.a != field.b {
+					fields = append(fields, field.name)
+				}
+			}
+			if len(fields) > 0 {
+				changes = append(changes, ManifestChange{ID: id, Change: ChangeModified, Href: itemB.Href, Fields: fields})
+			}
+		}
+	}
+	return changes
+}
+
+func spineIDRefs(p *Epub) []string {
+	refs := make([]string, 0, len(p.opfDoc.Spine.Items))
+	for _, item := range p.opfDoc.Spine.Items {
+		refs = append(refs, item.IDRef)
+	}
+	return refs
+}
+
+// metadataFields 将 <metadata> 展开为 字段 -> 值 列表：dc 元素以元素名为键，
+// <meta> 以 property 或 name 为键
+func metadataFields(p *Epub) (map[string][]string, error) {
+	nodes, err := parseXMLFragment(p.opfDoc.Metadata.InnerXML)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metadata: %w", err)
+	}
+	fields := make(map[string][]string)
+	for _, node := range nodes {
+		if node.kind != xmlElementNode {
+			continue
+		}
+		key, value := node.name, strings.TrimSpace(node.text())
+		if node.localName() == "meta" {
+			if property, ok := node.attr("property"); ok {
+				key = "meta:" + property
+			} else if name, ok := node.attr("name"); ok {
+				key = "meta:" + name
+				value, _ = node.attr("content")
+			}
+		}
+		fields[key] = append(fields[key], value)
+	}
+	if p.opfDoc.Version != "" {
+		fields["version"] = []string{p.opfDoc.Version}
+	}
+	return fields, nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WriteReport 输出便于阅读的文本报告
+func (d *DiffResult) WriteReport(w io.Writer) error {
+	var b strings.Builder
+	if d.Empty() {
+		b.WriteString("no differences\n")
+	}
+	if len(d.Metadata) > 0 {
+		b.WriteString("Metadata:\n")
+		for _, field := range d.Metadata {
+			fmt.Fprintf(&b, "  %s: %q -> %q\n", field.Field, field.Old, field.New)
+		}
+	}
+	if len(d.Manifest) > 0 {
+		b.WriteString("Manifest:\n")
+		for _, item := range d.Manifest {
+			fmt.Fprintf(&b, "  %-8s %s (%s)", item.Change, item.ID, item.Href)
+			if len(item.Fields) > 0 {
+				fmt.Fprintf(&b, " [%s]", strings.Join(item.Fields, ", "))
+			}
+			b.WriteString("\n")
+		}
+	}
+	if d.SpineA != nil {
+		b.WriteString("Spine:\n")
+		fmt.Fprintf(&b, "  - %s\n  + %s\n", strings.Join(d.SpineA, " "), strings.Join(d.SpineB, " "))
+	}
+	if len(d.Entries) > 0 {
+		b.WriteString("Files:\n")
+		for _, entry := range d.Entries {
+			switch entry.Change {
+			case ChangeAdded:
+				fmt.Fprintf(&b, "  added    %s (%d bytes)\n", entry.Name, entry.SizeB)
+			case ChangeRemoved:
+				fmt.Fprintf(&b, "  removed  %s (%d bytes)\n", entry.Name, entry.SizeA)
+			default:
+				fmt.Fprintf(&b, "  modified %s (%d -> %d bytes)", entry.Name, entry.SizeA, entry.SizeB)
+				if entry.MarkupOnly {
+					b.WriteString(", markup only")
+				}
+				b.WriteString("\n")
+			}
+		}
+		for _, entry := range d.Entries {
+			if entry.TextDiff != "" {
+				b.WriteString("\n")
+				b.WriteString(entry.TextDiff)
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
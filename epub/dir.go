@@ -0,0 +1,313 @@
+package epub
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const epubMimetype = "application/epub+zip"
+
+// Extract 将当前内容（包括尚未保存的修改）解压到目录，保留 zip 中记录的文件权限与修改时间
+func (p *Epub) Extract(dir string) error {
+	if dir == "" {
+		return fmt.Errorf("output directory cannot be empty")
+	}
+	if err := p.flushOPF(); err != nil {
+		return err
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	type dirTime struct {
+		path    string
+		modTime time.Time
+	}
+	var dirTimes []dirTime
+	for _, entry := range p.entries {
+		if entry.removed {
+			continue
+		}
+		if err := checkEntryPath(entry.header.Name); err != nil {
+			return err
+		}
+		norm := normalizeZipPath(entry.header.Name)
+		if norm == "." {
+			continue
+		}
+		target := filepath.Join(root, filepath.FromSlash(norm))
+		if !strings.HasPrefix(target, root+string(os.PathSeparator)) {
+			return &UnsafePathError{Name: entry.header.Name, Reason: "path traversal"}
+		}
+
+		if entry.isDir {
+			if err := os.MkdirAll(target, dirMode(entry.header.Mode())); err != nil {
+				return fmt.Errorf("failed to create directory (%s): %w", norm, err)
+			}
+			if !entry.header.Modified.IsZero() {
+				dirTimes = append(dirTimes, dirTime{target, entry.header.Modified})
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return fmt.Errorf("failed to create directory (%s): %w", norm, err)
+		}
+		if err := os.WriteFile(target, entry.data, fileMode(entry.header.Mode())); err != nil {
+			return fmt.Errorf("failed to write file (%s): %w", norm, err)
+		}
+		// WriteFile 不会修改已存在文件的权限
+		if err := os.Chmod(target, fileMode(entry.header.Mode())); err != nil {
+			return fmt.Errorf("failed to set file mode (%s): %w", norm, err)
+		}
+		if modified := entry.header.Modified; !modified.IsZero() {
+			if err := os.Chtimes(target, modified, modified); err != nil {
+				return fmt.Errorf("failed to set file time (%s): %w", norm, err)
+			}
+		}
+	}
+	// 目录的修改时间在写入文件后才能设置，由深到浅处理
+	for i := len(dirTimes) - 1; i >= 0; i-- {
+		_ = os.Chtimes(dirTimes[i].path, dirTimes[i].modTime, dirTimes[i].modTime)
+	}
+	return nil
+}
+
+// OpenDir 从解压后的目录构建 Epub，文件权限与修改时间会写入 zip 头；
+// mimetype 固定放在第一个且不压缩，目录中缺少时自动补上，以 . 开头的文件与目录会被忽略
+func OpenDir(dir string) (*Epub, error) {
+	return OpenDirWithOptions(dir, OpenOptions{})
+}
+
+// OpenDirWithOptions 与 OpenDir 相同，但会按 opts 检查 DRM 与大小限制
+func OpenDirWithOptions(dir string, opts OpenOptions) (*Epub, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", dir)
+	}
+
+	var names []string
+	err = filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if file != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ri, rj := dirEntryRank(names[i]), dirEntryRank(names[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return names[i] < names[j]
+	})
+
+	limits := newOpenLimits(opts)
+	if limits.entries > 0 && len(names) > limits.entries {
+		return nil, &LimitError{Kind: LimitEntryCount, Limit: float64(limits.entries), Value: float64(len(names))}
+	}
+
+	p := &Epub{
+		entryIndex: make(map[string]*zipEntry),
+	}
+	if len(names) == 0 || names[0] != "mimetype" {
+		header := zip.FileHeader{Name: "mimetype", Method: zip.Store, Modified: time.Now()}
+		if err := p.addLoadedEntry(&zipEntry{header: header, data: []byte(epubMimetype)}); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range names {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat file (%s): %w", name, err)
+		}
+		if limits.entrySize > 0 && info.Size() > limits.entrySize {
+			return nil, &LimitError{Kind: LimitEntrySize, Name: name, Limit: float64(limits.entrySize), Value: float64(info.Size())}
+		}
+		limits.read += info.Size()
+		if limits.totalSize > 0 && limits.read > limits.totalSize {
+			return nil, &LimitError{Kind: LimitTotalSize, Name: name, Limit: float64(limits.totalSize), Value: float64(limits.read)}
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file (%s): %w", name, err)
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zip header (%s): %w", name, err)
+		}
+		header.Name = name
+		header.Method = zip.Deflate
+		if name == "mimetype" {
+			header.Method = zip.Store
+		}
+		if err := p.addLoadedEntry(&zipEntry{header: *header, data: data}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.finishLoad(opts); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// dirEntryRank 让 mimetype 排在第一个，META-INF 紧随其后
+func dirEntryRank(name string) int {
+	switch {
+	case name == "mimetype":
+		return 0
+	case strings.HasPrefix(name, "META-INF/"):
+		return 1
+	}
+	return 2
+}
+
+// WatchOptions 控制 WatchDir 的行为
+type WatchOptions struct {
+	Interval time.Duration // 轮询间隔，默认 1 秒
+	Open     OpenOptions
+	// OnBuild 在每次重新打包后调用，err 为 nil 表示成功；打包失败不会中止监听
+	OnBuild func(err error)
+}
+
+// WatchDir 先打包一次目录，之后在目录内容变化时重新打包到 outputPath，直到 ctx 取消。
+// 变化通过轮询文件的大小与修改时间检测，输出文件以先写临时文件再重命名的方式替换
+func WatchDir(ctx context.Context, dir, outputPath string, opts WatchOptions) error {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	output, err := filepath.Abs(outputPath)
+	if err != nil {
+		return err
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(output, root+string(os.PathSeparator)) {
+		return fmt.Errorf("output file cannot be inside the watched directory: %s", outputPath)
+	}
+
+	build := func() {
+		p, err := OpenDirWithOptions(dir, opts.Open)
+		if err == nil {
+			err = saveAtomic(p, output)
+		}
+		if opts.OnBuild != nil {
+			opts.OnBuild(err)
+		}
+	}
+
+	last, err := dirSnapshot(dir)
+	if err != nil {
+		return err
+	}
+	build()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		current, err := dirSnapshot(dir)
+		if err != nil {
+			if opts.OnBuild != nil {
+				opts.OnBuild(err)
+			}
+			continue
+		}
+		if snapshotEqual(last, current) {
+			continue
+		}
+		last = current
+		build()
+	}
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// dirSnapshot 记录目录下所有文件的大小与修改时间
+func dirSnapshot(dir string) (map[string]fileState, error) {
+	snapshot := make(map[string]fileState)
+	err := filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		snapshot[file] = fileState{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	return snapshot, err
+}
+
+func snapshotEqual(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, state := range a {
+		other, ok := b[name]
+		if !ok || other.size != state.size || !other.modTime.Equal(state.modTime) {
+			return false
+		}
+	}
+	return true
+}
+
+func fileMode(mode os.FileMode) os.FileMode {
+	if perm := mode.Perm(); perm != 0 {
+		return perm
+	}
+	return 0o644
+}
+
+func dirMode(mode os.FileMode) os.FileMode {
+	if perm := mode.Perm(); perm != 0 {
+		return perm | 0o700
+	}
+	return 0o755
+}
@@ -0,0 +1,155 @@
+package epub
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeBookDir 写入一本解压后的书（不含 mimetype），返回目录
+func writeBookDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"META-INF/container.xml": testContainerXML,
+		"OEBPS/content.opf":      testOPF,
+		"OEBPS/Text/ch1.xhtml":   testChapter,
+		".git/HEAD":              "ref",
+		"OEBPS/.DS_Store":        "junk",
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestOpenDirExtract(t *testing.T) {
+	dir := writeBookDir(t)
+	chapter := filepath.Join(dir, "OEBPS", "Text", "ch1.xhtml")
+	modified := time.Date(2020, 5, 6, 7, 8, 10, 0, time.UTC)
+	if err := os.Chmod(chapter, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(chapter, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	book, err := OpenDir(dir)
+	if err != nil {
+		t.Fatalf("OpenDir() error = %v", err)
+	}
+	if info := book.Info(); info.Title != "Test" || info.Files != 4 {
+		t.Errorf("Info() = %+v, want the book with mimetype added and hidden files skipped", info)
+	}
+	output := filepath.Join(t.TempDir(), "book.epub")
+	if err := book.Save(output); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	reader, err := zip.OpenReader(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	var names []string
+	for _, f := range reader.File {
+		names = append(names, f.Name)
+	}
+	if first := reader.File[0]; first.Name != "mimetype" || first.Method != zip.Store {
+		t.Errorf("zip entries = %q, want a stored mimetype first", names)
+	}
+	if names[1] != "META-INF/container.xml" {
+		t.Errorf("zip entries = %q, want META-INF right after mimetype", names)
+	}
+
+	saved, err := Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "out")
+	if err := saved.Extract(out); err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(out, "mimetype")); err != nil || string(data) != epubMimetype {
+		t.Errorf("extracted mimetype = %q, %v", data, err)
+	}
+	info, err := os.Stat(filepath.Join(out, "OEBPS", "Text", "ch1.xhtml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 || !info.ModTime().Equal(modified) {
+		t.Errorf("extracted chapter mode %v, time %v, want 0600 and %v", info.Mode().Perm(), info.ModTime().UTC(), modified)
+	}
+
+	// 解压的目录可以重新打开，未保存的修改也会被解压
+	if err := saved.writeEntry("OEBPS/Text/ch1.xhtml", []byte("changed")); err != nil {
+		t.Fatal(err)
+	}
+	if err := saved.Extract(out); err != nil {
+		t.Fatalf("second Extract() error = %v", err)
+	}
+	reopened, err := OpenDir(out)
+	if err != nil {
+		t.Fatalf("OpenDir(extracted) error = %v", err)
+	}
+	if data, _ := reopened.ReadFile("OEBPS/Text/ch1.xhtml"); string(data) != "changed" {
+		t.Errorf("reopened chapter = %q, want the unsaved change", data)
+	}
+
+	if _, err := OpenDir(output); err == nil {
+		t.Error("OpenDir() on a file succeeded")
+	}
+}
+
+func TestWatchDir(t *testing.T) {
+	dir := writeBookDir(t)
+	if err := WatchDir(context.Background(), dir, filepath.Join(dir, "out.epub"), WatchOptions{}); err == nil {
+		t.Error("WatchDir() with the output inside the directory succeeded")
+	}
+
+	output := filepath.Join(t.TempDir(), "book.epub")
+	builds := make(chan error, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchDir(ctx, dir, output, WatchOptions{Interval: 10 * time.Millisecond, OnBuild: func(err error) { builds <- err }})
+	}()
+	wait := func() {
+		t.Helper()
+		select {
+		case err := <-builds:
+			if err != nil {
+				t.Fatalf("build error = %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a build")
+		}
+	}
+
+	wait()
+	if err := os.WriteFile(filepath.Join(dir, "OEBPS", "Text", "ch1.xhtml"), []byte("rebuilt"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wait()
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("WatchDir() error = %v", err)
+	}
+
+	book, err := Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := book.ReadFile("OEBPS/Text/ch1.xhtml"); string(data) != "rebuilt" {
+		t.Errorf("rebuilt chapter = %q, want the changed content", data)
+	}
+}
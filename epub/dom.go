@@ -0,0 +1,132 @@
+package epub
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// DOMFunc 处理一个章节解析后的文档树，返回是否修改了文档
+type DOMFunc func(name string, doc *html.Node) (changed bool, err error)
+
+// ApplyDOM 将每个 HTML 解析为 DOM 树后执行 fn，fn 返回 changed 时才序列化为 XHTML 写回，返回被修改的章节数
+func (p *Epub) ApplyDOM(fn DOMFunc) (int, error) {
+	return p.ApplyDOMWithOptions(context.Background(), fn, ApplyOptions{})
+}
+
+// ApplyDOMWithOptions 与 ApplyDOM 相同，并发、进度与错误处理的方式同 ApplyHTMLWithOptions；
+// 每个章节只解析一次，未修改的章节保持原样
+func (p *Epub) ApplyDOMWithOptions(ctx context.Context, fn DOMFunc, opts ApplyOptions) (int, error) {
+	if fn == nil {
+		return 0, nil
+	}
+	doctype := p.defaultDoctype()
+	return p.ApplyHTMLWithOptions(ctx, func(name string, content string) (string, error) {
+		doc, err := html.Parse(strings.NewReader(content))
+		if err != nil {
+			return "", fmt.Errorf("failed to parse HTML: %w", err)
+		}
+		changed, err := fn(name, doc)
+		if err != nil || !changed {
+			return content, err
+		}
+		return renderXHTMLDocument(doc, doctype)
+	}, opts)
+}
+
+// QueryAll 按文档顺序返回 root 下匹配选择器的元素（含嵌套的匹配），选择器语法同规则文件的 selector
+func QueryAll(root *html.Node, selectorText string) ([]*html.Node, error) {
+	sel, err := parseSelector(selectorText)
+	if err != nil {
+		return nil, err
+	}
+	var matches []*html.Node
+	walkElements(root, func(n *html.Node) {
+		if sel.match(n) {
+			matches = append(matches, n)
+		}
+	})
+	return matches, nil
+}
+
+// RemoveNode 将节点从文档中移除，已移除的节点不受影响
+func RemoveNode(n *html.Node) {
+	if n.Parent != nil {
+		n.Parent.RemoveChild(n)
+	}
+}
+
+// Unwrap 用节点的子节点替换节点本身，如将 <span>text</span> 变为 text
+func Unwrap(n *html.Node) {
+	parent := n.Parent
+	if parent == nil {
+		return
+	}
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		n.RemoveChild(c)
+		parent.InsertBefore(c, n)
+		c = next
+	}
+	parent.RemoveChild(n)
+}
+
+// RemoveAll 移除 root 下匹配选择器的元素，返回移除的个数（已移除元素内的匹配不重复计数）
+func RemoveAll(root *html.Node, selectorText string) (int, error) {
+	matches, err := QueryAll(root, selectorText)
+	if err != nil {
+		return 0, err
+	}
+	removed := make(map[*html.Node]bool, len(matches))
+	count := 0
+	for _, n := range matches {
+		inside := false
+		for a := n.Parent; a != nil; a = a.Parent {
+			if removed[a] {
+				inside = true
+				break
+			}
+		}
+		removed[n] = true
+		if !inside {
+			RemoveNode(n)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// UnwrapAll 对 root 下匹配选择器的元素执行 Unwrap，返回处理的个数
+func UnwrapAll(root *html.Node, selectorText string) (int, error) {
+	matches, err := QueryAll(root, selectorText)
+	if err != nil {
+		return 0, err
+	}
+	for _, n := range matches {
+		Unwrap(n)
+	}
+	return len(matches), nil
+}
+
+// Attr 返回元素的属性值，带前缀的属性使用 "xml:lang"、"epub:type" 这样的名称
+func Attr(n *html.Node, key string) (string, bool) {
+	return lookupAttr(n, key)
+}
+
+// SetAttr 设置元素的属性，返回属性值是否发生变化
+func SetAttr(n *html.Node, key, val string) bool {
+	if old, ok := lookupAttr(n, key); ok && old == val {
+		return false
+	}
+	setAttr(n, key, val)
+	return true
+}
+
+// RemoveAttr 删除元素的属性，返回属性是否存在
+func RemoveAttr(n *html.Node, key string) bool {
+	before := len(n.Attr)
+	removeAttr(n, key)
+	return len(n.Attr) != before
+}
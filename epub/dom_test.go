@@ -0,0 +1,91 @@
+package epub
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestApplyDOM(t *testing.T) {
+	book := openChapters(t, 2)
+	var names []string
+	count, err := book.ApplyDOM(func(name string, doc *html.Node) (bool, error) {
+		names = append(names, name)
+		// 未声明修改的章节即使改动了树也不写回
+		if _, err := RemoveAll(doc, "p"); err != nil {
+			return false, err
+		}
+		return strings.HasSuffix(name, "/ch2.xhtml"), nil
+	})
+	if err != nil {
+		t.Fatalf("ApplyDOM() error = %v", err)
+	}
+	if count != 1 || len(names) != 2 {
+		t.Errorf("ApplyDOM() = %d for chapters %q, want 1 of 2", count, names)
+	}
+	if data, _ := book.ReadFile("OEBPS/Text/ch1.xhtml"); string(data) != testChapter {
+		t.Errorf("unchanged chapter rewritten:\n%s", data)
+	}
+
+	data, _ := book.ReadFile("OEBPS/Text/ch2.xhtml")
+	if strings.Contains(string(data), "<p>") || !strings.Contains(string(data), `xmlns="http://www.w3.org/1999/xhtml"`) {
+		t.Errorf("changed chapter = %s, want the paragraph removed and XHTML output", data)
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("changed chapter is not well-formed XML: %v\n%s", err, data)
+		}
+	}
+}
+
+func TestDOMHelpers(t *testing.T) {
+	const page = `<html><body>` +
+		`<div class="ad"><div class="ad">nested</div></div>` +
+		`<p><span class="x">a</span> and <span class="x"><b>b</b></span></p>` +
+		`<a id="n1" epub:type="noteref" href="#fn1">1</a></body></html>`
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := RemoveAll(doc, ".ad"); err != nil || n != 1 {
+		t.Errorf("RemoveAll(.ad) = %d, %v, want 1 (the nested match is removed with its parent)", n, err)
+	}
+	if n, err := UnwrapAll(doc, "span.x"); err != nil || n != 2 {
+		t.Errorf("UnwrapAll(span.x) = %d, %v, want 2", n, err)
+	}
+	if _, err := RemoveAll(doc, "p:first-child"); err == nil {
+		t.Error("RemoveAll() with an unsupported selector succeeded")
+	}
+
+	links, err := QueryAll(doc, "a")
+	if err != nil || len(links) != 1 {
+		t.Fatalf("QueryAll(a) = %d, %v, want 1", len(links), err)
+	}
+	a := links[0]
+	if v, ok := Attr(a, "epub:type"); !ok || v != "noteref" {
+		t.Errorf("Attr(epub:type) = %q, %v, want noteref", v, ok)
+	}
+	if SetAttr(a, "epub:type", "noteref") {
+		t.Error("SetAttr() with the same value reported a change")
+	}
+	if !SetAttr(a, "class", "ref") || !RemoveAttr(a, "id") || RemoveAttr(a, "id") {
+		t.Error("SetAttr/RemoveAttr did not report the changes")
+	}
+
+	var out strings.Builder
+	if err := html.Render(&out, doc); err != nil {
+		t.Fatal(err)
+	}
+	want := `<body><p>a and <b>b</b></p><a epub:type="noteref" href="#fn1" class="ref">1</a></body>`
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("document = %s, want %s", out.String(), want)
+	}
+}
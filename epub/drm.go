@@ -0,0 +1,161 @@
+package epub
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// 保护方案
+const (
+	SchemeNone            = ""
+	SchemeAdobeADEPT      = "adobe-adept"
+	SchemeReadiumLCP      = "readium-lcp"
+	SchemeAppleFairPlay   = "apple-fairplay"
+	SchemeFontObfuscation = "font-obfuscation"
+	SchemeUnknown         = "unknown"
+)
+
+const (
+	encryptionXMLPath = "META-INF/encryption.xml"
+	rightsXMLPath     = "META-INF/rights.xml"
+	lcpLicensePath    = "META-INF/license.lcpl"
+	sinfXMLPath       = "META-INF/sinf.xml"
+
+	idpfFontAlgorithm  = "http://www.idpf.org/2008/embedding"
+	adobeFontAlgorithm = "http://ns.adobe.com/pdf/enc#RC"
+)
+
+// ErrProtected 表示 EPUB 受 DRM 保护，可通过 errors.Is 判断
+var ErrProtected = errors.New("epub is DRM protected")
+
+// ProtectedError 在 OpenOptions.RejectProtected 为 true 且书籍受 DRM 保护时返回
+type ProtectedError struct {
+	Protection Protection
+}
+
+func (e *ProtectedError) Error() string {
+	return fmt.Sprintf("epub is DRM protected: %s", e.Protection)
+}
+
+func (e *ProtectedError) Unwrap() error {
+	return ErrProtected
+}
+
+// Protection 描述 EPUB 的加密与 DRM 情况
+type Protection struct {
+	Scheme          string   // DRM 方案，未加密或仅字体混淆时为 SchemeNone / SchemeFontObfuscation
+	EncryptedFiles  []string // 被加密的文件（zip 内路径），不含混淆字体
+	ObfuscatedFonts []string // 使用字体混淆算法的文件，不影响内容处理
+	Markers         []string // 检测到的 DRM 相关文件，如 META-INF/rights.xml；无法解析的 encryption.xml 也记录在此
+}
+
+// Protected 返回书籍内容是否受 DRM 保护；仅有字体混淆时返回 false
+func (p Protection) Protected() bool {
+	return p.Scheme != SchemeNone && p.Scheme != SchemeFontObfuscation
+}
+
+func (p Protection) String() string {
+	switch p.Scheme {
+	case SchemeNone:
+		return "none"
+	case SchemeFontObfuscation:
+		return fmt.Sprintf("font obfuscation (%d fonts)", len(p.ObfuscatedFonts))
+	}
+	return fmt.Sprintf("%s (%d encrypted files)", p.Scheme, len(p.EncryptedFiles))
+}
+
+// Protection 返回打开时检测到的加密与 DRM 情况
+func (p *Epub) Protection() Protection {
+	prot := p.protection
+	prot.EncryptedFiles = append([]string(nil), prot.EncryptedFiles...)
+	prot.ObfuscatedFonts = append([]string(nil), prot.ObfuscatedFonts...)
+	prot.Markers = append([]string(nil), prot.Markers...)
+	return prot
+}
+
+type encryptionDoc struct {
+	Data []struct {
+		Method struct {
+			Algorithm string `xml:"Algorithm,attr"`
+		} `xml:"EncryptionMethod"`
+		KeyInfo struct {
+			Inner string `xml:",innerxml"`
+		} `xml:"KeyInfo"`
+		CipherReference struct {
+			URI string `xml:"URI,attr"`
+		} `xml:"CipherData>CipherReference"`
+	} `xml:"EncryptedData"`
+}
+
+// detectProtection 根据 META-INF 中的 encryption.xml、rights.xml、license.lcpl 与 sinf.xml 判断保护方案；
+// encryption.xml 无法解析时仍返回其余文件的检测结果，并将其记入 Markers，同时返回解析错误
+func (p *Epub) detectProtection() (Protection, error) {
+	var prot Protection
+	var parseErr error
+	for _, name := range []string{rightsXMLPath, lcpLicensePath, sinfXMLPath} {
+		if entry, ok := p.entryIndex[name]; ok && !entry.removed {
+			prot.Markers = append(prot.Markers, name)
+		}
+	}
+
+	lcpKey := false
+	if entry, ok := p.entryIndex[encryptionXMLPath]; ok && !entry.removed {
+		var doc encryptionDoc
+		decoder := xml.NewDecoder(bytes.NewReader(entry.data))
+		decoder.CharsetReader = xmlCharsetReader
+		if err := decoder.Decode(&doc); err != nil {
+			prot.Markers = append(prot.Markers, encryptionXMLPath)
+			parseErr = fmt.Errorf("failed to parse %s: %w", encryptionXMLPath, err)
+		}
+		for _, data := range doc.Data {
+			name := data.CipherReference.URI
+			if unescaped, err := url.PathUnescape(name); err == nil {
+				name = unescaped
+			}
+			name = normalizeZipPath(strings.TrimPrefix(name, "/"))
+			switch data.Method.Algorithm {
+			case idpfFontAlgorithm, adobeFontAlgorithm:
+				prot.ObfuscatedFonts = append(prot.ObfuscatedFonts, name)
+			default:
+				prot.EncryptedFiles = append(prot.EncryptedFiles, name)
+			}
+			if strings.Contains(data.KeyInfo.Inner, "license.lcpl") {
+				lcpKey = true
+			}
+		}
+		sort.Strings(prot.EncryptedFiles)
+		sort.Strings(prot.ObfuscatedFonts)
+	}
+
+	has := func(marker string) bool {
+		for _, m := range prot.Markers {
+			if m == marker {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case lcpKey || has(lcpLicensePath):
+		prot.Scheme = SchemeReadiumLCP
+	case has(sinfXMLPath):
+		prot.Scheme = SchemeAppleFairPlay
+	case has(rightsXMLPath) && isAdeptRights(p.entryIndex[rightsXMLPath].data):
+		prot.Scheme = SchemeAdobeADEPT
+	case len(prot.EncryptedFiles) > 0:
+		prot.Scheme = SchemeUnknown
+	case len(prot.ObfuscatedFonts) > 0:
+		prot.Scheme = SchemeFontObfuscation
+	}
+	return prot, parseErr
+}
+
+// isAdeptRights 判断 rights.xml 是否为 Adobe ADEPT 授权文件
+func isAdeptRights(data []byte) bool {
+	return bytes.Contains(data, []byte("http://ns.adobe.com/adept"))
+}
@@ -0,0 +1,94 @@
+package epub
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// encryptionXML 生成 encryption.xml，每个元素为 {算法, 文件, KeyInfo 内容}
+func encryptionXML(data ...[3]string) testFile {
+	xml := `<?xml version="1.0" encoding="UTF-8"?>
+<encryption xmlns="urn:oasis:names:tc:opendocument:xmlns:container" xmlns:enc="http://www.w3.org/2001/04/xmlenc#" xmlns:ds="http://www.w3.org/2000/09/xmldsig#">`
+	for _, d := range data {
+		xml += fmt.Sprintf(`<enc:EncryptedData><enc:EncryptionMethod Algorithm="%s"/>`+
+			`<ds:KeyInfo>%s</ds:KeyInfo><enc:CipherData><enc:CipherReference URI="%s"/></enc:CipherData></enc:EncryptedData>`,
+			d[0], d[2], d[1])
+	}
+	return testFile{encryptionXMLPath, xml + `</encryption>`}
+}
+
+func TestDetectProtection(t *testing.T) {
+	const aes = "http://www.w3.org/2001/04/xmlenc#aes256-cbc"
+	chapter := [3]string{aes, "OEBPS/Text/ch1.xhtml", ""}
+	font := [3]string{idpfFontAlgorithm, "OEBPS/Fonts/a%20b.otf", ""}
+	adobeFont := [3]string{adobeFontAlgorithm, "/OEBPS/Fonts/c.ttf", ""}
+	tests := []struct {
+		name        string
+		extra       []testFile
+		wantScheme  string
+		wantFiles   []string
+		wantFonts   []string
+		wantMarkers []string
+	}{
+		{name: "none"},
+		{name: "font obfuscation", extra: []testFile{encryptionXML(font, adobeFont)},
+			wantScheme: SchemeFontObfuscation, wantFonts: []string{"OEBPS/Fonts/a b.otf", "OEBPS/Fonts/c.ttf"}},
+		{name: "adobe adept", extra: []testFile{encryptionXML(chapter, font), {rightsXMLPath, `<adept:rights xmlns:adept="http://ns.adobe.com/adept"/>`}},
+			wantScheme: SchemeAdobeADEPT, wantFiles: []string{"OEBPS/Text/ch1.xhtml"}, wantFonts: []string{"OEBPS/Fonts/a b.otf"},
+			wantMarkers: []string{rightsXMLPath}},
+		{name: "readium lcp license", extra: []testFile{encryptionXML(chapter), {lcpLicensePath, "{}"}},
+			wantScheme: SchemeReadiumLCP, wantFiles: []string{"OEBPS/Text/ch1.xhtml"}, wantMarkers: []string{lcpLicensePath}},
+		{name: "readium lcp key info", extra: []testFile{encryptionXML([3]string{aes, "OEBPS/Text/ch1.xhtml",
+			`<ds:RetrievalMethod URI="license.lcpl#/encryption/content_key"/>`})},
+			wantScheme: SchemeReadiumLCP, wantFiles: []string{"OEBPS/Text/ch1.xhtml"}},
+		{name: "apple fairplay", extra: []testFile{encryptionXML(chapter), {sinfXMLPath, "<fairplay/>"}},
+			wantScheme: SchemeAppleFairPlay, wantFiles: []string{"OEBPS/Text/ch1.xhtml"}, wantMarkers: []string{sinfXMLPath}},
+		{name: "unknown", extra: []testFile{encryptionXML(chapter)},
+			wantScheme: SchemeUnknown, wantFiles: []string{"OEBPS/Text/ch1.xhtml"}},
+		{name: "non-adept rights", extra: []testFile{{rightsXMLPath, "<rights/>"}}, wantMarkers: []string{rightsXMLPath}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := writeTestEPUB(t, "", tt.extra...)
+			book, err := Open(input)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			prot := book.Protection()
+			if prot.Scheme != tt.wantScheme || !slices.Equal(prot.EncryptedFiles, tt.wantFiles) ||
+				!slices.Equal(prot.ObfuscatedFonts, tt.wantFonts) || !slices.Equal(prot.Markers, tt.wantMarkers) {
+				t.Errorf("Protection() = %+v, want scheme %q, files %q, fonts %q, markers %q",
+					prot, tt.wantScheme, tt.wantFiles, tt.wantFonts, tt.wantMarkers)
+			}
+
+			_, err = OpenWithOptions(input, OpenOptions{RejectProtected: true})
+			var protErr *ProtectedError
+			if prot.Protected() {
+				if !errors.As(err, &protErr) || !errors.Is(err, ErrProtected) || protErr.Protection.Scheme != tt.wantScheme {
+					t.Errorf("OpenWithOptions(RejectProtected) error = %v, want *ProtectedError for %s", err, tt.wantScheme)
+				}
+			} else if err != nil {
+				t.Errorf("OpenWithOptions(RejectProtected) error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestDetectProtectionMalformedEncryption(t *testing.T) {
+	input := writeTestEPUB(t, "", testFile{encryptionXMLPath, "<encryption><EncryptedData>"})
+	book, err := Open(input)
+	if err != nil {
+		t.Fatalf("Open() error = %v, want the book opened", err)
+	}
+	if prot := book.Protection(); !slices.Contains(prot.Markers, encryptionXMLPath) {
+		t.Errorf("Protection().Markers = %q, want %s recorded", prot.Markers, encryptionXMLPath)
+	}
+
+	_, err = OpenWithOptions(input, OpenOptions{RejectProtected: true})
+	var protErr *ProtectedError
+	if err == nil || errors.As(err, &protErr) {
+		t.Errorf("OpenWithOptions(RejectProtected) error = %v, want the parse error", err)
+	}
+}
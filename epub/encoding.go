@@ -0,0 +1,252 @@
+package epub
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// EncodingConversion 记录一次编码转换
+type EncodingConversion struct {
+	Name     string `json:"name"`
+	From     string `json:"from"`
+	Declared string `json:"declared,omitempty"`
+}
+
+var (
+	xmlDeclEncodingRegex = regexp.MustCompile(`(?i)(<\?xml[^>]*?encoding\s*=\s*["'])([^"']*)(["'])`)
+	metaCharsetRegex     = regexp.MustCompile(`(?i)(<meta[^>]*?charset\s*=\s*["']?)([a-z0-9_\-]+)`)
+	cssCharsetRegex      = regexp.MustCompile(`(?i)(@charset\s*["'])([^"']*)(["'])`)
+)
+
+// 中文高频字（简繁），用于在 GBK 与 Big5 之间做判断：用错误的编码解码时，结果中很少出现这些字
+const frequentHan = "的一是不了在人有我他这个们中来上大为和国地到以说时要就出会可也你对生能而子那得于着下自之年过发后作里用道行所然家种事成方多经么去法学如都同现当没动面起看定天分还进好小部其些主样理心她本前开但因只从想实" +
+	"這個們來為國說時會對後裡經學現當動還進開從實問長點間體麼關見頭門話機樣邊發過們讓愛聽覺"
+
+var frequentHanSet = func() map[rune]bool {
+	set := make(map[rune]bool)
+	for _, r := range frequentHan {
+		set[r] = true
+	}
+	return set
+}()
+
+// NormalizeEncoding 检测 HTML/NCX/CSS 等文本文件的编码，将非 UTF-8 的内容转换为 UTF-8，
+// 并修正 XML 声明、meta charset 与 @charset，返回被转换的文件列表
+func (p *Epub) NormalizeEncoding() ([]EncodingConversion, error) {
+	var conversions []EncodingConversion
+	for _, entry := range p.entries {
+		if entry.removed || entry.isDir || !isTextEntry(entry) || p.isOPFEntry(entry) {
+			continue
+		}
+		from, declared := DetectEncoding(entry.data)
+		if from == "utf-8" && !hasLegacyDeclaration(declared) && !bytes.HasPrefix(entry.data, utf8BOM) {
+			continue
+		}
+
+		converted, err := toUTF8(entry.data, from)
+		if err != nil {
+			return conversions, fmt.Errorf("failed to convert %s from %s: %w", entry.header.Name, from, err)
+		}
+		entry.data = fixCharsetDeclarations(converted)
+		conversions = append(conversions, EncodingConversion{
+			Name:     entry.header.Name,
+			From:     from,
+			Declared: declared,
+		})
+	}
+	return conversions, nil
+}
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// DetectEncoding 检测文本内容的编码，返回规范化的编码名（如 "utf-8"、"gb18030"、"big5"）
+// 以及文件中声明的编码（未声明时为空）
+// 判断顺序：BOM -> 合法 UTF-8 -> 声明的编码（解码结果可信时） -> 字节启发式
+func DetectEncoding(data []byte) (string, string) {
+	declared := declaredCharset(data)
+
+	switch {
+	case bytes.HasPrefix(data, utf8BOM):
+		return "utf-8", declared
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return "utf-16le", declared
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return "utf-16be", declared
+	}
+
+	if utf8.Valid(data) {
+		return "utf-8", declared
+	}
+
+	// 声明的编码排在最前，同分时优先
+	var candidates []string
+	if name := canonicalEncoding(declared); name != "" && name != "utf-8" {
+		candidates = append(candidates, name)
+	}
+	for _, name := range []string{"gb18030", "big5"} {
+		if len(candidates) == 0 || candidates[0] != name {
+			candidates = append(candidates, name)
+		}
+	}
+
+	best, bestScore := "gb18030", 0
+	for i, name := range candidates {
+		score, ok := decodeScore(data, name)
+		if !ok {
+			continue
+		}
+		if i == 0 || score > bestScore {
+			best, bestScore = name, score
+		}
+	}
+	return best, declared
+}
+
+// decodeScore 用指定编码解码并打分：高频汉字加分，替换字符与控制字符扣分
+func decodeScore(data []byte, name string) (int, bool) {
+	enc := lookupEncoding(name)
+	if enc == nil {
+		return 0, false
+	}
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return 0, false
+	}
+	score := 0
+	for _, r := range string(decoded) {
+		switch {
+		case r == utf8.RuneError:
+			score -= 10
+		case frequentHanSet[r]:
+			score += 2
+		case r < 0x20 && r != '\n' && r != '\r' && r != '\t':
+			score -= 5
+		case r >= 0xE000 && r <= 0xF8FF:
+			// 私用区字符通常意味着解码错误
+			score -= 5
+		}
+	}
+	return score, true
+}
+
+// toUTF8 将内容从指定编码转换为 UTF-8，并去除 BOM
+func toUTF8(data []byte, name string) ([]byte, error) {
+	if name == "utf-8" {
+		return bytes.TrimPrefix(data, utf8BOM), nil
+	}
+	enc := lookupEncoding(name)
+	if enc == nil {
+		return nil, fmt.Errorf("unsupported encoding: %s", name)
+	}
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimPrefix(decoded, utf8BOM), nil
+}
+
+func lookupEncoding(name string) encoding.Encoding {
+	switch name {
+	case "gb18030":
+		return simplifiedchinese.GB18030
+	case "gbk":
+		return simplifiedchinese.GBK
+	case "big5":
+		return traditionalchinese.Big5
+	case "utf-16le":
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	case "utf-16be":
+		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM)
+	}
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil
+	}
+	return enc
+}
+
+// canonicalEncoding 将声明的编码名规范化；GB2312/GBK 统一按其超集 GB18030 处理
+func canonicalEncoding(label string) string {
+	label = strings.ToLower(strings.TrimSpace(label))
+	if label == "" {
+		return ""
+	}
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return ""
+	}
+	name, err := htmlindex.Name(enc)
+	if err != nil {
+		return ""
+	}
+	switch name {
+	case "gbk", "gb18030":
+		return "gb18030"
+	}
+	return name
+}
+
+func hasLegacyDeclaration(declared string) bool {
+	name := canonicalEncoding(declared)
+	return name != "" && name != "utf-8"
+}
+
+// declaredCharset 读取 XML 声明、meta charset 或 @charset 中声明的编码
+func declaredCharset(data []byte) string {
+	head := data
+	if len(head) > 2048 {
+		head = head[:2048]
+	}
+	for _, re := range []*regexp.Regexp{xmlDeclEncodingRegex, metaCharsetRegex, cssCharsetRegex} {
+		if m := re.FindSubmatch(head); m != nil {
+			return string(m[2])
+		}
+	}
+	return ""
+}
+
+// fixCharsetDeclarations 将内容中的编码声明统一改为 UTF-8
+func fixCharsetDeclarations(data []byte) []byte {
+	data = xmlDeclEncodingRegex.ReplaceAll(data, []byte("${1}utf-8${3}"))
+	data = metaCharsetRegex.ReplaceAll(data, []byte("${1}utf-8"))
+	data = cssCharsetRegex.ReplaceAll(data, []byte("${1}UTF-8${3}"))
+	return data
+}
+
+// xmlCharsetReader 供 xml.Decoder 使用，支持非 UTF-8 编码的 OPF
+func xmlCharsetReader(label string, input io.Reader) (io.Reader, error) {
+	name := canonicalEncoding(label)
+	if name == "" {
+		return nil, fmt.Errorf("unsupported encoding: %s", label)
+	}
+	if name == "utf-8" {
+		return input, nil
+	}
+	return lookupEncoding(name).NewDecoder().Reader(input), nil
+}
+
+func (p *Epub) isOPFEntry(entry *zipEntry) bool {
+	return normalizeZipPath(entry.header.Name) == p.opfPath
+}
+
+func isTextEntry(entry *zipEntry) bool {
+	if isHTMLEntry(entry) {
+		return true
+	}
+	switch strings.ToLower(path.Ext(entry.header.Name)) {
+	case ".ncx", ".opf", ".css", ".xml", ".svg", ".txt":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package epub
+
+import (
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+func mustEncode(t *testing.T, enc encoding.Encoding, text string) []byte {
+	t.Helper()
+	data, err := enc.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDetectEncoding(t *testing.T) {
+	const simplified = "<p>这是我们的书，他说了一个人的故事。</p>"
+	const traditional = "<p>這是我們的書，他說了一個人的故事。</p>"
+	tests := []struct {
+		name         string
+		data         []byte
+		wantEncoding string
+		wantDeclared string
+	}{
+		{"ascii", []byte("<p>hello</p>"), "utf-8", ""},
+		{"utf-8", []byte(simplified), "utf-8", ""},
+		{"utf-8 bom", append([]byte{0xEF, 0xBB, 0xBF}, simplified...), "utf-8", ""},
+		{"utf-16le bom", []byte{0xFF, 0xFE, '<', 0}, "utf-16le", ""},
+		{"utf-16be bom", []byte{0xFE, 0xFF, 0, '<'}, "utf-16be", ""},
+		{
+			"gbk declared in xml",
+			append([]byte(`<?xml version="1.0" encoding="GBK"?>`), mustEncode(t, simplifiedchinese.GBK, simplified)...),
+			"gb18030", "GBK",
+		},
+		{
+			"gbk undeclared",
+			mustEncode(t, simplifiedchinese.GBK, simplified),
+			"gb18030", "",
+		},
+		{
+			"big5 declared in meta",
+			append([]byte(`<meta charset="big5"/>`), mustEncode(t, traditionalchinese.Big5, traditional)...),
+			"big5", "big5",
+		},
+		{
+			"utf-8 mislabelled as gbk",
+			[]byte(`<?xml version="1.0" encoding="gbk"?>` + simplified),
+			"utf-8", "gbk",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotEncoding, gotDeclared := DetectEncoding(tt.data)
+			if gotEncoding != tt.wantEncoding || gotDeclared != tt.wantDeclared {
+				t.Errorf("DetectEncoding() = (%q, %q), want (%q, %q)", gotEncoding, gotDeclared, tt.wantEncoding, tt.wantDeclared)
+			}
+		})
+	}
+}
+
+func TestToUTF8(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		encoding string
+		want     string
+	}{
+		{"utf-8 bom stripped", append([]byte{0xEF, 0xBB, 0xBF}, "中文内容"...), "utf-8", "中文内容"},
+		{"gb18030", mustEncode(t, simplifiedchinese.GB18030, "中文内容"), "gb18030", "中文内容"},
+		{"big5", mustEncode(t, traditionalchinese.Big5, "中文內容"), "big5", "中文內容"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toUTF8(tt.data, tt.encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("toUTF8() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if _, err := toUTF8([]byte("x"), "no-such-encoding"); err == nil {
+		t.Error("toUTF8() with an unknown encoding succeeded")
+	}
+}
@@ -3,6 +3,7 @@ package epub
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io"
@@ -10,6 +11,7 @@ import (
 	"path"
 	"regexp"
 	"strings"
+	"time"
 )
 
 // Epub 封装 EPUB 解压、修改与重新打包的能力
@@ -20,12 +22,19 @@ type Epub struct {
 	opfPath string
 	opfDir  string
 	opfDoc  *opfPackage
+	// opfTree 保存 OPF 的原始结构，opfOrig 为上次写回时的 opfDoc，
+	// 保存时只把两者之间的差异写回 opfTree，以保留未修改的内容
+	opfTree *xmlNode
+	opfOrig *opfPackage
 
 	idCounter int
+
+	protection Protection
 }
 
 type zipEntry struct {
-	header  zip.FileHeader
+	header zip.FileHeader
+	// data 在修改时必须整体替换为新的切片，不能原地改写：Snapshot 与 Epub 共享同一份内容
 	data    []byte
 	isDir   bool
 	removed bool
@@ -35,13 +44,35 @@ type zipEntry struct {
 type ProcessOptions struct {
 	InputPath          string
 	OutputPath         string
+	OpenOptions        OpenOptions
+	NormalizeEncoding  bool // 先将非 UTF-8 的文本文件转换为 UTF-8
 	RemoveHTMLKeywords []string
+	Rules              *RuleSet // 按规则清理章节内容，见 ApplyRules
 	ReplaceHTML        func(name string, html string) (string, error)
+	ReplaceDOM         DOMFunc // 以 DOM 树处理章节，见 ApplyDOM
 	Customize          func(p *Epub) error
 }
 
+// OpenOptions 控制 OpenWithOptions 的行为。
+// 安全限制为 0 时使用默认值（见 DefaultMaxTotalSize 等），为负数时不限制；
+// 路径穿越与重名条目总是会被拒绝
+type OpenOptions struct {
+	// RejectProtected 为 true 时，受 DRM 保护的书籍返回 *ProtectedError；字体混淆不受影响
+	RejectProtected bool
+
+	MaxTotalSize        int64   // 解压后的总大小上限（字节）
+	MaxEntrySize        int64   // 单个文件解压后的大小上限（字节）
+	MaxEntries          int     // 条目数上限
+	MaxCompressionRatio float64 // 单个文件解压大小与压缩大小之比的上限
+}
+
 // Open 从 EPUB 文件构建 Epub，所有数据会被读取到内存中
 func Open(inputPath string) (*Epub, error) {
+	return OpenWithOptions(inputPath, OpenOptions{})
+}
+
+// OpenWithOptions 与 Open 相同，但可以通过 opts 控制打开时的检查
+func OpenWithOptions(inputPath string, opts OpenOptions) (*Epub, error) {
 	reader, err := zip.OpenReader(inputPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open EPUB: %w", err)
@@ -50,6 +81,11 @@ func Open(inputPath string) (*Epub, error) {
 		_ = reader.Close()
 	}(reader)
 
+	limits := newOpenLimits(opts)
+	if err := limits.checkEntries(reader.File); err != nil {
+		return nil, err
+	}
+
 	p := &Epub{
 		entryIndex: make(map[string]*zipEntry),
 	}
@@ -61,50 +97,70 @@ func Open(inputPath string) (*Epub, error) {
 			isDir:  f.FileInfo().IsDir(),
 		}
 
-		normName := normalizeZipPath(header.Name)
 		if !entry.isDir {
-			rc, err := f.Open()
+			data, err := limits.readEntry(f)
 			if err != nil {
-				return nil, fmt.Errorf("failed to read zip entry (%s): %w", header.Name, err)
-			}
-
-			data, err := io.ReadAll(rc)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read file content (%s): %w", header.Name, err)
+				return nil, err
 			}
+			entry.data = data
+		}
+		if err := p.addLoadedEntry(entry); err != nil {
+			return nil, err
+		}
+	}
 
-			err = rc.Close()
-			if err != nil {
-				return nil, fmt.Errorf("failed to close file (%s): %w", header.Name, err)
-			}
+	if err := p.finishLoad(opts); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
 
-			entry.data = data
+// addLoadedEntry 加入读取到的条目，遇到 OPF 时解析 package 文档
+func (p *Epub) addLoadedEntry(entry *zipEntry) error {
+	normName := normalizeZipPath(entry.header.Name)
+	if !entry.isDir && isOPFFile(entry.header.Name) {
+		p.opfPath = normName
+		p.opfDir = normalizeZipPath(path.Dir(normName))
+		if p.opfDir == "." {
+			p.opfDir = ""
+		}
 
-			if isOPFFile(header.Name) {
-				p.opfPath = normName
-				p.opfDir = normalizeZipPath(path.Dir(normName))
-				if p.opfDir == "." {
-					p.opfDir = ""
-				}
-
-				doc := &opfPackage{}
-				if err := xml.Unmarshal(data, doc); err != nil {
-					return nil, fmt.Errorf("failed to parse content.opf (%s): %w", header.Name, err)
-				}
-				p.opfDoc = doc
-				p.idCounter = len(doc.Manifest.Items)
-			}
+		doc := &opfPackage{}
+		decoder := xml.NewDecoder(bytes.NewReader(entry.data))
+		decoder.CharsetReader = xmlCharsetReader
+		if err := decoder.Decode(doc); err != nil {
+			return fmt.Errorf("failed to parse content.opf (%s): %w", entry.header.Name, err)
 		}
+		p.opfDoc = doc
+		p.opfOrig = clonePackage(doc)
+		p.idCounter = len(doc.Manifest.Items)
+		// 原始结构解析失败时退回到 encoding/xml 重新序列化
+		if tree, err := parseXMLTree(entry.data); err == nil && tree.root() != nil {
+			p.opfTree = tree
+		}
+	}
 
-		p.entries = append(p.entries, entry)
-		p.entryIndex[normName] = entry
+	p.entries = append(p.entries, entry)
+	p.entryIndex[normName] = entry
+	return nil
+}
+
+// finishLoad 在所有条目读取完成后检测 DRM 并确认 OPF 存在
+func (p *Epub) finishLoad(opts OpenOptions) error {
+	prot, err := p.detectProtection()
+	p.protection = prot
+	if err != nil && opts.RejectProtected {
+		// 无法确认哪些文件被加密，拒绝模式下不冒险打开
+		return err
+	}
+	if opts.RejectProtected && prot.Protected() {
+		return &ProtectedError{Protection: prot}
 	}
 
 	if p.opfDoc == nil {
-		return nil, fmt.Errorf("content.opf not found")
+		return fmt.Errorf("content.opf not found")
 	}
-
-	return p, nil
+	return nil
 }
 
 // Save 将当前状态写入新的 EPUB 文件
@@ -121,11 +177,19 @@ func (p *Epub) Save(outputPath string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create output file: %w", err)
 	}
-	defer func() { _ = outFile.Close() }()
-
-	writer := zip.NewWriter(outFile)
-	defer func() { _ = writer.Close() }()
+	if err := p.writeZip(outFile); err != nil {
+		_ = outFile.Close()
+		return err
+	}
+	if err := outFile.Close(); err != nil {
+		return fmt.Errorf("failed to close output file: %w", err)
+	}
+	return nil
+}
 
+// writeZip 写出所有条目；只有 zip.Writer.Close 成功（中央目录已写入）才算完整
+func (p *Epub) writeZip(w io.Writer) error {
+	writer := zip.NewWriter(w)
 	for _, entry := range p.entries {
 		if entry.removed {
 			continue
@@ -133,16 +197,20 @@ func (p *Epub) Save(outputPath string) error {
 
 		if entry.isDir {
 			if err := writeDirEntry(writer, &entry.header); err != nil {
+				_ = writer.Close()
 				return err
 			}
 			continue
 		}
 
 		if err := writeFileEntry(writer, &entry.header, entry.data); err != nil {
+			_ = writer.Close()
 			return err
 		}
 	}
-
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to finish zip archive: %w", err)
+	}
 	return nil
 }
 
@@ -197,27 +265,9 @@ func (p *Epub) CountHTML() int {
 
 // ApplyHTML 对所有 HTML 执行自定义函数，返回被修改的章节数
 func (p *Epub) ApplyHTML(fn func(name string, html string) (string, error)) (int, error) {
-	if fn == nil {
-		return 0, nil
-	}
-
-	modified := 0
-	currentIndex := 0
-	for _, entry := range p.entryIndex {
-		if entry.removed || !isHTMLEntry(entry) {
-			continue
-		}
-		original := string(entry.data)
-		// 计算进度：当前处理的 HTML 文件索引 / 总 HTML 文件数
-		updated, err := fn(entry.header.Name, original)
-		if err != nil {
-			return 0, fmt.Errorf("failed to process HTML (%s): %w", entry.header.Name, err)
-		}
-		if updated != original {
-			entry.data = []byte(updated)
-			modified++
-		}
-		currentIndex++
+	modified, err := p.ApplyHTMLWithOptions(context.Background(), fn, ApplyOptions{})
+	if err != nil {
+		return 0, err
 	}
 	return modified, nil
 }
@@ -242,9 +292,14 @@ func (p *Epub) RemoveHTMLContaining(keywords []string) ([]string, error) {
 	return removed, nil
 }
 
-// AddChapter 新增章节
+// AddChapter 新增章节，内容会被规范化为格式良好的 XHTML（见 AddChapterWithOptions）
 // filePath 为 ZIP 内路径（相对于 EPUB 根目录），spineIndex 为插入到 OPF spine 的位置（-1 表示追加）
 func (p *Epub) AddChapter(filePath, html string, spineIndex int) error {
+	return p.AddChapterWithOptions(filePath, html, ChapterOptions{SpineIndex: spineIndex})
+}
+
+// addChapter 原样写入章节内容并加入 manifest 与 spine
+func (p *Epub) addChapter(filePath, html string, spineIndex int) error {
 	if filePath == "" {
 		return fmt.Errorf("chapter path cannot be empty")
 	}
@@ -367,6 +422,69 @@ func (p *Epub) SaveAs(outputPath string) error {
 	return p.Save(outputPath)
 }
 
+// Process 按 ProcessOptions 打开、处理并保存 EPUB
+// 处理顺序：编码规范化 -> 删除包含关键词的章节 -> Rules -> ReplaceHTML -> ReplaceDOM -> Customize
+func Process(opts ProcessOptions) error {
+	if opts.InputPath == "" {
+		return fmt.Errorf("input path cannot be empty")
+	}
+	outputPath := opts.OutputPath
+	if outputPath == "" {
+		outputPath = opts.InputPath
+	}
+
+	p, err := OpenWithOptions(opts.InputPath, opts.OpenOptions)
+	if err != nil {
+		return err
+	}
+	if err := p.Apply(opts); err != nil {
+		return err
+	}
+	return p.Save(outputPath)
+}
+
+// Apply 在当前 Epub 上执行 ProcessOptions 中的处理步骤，忽略 InputPath 与 OutputPath；
+// 任一步骤失败时回滚到调用前的状态
+func (p *Epub) Apply(opts ProcessOptions) error {
+	return p.Transaction(func(p *Epub) error {
+		return p.apply(opts)
+	})
+}
+
+func (p *Epub) apply(opts ProcessOptions) error {
+	if opts.NormalizeEncoding {
+		if _, err := p.NormalizeEncoding(); err != nil {
+			return err
+		}
+	}
+	if len(opts.RemoveHTMLKeywords) > 0 {
+		if _, err := p.RemoveHTMLContaining(opts.RemoveHTMLKeywords); err != nil {
+			return err
+		}
+	}
+	if opts.Rules != nil {
+		if _, err := p.ApplyRules(opts.Rules); err != nil {
+			return err
+		}
+	}
+	if opts.ReplaceHTML != nil {
+		if _, err := p.ApplyHTML(opts.ReplaceHTML); err != nil {
+			return err
+		}
+	}
+	if opts.ReplaceDOM != nil {
+		if _, err := p.ApplyDOM(opts.ReplaceDOM); err != nil {
+			return err
+		}
+	}
+	if opts.Customize != nil {
+		if err := opts.Customize(p); err != nil {
+			return fmt.Errorf("customize failed: %w", err)
+		}
+	}
+	return nil
+}
+
 // ---------- 内部工具 ----------
 
 func (p *Epub) removeEntry(norm string) error {
@@ -385,6 +503,9 @@ func (p *Epub) removeEntry(norm string) error {
 			return err
 		}
 		p.removeFromOPF(href)
+		if err := p.removeNavigationReferences(norm); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -479,14 +600,24 @@ func (p *Epub) flushOPF() error {
 	if p.opfDoc == nil {
 		return nil
 	}
-	serialized, err := serializeOPF(p.opfDoc)
-	if err != nil {
-		return err
-	}
 	entry, ok := p.entryIndex[p.opfPath]
 	if !ok {
 		return fmt.Errorf("content.opf not found in entries")
 	}
+
+	if p.opfTree != nil {
+		if err := p.syncOPFTree(); err != nil {
+			return err
+		}
+		entry.data = p.opfTree.bytes()
+		p.opfOrig = clonePackage(p.opfDoc)
+		return nil
+	}
+
+	serialized, err := serializeOPF(p.opfDoc)
+	if err != nil {
+		return err
+	}
 	entry.data = serialized
 	return nil
 }
@@ -596,6 +727,39 @@ func (p *Epub) extractImagePathsFromHTML(htmlContent, htmlDir string) []string {
 	return imagePaths
 }
 
+// addResource 新增不进入 spine 的文件并加入 manifest，返回 manifest id
+func (p *Epub) addResource(norm, mediaType, properties string, data []byte) (string, error) {
+	if p.opfDoc == nil {
+		return "", fmt.Errorf("content.opf not loaded")
+	}
+	if existing, ok := p.entryIndex[norm]; ok && !existing.removed {
+		return "", fmt.Errorf("file already exists: %s", norm)
+	}
+	href, err := p.hrefForOPF(norm)
+	if err != nil {
+		return "", err
+	}
+	if err := p.ensureDirectories(norm); err != nil {
+		return "", err
+	}
+
+	entry := &zipEntry{
+		header: zip.FileHeader{Name: norm, Method: zip.Deflate, Modified: time.Now()},
+		data:   data,
+	}
+	p.entries = append(p.entries, entry)
+	p.entryIndex[norm] = entry
+
+	id := p.generateID(path.Base(norm))
+	p.opfDoc.Manifest.Items = append(p.opfDoc.Manifest.Items, opfManifestItem{
+		ID:         id,
+		Href:       href,
+		MediaType:  mediaType,
+		Properties: properties,
+	})
+	return id, nil
+}
+
 // addImageFile 添加图片文件到 EPUB 并更新 manifest
 func (p *Epub) addImageFile(epubImgPath string, imgData []byte) error {
 	norm := normalizeZipPath(epubImgPath)
@@ -841,7 +1005,13 @@ type opfSpineItem struct {
 }
 
 type opfGuide struct {
-	InnerXML []byte `xml:",innerxml"`
+	References []opfGuideReference `xml:"reference"`
+}
+
+type opfGuideReference struct {
+	Type  string `xml:"type,attr"`
+	Title string `xml:"title,attr,omitempty"`
+	Href  string `xml:"href,attr"`
 }
 
 func writeDirEntry(writer *zip.Writer, header *zip.FileHeader) error {
@@ -858,7 +1028,10 @@ func writeDirEntry(writer *zip.Writer, header *zip.FileHeader) error {
 
 func writeFileEntry(writer *zip.Writer, header *zip.FileHeader, data []byte) error {
 	fileHeader := *header
-	if fileHeader.Method == 0 {
+	if normalizeZipPath(fileHeader.Name) == "mimetype" {
+		// OCF 要求 mimetype 不压缩
+		fileHeader.Method = zip.Store
+	} else if fileHeader.Method == 0 {
 		fileHeader.Method = zip.Deflate
 	}
 	w, err := writer.CreateHeader(&fileHeader)
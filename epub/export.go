@@ -0,0 +1,535 @@
+package epub
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTMLExportOptions 控制 ExportHTML
+type HTMLExportOptions struct {
+	// Title 为页面标题，默认使用书名
+	Title string
+	// ExternalAssets 为 true 时图片、字体等资源写入输出文件旁的 <文件名>_files 目录（该目录已存在且非空时报错），
+	// 否则以 data: URI 内嵌，导出结果为单个文件
+	ExternalAssets bool
+	// NoSidebar 为 true 时不生成目录侧栏
+	NoSidebar bool
+}
+
+// exportCSS 为导出页面的布局：左侧固定的目录侧栏，窄屏时侧栏位于正文上方
+const exportCSS = `#epub-sidebar {
+  position: fixed;
+  top: 0;
+  bottom: 0;
+  left: 0;
+  width: 16em;
+  overflow-y: auto;
+  box-sizing: border-box;
+  padding: 1em;
+  border-right: 1px solid #ddd;
+  background: #fafafa;
+  font-size: 0.9em;
+}
+#epub-sidebar ol {
+  list-style: none;
+  margin: 0;
+  padding-left: 1em;
+}
+#epub-sidebar > ol {
+  padding-left: 0;
+}
+#epub-sidebar li {
+  margin: 0.3em 0;
+}
+#epub-sidebar a {
+  color: inherit;
+  text-decoration: none;
+}
+#epub-sidebar a:hover {
+  text-decoration: underline;
+}
+#epub-content.with-sidebar {
+  margin-left: 17em;
+}
+#epub-content > section.epub-chapter {
+  max-width: 48em;
+  margin: 0 auto;
+  padding: 1em;
+}
+#epub-content > section.epub-chapter + section.epub-chapter {
+  border-top: 1px solid #ddd;
+}
+#epub-content img {
+  max-width: 100%;
+}
+@media (max-width: 48em) {
+  #epub-sidebar {
+    position: static;
+    width: auto;
+    border-right: none;
+    border-bottom: 1px solid #ddd;
+  }
+  #epub-content.with-sidebar {
+    margin-left: 0;
+  }
+}
+`
+
+var (
+	cssImportRuleRegex  = regexp.MustCompile(`(?i)@import\s+(?:url\(\s*)?["']?([^"')\s;]+)["']?\s*\)?[^;]*;`)
+	cssCharsetRuleRegex = regexp.MustCompile(`(?i)@charset\s+["'][^"']*["']\s*;`)
+)
+
+// idRefAttrs 为引用同一文档内 id 的属性，值为以空格分隔的 id 列表
+var idRefAttrs = []string{"for", "headers", "aria-labelledby", "aria-describedby", "aria-controls"}
+
+// htmlExporter 保存导出过程中的状态
+type htmlExporter struct {
+	p          *Epub
+	mediaTypes map[string]string       // ZIP 内路径 -> manifest 中的 media-type
+	chapters   map[string]int          // 章节路径 -> 序号（从 1 开始）
+	assets     map[string]string       // ZIP 内路径 -> 导出后的链接
+	filesDir   string                  // 外部资源目录，为空时以 data: URI 内嵌
+	filesRef   string                  // 外部资源目录相对输出文件的链接
+	sheets     []*exportSheet          // 按首次出现的顺序排列的样式
+	sheetPaths map[string]*exportSheet // 样式表路径 -> 内联后的样式
+	importing  map[string]bool         // 正在展开的样式表，用于避免 @import 循环
+}
+
+// exportSheet 为一段内联的章节样式，输出时限定在使用它的章节内
+type exportSheet struct {
+	css      string
+	class    string // 链接该样式表的章节 section 上的 class，为空时只用于 chapters[0]
+	chapters []int
+}
+
+// ExportHTML 将 spine 中的章节按顺序合并为一个 HTML 文件：每个章节为一个 section，
+// 章节间的链接改为页内锚点（章节内的 id 加上 cN- 前缀以免冲突），样式表内联为 style 并限定在所属章节内，
+// 图片、字体等资源以 data: URI 内嵌或写入旁边的目录，并根据目录生成侧栏导航
+func (p *Epub) ExportHTML(outputPath string, opts HTMLExportOptions) error {
+	if p.opfDoc == nil {
+		return fmt.Errorf("content.opf not loaded")
+	}
+	e := &htmlExporter{
+		p:          p,
+		mediaTypes: make(map[string]string),
+		chapters:   make(map[string]int),
+		assets:     make(map[string]string),
+		sheetPaths: make(map[string]*exportSheet),
+		importing:  make(map[string]bool),
+	}
+	for _, item := range p.opfDoc.Manifest.Items {
+		e.mediaTypes[p.pathFromHref(item.Href)] = item.MediaType
+	}
+	if opts.ExternalAssets {
+		base := strings.TrimSuffix(filepath.Base(outputPath), filepath.Ext(outputPath)) + "_files"
+		e.filesDir = filepath.Join(filepath.Dir(outputPath), base)
+		e.filesRef = (&url.URL{Path: base}).EscapedPath()
+	}
+
+	var chapters []*zipEntry
+	for _, norm := range p.SpinePaths() {
+		entry := p.entryIndex[norm]
+		if entry == nil || entry.removed || !isHTMLEntry(entry) || e.chapters[norm] > 0 {
+			continue
+		}
+		chapters = append(chapters, entry)
+		e.chapters[norm] = len(chapters)
+	}
+	if len(chapters) == 0 {
+		return fmt.Errorf("no chapters to export")
+	}
+	if e.filesDir != "" {
+		// 不清理已有的目录，以免删除用户的文件
+		entries, err := os.ReadDir(e.filesDir)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to check assets directory: %w", err)
+		}
+		if len(entries) > 0 {
+			return fmt.Errorf("assets directory is not empty: %s", e.filesDir)
+		}
+	}
+
+	page, err := html.Parse(strings.NewReader(`<!DOCTYPE html><html><head><meta charset="utf-8"/>` +
+		`<meta name="viewport" content="width=device-width, initial-scale=1"/><title></title></head>` +
+		`<body class="epub-export"></body></html>`))
+	if err != nil {
+		return err
+	}
+	info := p.Info()
+	if info.Language != "" {
+		setAttr(findElement(page, atom.Html), "lang", info.Language)
+	}
+	title := opts.Title
+	if title == "" {
+		title = info.Title
+	}
+	findElement(page, atom.Title).AppendChild(&html.Node{Type: html.TextNode, Data: title})
+
+	content := newElement(atom.Main, "id", "epub-content")
+	var titles []string
+	for i, entry := range chapters {
+		section, chapterTitle, err := e.chapter(entry, i+1)
+		if err != nil {
+			return err
+		}
+		content.AppendChild(section)
+		titles = append(titles, chapterTitle)
+	}
+
+	body := findElement(page, atom.Body)
+	if !opts.NoSidebar {
+		sidebar, err := e.sidebar(chapters, titles)
+		if err != nil {
+			return err
+		}
+		body.AppendChild(sidebar)
+		setAttr(content, "class", "with-sidebar")
+	}
+	body.AppendChild(content)
+
+	head := findElement(page, atom.Head)
+	styles := make([]string, 0, len(e.sheets)+1)
+	for _, sheet := range e.sheets {
+		if strings.TrimSpace(sheet.css) == "" {
+			continue
+		}
+		scope := "." + sheet.class
+		if sheet.class == "" {
+			scope = "#chapter-" + strconv.Itoa(sheet.chapters[0])
+		}
+		styles = append(styles, scopeCSS(sheet.css, scope, chapterIDSelector(sheet.chapters)))
+	}
+	for _, css := range append(styles, exportCSS) {
+		style := newElement(atom.Style)
+		style.AppendChild(&html.Node{Type: html.TextNode, Data: css})
+		head.AppendChild(style)
+	}
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, page); err != nil {
+		return fmt.Errorf("failed to render HTML: %w", err)
+	}
+	if dir := filepath.Dir(outputPath); dir != "" {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write HTML: %w", err)
+	}
+	return nil
+}
+
+// chapter 将章节的 body 转换为 section，收集其样式，返回 section 与章节标题
+func (e *htmlExporter) chapter(entry *zipEntry, index int) (*html.Node, string, error) {
+	norm := entry.header.Name
+	doc, err := html.Parse(strings.NewReader(string(entry.data)))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse HTML (%s): %w", norm, err)
+	}
+	title := chapterTitle(doc)
+
+	classes := []string{"epub-chapter"}
+	if head := findElement(doc, atom.Head); head != nil {
+		walkElements(head, func(n *html.Node) {
+			if err != nil {
+				return
+			}
+			switch n.DataAtom {
+			case atom.Link:
+				href := attrValue(n, "href")
+				if hasProperty(strings.ToLower(attrValue(n, "rel")), "stylesheet") && href != "" && !isExternalRef(href) {
+					var sheet *exportSheet
+					sheet, err = e.linkedSheet(e.resolve(norm, href))
+					if sheet != nil && !slices.Contains(sheet.chapters, index) {
+						sheet.chapters = append(sheet.chapters, index)
+						classes = append(classes, sheet.class)
+					}
+				}
+			case atom.Style:
+				if n.FirstChild != nil && n.FirstChild.Type == html.TextNode {
+					var css string
+					css, err = e.css(n.FirstChild.Data, norm)
+					e.sheets = append(e.sheets, &exportSheet{css: css, chapters: []int{index}})
+				}
+			}
+		})
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	prefix := "c" + strconv.Itoa(index) + "-"
+	section := newElement(atom.Section, "id", "chapter-"+strconv.Itoa(index))
+	body := findElement(doc, atom.Body)
+	if body != nil {
+		classes = append(classes, strings.Fields(attrValue(body, "class"))...)
+	}
+	setAttr(section, "class", strings.Join(classes, " "))
+	if body == nil {
+		return section, title, nil
+	}
+	for c := body.FirstChild; c != nil; {
+		next := c.NextSibling
+		body.RemoveChild(c)
+		section.AppendChild(c)
+		c = next
+	}
+
+	walkElements(section, func(n *html.Node) {
+		// section 自身的 id 不加前缀，章节链接指向它
+		if err != nil || n == section {
+			return
+		}
+		for i, attr := range n.Attr {
+			key := attr.Key
+			if attr.Namespace != "" {
+				key = attr.Namespace + ":" + attr.Key
+			}
+			switch key {
+			case "id":
+				n.Attr[i].Val = prefix + attr.Val
+			case "name":
+				if n.DataAtom == atom.A {
+					n.Attr[i].Val = prefix + attr.Val
+				}
+			case "href", "xlink:href":
+				if n.DataAtom == atom.A || n.DataAtom == atom.Area || n.Data == "use" {
+					n.Attr[i].Val = e.link(norm, attr.Val, prefix)
+				} else {
+					n.Attr[i].Val, err = e.asset(norm, attr.Val)
+				}
+			case "src", "poster", "data":
+				if key != "data" || n.DataAtom == atom.Object {
+					n.Attr[i].Val, err = e.asset(norm, attr.Val)
+				}
+			case "style":
+				n.Attr[i].Val, err = e.css(attr.Val, norm)
+			default:
+				for _, ref := range idRefAttrs {
+					if key == ref {
+						ids := strings.Fields(attr.Val)
+						for j := range ids {
+							ids[j] = prefix + ids[j]
+						}
+						n.Attr[i].Val = strings.Join(ids, " ")
+					}
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+		// srcset 中的候选图片无法逐一内嵌，保留 src 即可
+		removeAttr(n, "srcset")
+		if n.DataAtom == atom.Style && n.FirstChild != nil && n.FirstChild.Type == html.TextNode {
+			var css string
+			css, err = e.css(n.FirstChild.Data, norm)
+			n.FirstChild.Data = scopeCSS(css, "#chapter-"+strconv.Itoa(index), chapterIDSelector([]int{index}))
+		}
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to export %s: %w", norm, err)
+	}
+	return section, title, nil
+}
+
+// sidebar 根据书中的目录生成侧栏，没有目录时列出各章节的标题
+func (e *htmlExporter) sidebar(chapters []*zipEntry, titles []string) (*html.Node, error) {
+	toc, err := e.p.TOC()
+	if err != nil {
+		return nil, err
+	}
+	if len(toc) == 0 {
+		for i, entry := range chapters {
+			title := titles[i]
+			if title == "" {
+				title = path.Base(entry.header.Name)
+			}
+			toc = append(toc, &TOCEntry{Title: title, Href: entry.header.Name, Level: 1})
+		}
+	}
+	var list func(entries []*TOCEntry) *html.Node
+	list = func(entries []*TOCEntry) *html.Node {
+		ol := newElement(atom.Ol)
+		for _, entry := range entries {
+			li := newElement(atom.Li)
+			label := newElement(atom.Span)
+			if entry.Href != "" {
+				label = newElement(atom.A, "href", e.link("", entry.Href, ""))
+			}
+			label.AppendChild(&html.Node{Type: html.TextNode, Data: entry.Title})
+			li.AppendChild(label)
+			if len(entry.Children) > 0 {
+				li.AppendChild(list(entry.Children))
+			}
+			ol.AppendChild(li)
+		}
+		return ol
+	}
+	nav := newElement(atom.Nav, "id", "epub-sidebar")
+	nav.AppendChild(list(toc))
+	return nav, nil
+}
+
+// resolve 将 base 中的相对链接（可能经过 URL 编码）解析为 ZIP 内路径
+func (e *htmlExporter) resolve(base, ref string) string {
+	target := stripFragment(ref)
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	return resolveHref(base, target)
+}
+
+// link 改写超链接：指向章节的链接改为页内锚点，指向其它文件的链接改为导出后的资源，
+// 外部链接与无法解析的链接保持不变
+func (e *htmlExporter) link(base, ref, prefix string) string {
+	if ref == "" || isExternalRef(ref) {
+		return ref
+	}
+	fragment := fragmentOf(ref)
+	if unescaped, err := url.PathUnescape(fragment); err == nil {
+		fragment = unescaped
+	}
+	if strings.HasPrefix(ref, "#") {
+		if fragment == "#" {
+			return ref
+		}
+		return "#" + prefix + fragment[1:]
+	}
+	norm := e.resolve(base, ref)
+	if index, ok := e.chapters[norm]; ok {
+		if len(fragment) <= 1 {
+			return "#chapter-" + strconv.Itoa(index)
+		}
+		return "#c" + strconv.Itoa(index) + "-" + fragment[1:]
+	}
+	if entry, ok := e.p.entryIndex[norm]; ok && !entry.removed && !entry.isDir {
+		if asset, err := e.asset(base, ref); err == nil {
+			return asset
+		}
+	}
+	return ref
+}
+
+// asset 将资源引用改为 data: URI 或外部资源目录中的链接，书中不存在的资源保持不变
+func (e *htmlExporter) asset(base, ref string) (string, error) {
+	if ref == "" || strings.HasPrefix(ref, "#") || isExternalRef(ref) {
+		return ref, nil
+	}
+	norm := e.resolve(base, ref)
+	if u, ok := e.assets[norm]; ok {
+		return u + fragmentOf(ref), nil
+	}
+	data, ok := e.p.ReadFile(norm)
+	if !ok {
+		return ref, nil
+	}
+
+	var u string
+	if e.filesDir != "" {
+		target := filepath.Join(e.filesDir, filepath.FromSlash(norm))
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(target, data, 0o644); err != nil {
+			return "", fmt.Errorf("failed to write asset: %w", err)
+		}
+		u = e.filesRef + "/" + (&url.URL{Path: norm}).EscapedPath()
+	} else {
+		mediaType := e.mediaTypes[norm]
+		if mediaType == "" {
+			mediaType = mime.TypeByExtension(strings.ToLower(path.Ext(norm)))
+		}
+		if mediaType == "" {
+			mediaType = "application/octet-stream"
+		}
+		u = "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	}
+	e.assets[norm] = u
+	return u + fragmentOf(ref), nil
+}
+
+// linkedSheet 返回章节链接的样式表，同一文件只内联一次，由链接它的各章节共用
+func (e *htmlExporter) linkedSheet(norm string) (*exportSheet, error) {
+	if sheet, ok := e.sheetPaths[norm]; ok {
+		return sheet, nil
+	}
+	css, err := e.stylesheet(norm)
+	if err != nil {
+		return nil, err
+	}
+	sheet := &exportSheet{css: css, class: "epub-css-" + strconv.Itoa(len(e.sheetPaths)+1)}
+	e.sheetPaths[norm] = sheet
+	e.sheets = append(e.sheets, sheet)
+	return sheet, nil
+}
+
+// chapterIDSelector 返回把样式中的 #id 改写为各章节导出后 id（cN-id）的函数
+func chapterIDSelector(chapters []int) func(string) string {
+	return func(id string) string {
+		if len(chapters) == 1 {
+			return "#c" + strconv.Itoa(chapters[0]) + "-" + id
+		}
+		ids := make([]string, len(chapters))
+		for i, index := range chapters {
+			ids[i] = "#c" + strconv.Itoa(index) + "-" + id
+		}
+		return ":is(" + strings.Join(ids, ", ") + ")"
+	}
+}
+
+// stylesheet 读取样式表并展开其中的 @import，循环导入或不存在的文件返回空字符串
+func (e *htmlExporter) stylesheet(norm string) (string, error) {
+	if e.importing[norm] {
+		return "", nil
+	}
+	e.importing[norm] = true
+	defer delete(e.importing, norm)
+	data, ok := e.p.ReadFile(norm)
+	if !ok {
+		return "", nil
+	}
+	return e.css(string(bytes.TrimPrefix(data, []byte("\ufeff"))), norm)
+}
+
+// css 展开 @import 并将 url() 中的资源改为导出后的链接，相对链接以 base 为基准
+func (e *htmlExporter) css(text, base string) (string, error) {
+	var err error
+	text = cssCharsetRuleRegex.ReplaceAllString(text, "")
+	text = cssImportRuleRegex.ReplaceAllStringFunc(text, func(rule string) string {
+		ref := cssImportRuleRegex.FindStringSubmatch(rule)[1]
+		if err != nil || isExternalRef(ref) {
+			return rule
+		}
+		var imported string
+		imported, err = e.stylesheet(e.resolve(base, ref))
+		return imported
+	})
+	if err != nil {
+		return "", err
+	}
+	text = replaceRefs(text, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+		var u string
+		u, err = e.asset(base, ref)
+		return u
+	}, cssURLRegex)
+	return text, err
+}
@@ -0,0 +1,486 @@
+package epub
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// DefaultFootnoteMarkers 是默认识别的脚注标记，第一个捕获组为脚注编号
+var DefaultFootnoteMarkers = []string{
+	`\[(\d{1,3})\]`,
+	`［(\d{1,3})］`,
+	`【(\d{1,3})】`,
+	`〔(\d{1,3})〕`,
+	`\[注(\d{1,3})\]`,
+}
+
+// FootnoteOptions 控制 ConvertFootnotes 的识别规则
+type FootnoteOptions struct {
+	// Markers 为脚注标记的正则，第一个捕获组为脚注编号，为空时使用 DefaultFootnoteMarkers。
+	// 正文中出现的标记会被识别为引用，以标记开头的段落会被识别为注释内容
+	Markers []string
+}
+
+// ConvertFootnotes 将章节中的脚注转换为 EPUB3 弹出式注释，返回转换的注释数：
+//   - 正文中的 [1] 等标记改写为 <a epub:type="noteref">，注释段落改写为 <aside epub:type="footnote">
+//   - 已有的 <sup><a href="#..."> 结构补充 epub:type，并把目标段落包装为 aside
+//
+// 引用与注释之间会建立双向链接，只有引用与注释都存在于同一章节时才会转换
+func (p *Epub) ConvertFootnotes(opts FootnoteOptions) (int, error) {
+	patterns := opts.Markers
+	if len(patterns) == 0 {
+		patterns = DefaultFootnoteMarkers
+	}
+	var markers []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return 0, fmt.Errorf("invalid footnote marker %q: %w", pattern, err)
+		}
+		if re.NumSubexp() < 1 {
+			return 0, fmt.Errorf("footnote marker %q must have a capture group", pattern)
+		}
+		markers = append(markers, re)
+	}
+
+	total := 0
+	for _, entry := range p.htmlEntriesInOrder() {
+		if normalizeZipPath(entry.header.Name) == p.navPath() {
+			continue
+		}
+		doc, err := html.Parse(strings.NewReader(string(entry.data)))
+		if err != nil {
+			return total, fmt.Errorf("failed to parse HTML (%s): %w", entry.header.Name, err)
+		}
+
+		converted := convertLinkedFootnotes(doc, markers) + convertTextFootnotes(doc, markers)
+		if converted == 0 {
+			continue
+		}
+		rendered, err := renderXHTMLDocument(doc, p.defaultDoctype())
+		if err != nil {
+			return total, fmt.Errorf("failed to render HTML (%s): %w", entry.header.Name, err)
+		}
+		entry.data = []byte(rendered)
+		total += converted
+	}
+	return total, nil
+}
+
+// convertLinkedFootnotes 处理已有的页内链接形式的脚注（<sup><a href="#fn1">1</a></sup>）
+func convertLinkedFootnotes(doc *html.Node, markers []*regexp.Regexp) int {
+	ids := make(map[string]*html.Node)
+	walkElements(doc, func(n *html.Node) {
+		if id := attrValue(n, "id"); id != "" {
+			ids[id] = n
+		}
+	})
+
+	converted := 0
+	// 多个引用指向同一注释时只转换一次，其余引用只标记为 noteref
+	done := make(map[string]bool)
+	var refs []*html.Node
+	walkElements(doc, func(n *html.Node) {
+		if n.DataAtom == atom.A && strings.HasPrefix(attrValue(n, "href"), "#") && isFootnoteRef(n, markers) {
+			refs = append(refs, n)
+		}
+	})
+
+	for i, ref := range refs {
+		noteID := strings.TrimPrefix(attrValue(ref, "href"), "#")
+		if done[noteID] {
+			setAttr(ref, "epub:type", "noteref")
+			continue
+		}
+		target := ids[noteID]
+		if target == nil || isInside(ref, target) {
+			continue
+		}
+		block := footnoteBlock(target)
+		if block == nil {
+			continue
+		}
+		done[noteID] = true
+
+		refID := attrValue(ref, "id")
+		if refID == "" {
+			refID = uniqueID(ids, fmt.Sprintf("noteref-%d", i+1))
+			setAttr(ref, "id", refID)
+			ids[refID] = ref
+		}
+		setAttr(ref, "epub:type", "noteref")
+
+		// 注释的 id 移到 aside 上（目标可能是块本身，也可能是块内的锚点），避免出现重复的 id
+		aside := wrapInAside(block, "")
+		if target != aside && attrValue(aside, "id") == "" {
+			removeAttr(target, "id")
+			setAttr(aside, "id", noteID)
+			ids[noteID] = aside
+		}
+		if !hasLinkTo(aside, refID) {
+			back := newElement(atom.A, "href", "#"+refID)
+			back.AppendChild(&html.Node{Type: html.TextNode, Data: "↩"})
+			block.AppendChild(&html.Node{Type: html.TextNode, Data: " "})
+			block.AppendChild(back)
+		}
+		converted++
+	}
+	return converted
+}
+
+// convertTextFootnotes 处理纯文本形式的脚注：正文中的 [1] 与以 [1] 开头的注释段落
+func convertTextFootnotes(doc *html.Node, markers []*regexp.Regexp) int {
+	ids := make(map[string]*html.Node)
+	walkElements(doc, func(n *html.Node) {
+		if id := attrValue(n, "id"); id != "" {
+			ids[id] = n
+		}
+	})
+
+	// 以标记开头的块级元素视为注释
+	notes := make(map[string]*html.Node)
+	var noteOrder []string
+	walkElements(doc, func(n *html.Node) {
+		if !isNoteCandidate(n) || hasAncestorAside(n) {
+			return
+		}
+		text := strings.TrimSpace(nodeText(n))
+		for _, re := range markers {
+			loc := re.FindStringSubmatchIndex(text)
+			if loc == nil || loc[0] != 0 {
+				continue
+			}
+			key := text[loc[2]:loc[3]]
+			if _, ok := notes[key]; !ok {
+				notes[key] = n
+				noteOrder = append(noteOrder, key)
+			}
+			return
+		}
+	})
+	if len(notes) == 0 {
+		return 0
+	}
+
+	// 找出正文中的引用标记（跳过注释段落、链接与脚本）
+	type textMatch struct {
+		node  *html.Node
+		start int
+		end   int
+		key   string
+	}
+	var matches []textMatch
+	referenced := make(map[string]bool)
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.A, atom.Script, atom.Style, atom.Head:
+				return
+			}
+			for _, note := range notes {
+				if n == note {
+					return
+				}
+			}
+		}
+		if n.Type == html.TextNode {
+			for _, re := range markers {
+				for _, loc := range re.FindAllStringSubmatchIndex(n.Data, -1) {
+					key := n.Data[loc[2]:loc[3]]
+					if _, ok := notes[key]; ok {
+						matches = append(matches, textMatch{node: n, start: loc[0], end: loc[1], key: key})
+						referenced[key] = true
+					}
+				}
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	if len(matches) == 0 {
+		return 0
+	}
+
+	// 生成的 fn-/fnref- id 避开文档中已有的 id
+	noteIDs := make(map[string]string)
+	refIDs := make(map[string]string)
+	for _, key := range noteOrder {
+		if !referenced[key] {
+			continue
+		}
+		noteIDs[key] = uniqueID(ids, "fn-"+key)
+		ids[noteIDs[key]] = notes[key]
+		refIDs[key] = uniqueID(ids, "fnref-"+key)
+		ids[refIDs[key]] = notes[key]
+	}
+
+	// 按文本节点分组后从后往前拆分，保证偏移量有效
+	assigned := make(map[string]bool)
+	byNode := make(map[*html.Node][]textMatch)
+	var nodes []*html.Node
+	for _, m := range matches {
+		if _, ok := byNode[m.node]; !ok {
+			nodes = append(nodes, m.node)
+		}
+		byNode[m.node] = append(byNode[m.node], m)
+	}
+	for _, node := range nodes {
+		ms := byNode[node]
+		sort.Slice(ms, func(i, j int) bool { return ms[i].start < ms[j].start })
+
+		text := node.Data
+		parent := node.Parent
+		pos := 0
+		for _, m := range ms {
+			if m.start < pos {
+				continue
+			}
+			if m.start > pos {
+				parent.InsertBefore(&html.Node{Type: html.TextNode, Data: text[pos:m.start]}, node)
+			}
+			ref := newElement(atom.A, "epub:type", "noteref", "href", "#"+noteIDs[m.key])
+			if !assigned[m.key] {
+				assigned[m.key] = true
+				setAttr(ref, "id", refIDs[m.key])
+			}
+			ref.AppendChild(&html.Node{Type: html.TextNode, Data: text[m.start:m.end]})
+			sup := newElement(atom.Sup)
+			sup.AppendChild(ref)
+			parent.InsertBefore(sup, node)
+			pos = m.end
+		}
+		if pos < len(text) {
+			parent.InsertBefore(&html.Node{Type: html.TextNode, Data: text[pos:]}, node)
+		}
+		parent.RemoveChild(node)
+	}
+
+	converted := 0
+	for _, key := range noteOrder {
+		if !referenced[key] {
+			continue
+		}
+		note := notes[key]
+		wrapInAside(note, noteIDs[key])
+		linkNoteMarker(note, markers, key, "#"+refIDs[key])
+		converted++
+	}
+	return converted
+}
+
+// linkNoteMarker 将注释开头的标记改写为指回引用处的链接
+func linkNoteMarker(note *html.Node, markers []*regexp.Regexp, key, href string) {
+	var first *html.Node
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if first != nil {
+			return
+		}
+		if n.Type == html.TextNode && strings.TrimSpace(n.Data) != "" {
+			first = n
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(note)
+	if first == nil || (first.Parent != nil && first.Parent.DataAtom == atom.A) {
+		return
+	}
+	for _, re := range markers {
+		loc := re.FindStringSubmatchIndex(first.Data)
+		if loc == nil || strings.TrimSpace(first.Data[:loc[0]]) != "" || first.Data[loc[2]:loc[3]] != key {
+			continue
+		}
+		if loc[0] > 0 {
+			first.Parent.InsertBefore(&html.Node{Type: html.TextNode, Data: first.Data[:loc[0]]}, first)
+		}
+		back := newElement(atom.A, "href", href)
+		back.AppendChild(&html.Node{Type: html.TextNode, Data: first.Data[loc[0]:loc[1]]})
+		first.Parent.InsertBefore(back, first)
+		first.Data = first.Data[loc[1]:]
+		return
+	}
+}
+
+// wrapInAside 用 <aside epub:type="footnote"> 包装节点，节点本身或其父节点已是 footnote aside 时直接返回该 aside；
+// 节点内已有同名 id 时不再给 aside 设置 id
+func wrapInAside(n *html.Node, id string) *html.Node {
+	if isFootnoteAside(n) {
+		return n
+	}
+	if parent := n.Parent; parent != nil && isFootnoteAside(parent) {
+		return parent
+	}
+	aside := newElement(atom.Aside, "epub:type", "footnote")
+	if id != "" && !hasID(n, id) {
+		setAttr(aside, "id", id)
+	}
+	n.Parent.InsertBefore(aside, n)
+	n.Parent.RemoveChild(n)
+	aside.AppendChild(n)
+	return aside
+}
+
+func isFootnoteAside(n *html.Node) bool {
+	return n.DataAtom == atom.Aside && hasProperty(attrValue(n, "epub:type"), "footnote")
+}
+
+// hasID 判断 n 或其后代元素中是否有指定的 id
+func hasID(n *html.Node, id string) bool {
+	found := false
+	walkElements(n, func(e *html.Node) {
+		if attrValue(e, "id") == id {
+			found = true
+		}
+	})
+	return found
+}
+
+// isFootnoteRef 判断页内链接是否像脚注引用：位于 sup 中，或链接文本是标记/数字/星号
+func isFootnoteRef(a *html.Node, markers []*regexp.Regexp) bool {
+	if hasProperty(attrValue(a, "epub:type"), "noteref") {
+		return true
+	}
+	for n := a.Parent; n != nil; n = n.Parent {
+		if n.DataAtom == atom.Sup {
+			return true
+		}
+		if n.Type == html.ElementNode && isBlockElement(n.DataAtom) {
+			break
+		}
+	}
+	text := strings.TrimSpace(nodeText(a))
+	if text == "" {
+		return false
+	}
+	if strings.Trim(text, "0123456789*†‡") == "" {
+		return true
+	}
+	for _, re := range markers {
+		if loc := re.FindStringIndex(text); loc != nil && loc[0] == 0 && loc[1] == len(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// footnoteBlock 返回目标所在的注释块：目标本身是块级元素时为其本身，否则为最近的块级祖先
+func footnoteBlock(target *html.Node) *html.Node {
+	for n := target; n != nil; n = n.Parent {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		switch n.DataAtom {
+		case atom.Body, atom.Html:
+			return nil
+		}
+		if isNoteCandidate(n) || n.DataAtom == atom.Aside {
+			return n
+		}
+	}
+	return nil
+}
+
+func isNoteCandidate(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	switch n.DataAtom {
+	case atom.P, atom.Div, atom.Li, atom.Dd, atom.Blockquote:
+		// 只取最内层的块，避免把包含整章的 div 当作注释
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && isBlockElement(c.DataAtom) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func hasAncestorAside(n *html.Node) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.DataAtom == atom.Aside {
+			return true
+		}
+	}
+	return false
+}
+
+func isInside(n, ancestor *html.Node) bool {
+	for p := n; p != nil; p = p.Parent {
+		if p == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+func hasLinkTo(n *html.Node, id string) bool {
+	found := false
+	walkElements(n, func(e *html.Node) {
+		if e.DataAtom == atom.A && attrValue(e, "href") == "#"+id {
+			found = true
+		}
+	})
+	return found
+}
+
+func uniqueID(ids map[string]*html.Node, base string) string {
+	id := base
+	for i := 2; ids[id] != nil; i++ {
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+	return id
+}
+
+// walkElements 深度优先遍历所有元素节点
+func walkElements(n *html.Node, fn func(*html.Node)) {
+	if n.Type == html.ElementNode {
+		fn(n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walkElements(c, fn)
+	}
+}
+
+// setAttr 设置 HTML 元素属性
+func setAttr(n *html.Node, key, val string) {
+	for i, attr := range n.Attr {
+		name := attr.Key
+		if attr.Namespace != "" {
+			name = attr.Namespace + ":" + attr.Key
+		}
+		if name == key {
+			n.Attr[i] = html.Attribute{Key: key, Val: val}
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+// removeAttr 删除 HTML 元素属性
+func removeAttr(n *html.Node, key string) {
+	attrs := n.Attr[:0]
+	for _, attr := range n.Attr {
+		name := attr.Key
+		if attr.Namespace != "" {
+			name = attr.Namespace + ":" + attr.Key
+		}
+		if name != key {
+			attrs = append(attrs, attr)
+		}
+	}
+	n.Attr = attrs
+}
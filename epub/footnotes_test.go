@@ -0,0 +1,104 @@
+package epub
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestConvertFootnotes(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		converted int
+		asides    int
+		want      []string
+	}{
+		{
+			name:      "linked paragraph",
+			body:      `<p>a<sup><a href="#fn1">1</a></sup></p><p id="fn1">note</p>`,
+			converted: 1, asides: 1,
+			want: []string{
+				`<a href="#fn1" id="noteref-1" epub:type="noteref">1</a>`,
+				`<aside epub:type="footnote" id="fn1"><p>note <a href="#noteref-1">↩</a></p></aside>`,
+			},
+		},
+		{
+			name:      "linked inline anchor",
+			body:      `<p>a<sup><a href="#fn1">1</a></sup></p><p><a id="fn1">1.</a> note</p>`,
+			converted: 1, asides: 1,
+			want: []string{`<aside epub:type="footnote" id="fn1"><p><a>1.</a> note`},
+		},
+		{
+			name:      "several refs to one note",
+			body:      `<p>a<sup><a href="#n">1</a></sup> b<sup><a href="#n">1</a></sup></p><p id="n">note</p>`,
+			converted: 1, asides: 1,
+			want: []string{`<a href="#n" epub:type="noteref">1</a>`},
+		},
+		{
+			name:      "note already in aside",
+			body:      `<p>a<sup><a href="#n">1</a></sup></p><aside epub:type="footnote"><p id="n">note</p></aside>`,
+			converted: 1, asides: 1,
+			want: []string{`<aside epub:type="footnote" id="n"><p>note`},
+		},
+		{
+			name:      "text markers",
+			body:      `<p>x[1] y[1]</p><p>[1] note</p>`,
+			converted: 1, asides: 1,
+			want: []string{
+				`<a epub:type="noteref" href="#fn-1" id="fnref-1">[1]</a>`,
+				`<aside epub:type="footnote" id="fn-1"><p><a href="#fnref-1">[1]</a> note</p></aside>`,
+			},
+		},
+		{
+			name:      "text markers avoid existing ids",
+			body:      `<p id="fn-1">x[1]</p><p id="fnref-1">[1] note</p>`,
+			converted: 1, asides: 1,
+			want: []string{`href="#fn-1-2" id="fnref-1-2"`, `<aside epub:type="footnote" id="fn-1-2">`},
+		},
+		{
+			name: "missing target",
+			body: `<p>a<sup><a href="#nothing">1</a></sup></p>`,
+		},
+	}
+	var markers []*regexp.Regexp
+	for _, pattern := range DefaultFootnoteMarkers {
+		markers = append(markers, regexp.MustCompile(pattern))
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader("<html><body>" + tt.body + "</body></html>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			converted := convertLinkedFootnotes(doc, markers) + convertTextFootnotes(doc, markers)
+			var buf strings.Builder
+			if err := html.Render(&buf, doc); err != nil {
+				t.Fatal(err)
+			}
+			got := buf.String()
+			if converted != tt.converted {
+				t.Errorf("converted %d notes, want %d", converted, tt.converted)
+			}
+			if asides := strings.Count(got, "<aside"); asides != tt.asides {
+				t.Errorf("got %d asides, want %d:\n%s", asides, tt.asides, got)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("output missing %q:\n%s", want, got)
+				}
+			}
+			seen := make(map[string]bool)
+			walkElements(doc, func(n *html.Node) {
+				if id := attrValue(n, "id"); id != "" {
+					if seen[id] {
+						t.Errorf("duplicate id %q:\n%s", id, got)
+					}
+					seen[id] = true
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package epub
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testFile 为写入测试 EPUB 的一个条目
+type testFile struct {
+	name string
+	data string
+}
+
+const testContainerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+const testOPF = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="uid">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="uid">urn:uuid:test</dc:identifier>
+    <dc:title>Test</dc:title>
+    <dc:language>en</dc:language>
+  </metadata>
+  <manifest>
+    <item id="ch1" href="Text/ch1.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+  <spine>
+    <itemref idref="ch1"/>
+  </spine>
+</package>
+`
+
+const testChapter = `<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml"><head><title>Chapter</title></head><body><p>Hello</p></body></html>
+`
+
+// writeTestEPUB 在临时目录中写入一个 EPUB：mimetype、container.xml 与 opf 为 testOPF 的最小书籍，
+// 再追加 extra 中的条目；opf 为空时使用 testOPF
+func writeTestEPUB(t *testing.T, opf string, extra ...testFile) string {
+	t.Helper()
+	if opf == "" {
+		opf = testOPF
+	}
+	files := append([]testFile{
+		{"META-INF/container.xml", testContainerXML},
+		{"OEBPS/content.opf", opf},
+		{"OEBPS/Text/ch1.xhtml", testChapter},
+	}, extra...)
+
+	outputPath := filepath.Join(t.TempDir(), "test.epub")
+	out, err := os.Create(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = out.Close() }()
+	writer := zip.NewWriter(out)
+	w, err := writer.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(epubMimetype)); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		w, err := writer.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return outputPath
+}
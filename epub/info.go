@@ -0,0 +1,214 @@
+package epub
+
+import (
+	"bytes"
+	"encoding/xml"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Info 描述 EPUB 的基础信息
+type Info struct {
+	Title      string   `json:"title"`
+	Creators   []string `json:"creators,omitempty"`
+	Language   string   `json:"language,omitempty"`
+	Identifier string   `json:"identifier,omitempty"`
+	Version    string   `json:"version,omitempty"`
+	OPFPath    string   `json:"opfPath"`
+	Chapters   int      `json:"chapters"`
+	Files      int      `json:"files"`
+	Protection string   `json:"protection,omitempty"`
+}
+
+// FileInfo 描述 EPUB 内的单个文件
+type FileInfo struct {
+	Name           string `json:"name"`
+	Size           int    `json:"size"`
+	CompressedSize uint64 `json:"compressedSize,omitempty"`
+	MediaType      string `json:"mediaType,omitempty"`
+	InSpine        bool   `json:"inSpine,omitempty"`
+}
+
+// Info 返回书名、作者等基础信息
+func (p *Epub) Info() Info {
+	info := Info{
+		OPFPath:  p.opfPath,
+		Chapters: p.CountHTML(),
+	}
+	for _, entry := range p.entries {
+		if !entry.removed && !entry.isDir {
+			info.Files++
+		}
+	}
+	if p.protection.Scheme != SchemeNone {
+		info.Protection = p.protection.String()
+	}
+	if p.opfDoc == nil {
+		return info
+	}
+	info.Version = p.opfDoc.Version
+
+	meta := parseDCMetadata(p.opfDoc.Metadata.InnerXML)
+	if len(meta.Titles) > 0 {
+		info.Title = strings.TrimSpace(meta.Titles[0])
+	}
+	for _, creator := range meta.Creators {
+		if c := strings.TrimSpace(creator); c != "" {
+			info.Creators = append(info.Creators, c)
+		}
+	}
+	if len(meta.Languages) > 0 {
+		info.Language = strings.TrimSpace(meta.Languages[0])
+	}
+	for _, id := range meta.Identifiers {
+		if p.opfDoc.UniqueIdentifier == "" || id.ID == p.opfDoc.UniqueIdentifier {
+			info.Identifier = strings.TrimSpace(id.Value)
+			break
+		}
+	}
+	return info
+}
+
+// Files 返回 EPUB 内所有文件（不含目录），按 ZIP 中的顺序排列
+func (p *Epub) Files() []FileInfo {
+	mediaTypes := make(map[string]string)
+	spine := make(map[string]bool)
+	for _, name := range p.SpinePaths() {
+		spine[name] = true
+	}
+	if p.opfDoc != nil {
+		for _, item := range p.opfDoc.Manifest.Items {
+			mediaTypes[p.pathFromHref(item.Href)] = item.MediaType
+		}
+	}
+
+	var files []FileInfo
+	for _, entry := range p.entries {
+		if entry.removed || entry.isDir {
+			continue
+		}
+		norm := normalizeZipPath(entry.header.Name)
+		files = append(files, FileInfo{
+			Name:           entry.header.Name,
+			Size:           len(entry.data),
+			CompressedSize: entry.header.CompressedSize64,
+			MediaType:      mediaTypes[norm],
+			InSpine:        spine[norm],
+		})
+	}
+	return files
+}
+
+// SpinePaths 按 spine 顺序返回章节在 ZIP 内的路径
+func (p *Epub) SpinePaths() []string {
+	if p.opfDoc == nil {
+		return nil
+	}
+	hrefs := make(map[string]string, len(p.opfDoc.Manifest.Items))
+	for _, item := range p.opfDoc.Manifest.Items {
+		hrefs[item.ID] = item.Href
+	}
+	var paths []string
+	for _, ref := range p.opfDoc.Spine.Items {
+		href, ok := hrefs[ref.IDRef]
+		if !ok {
+			continue
+		}
+		norm := p.pathFromHref(href)
+		if entry, ok := p.entryIndex[norm]; ok && !entry.removed {
+			paths = append(paths, norm)
+		}
+	}
+	return paths
+}
+
+// ReadFile 读取 EPUB 内指定文件的内容
+func (p *Epub) ReadFile(filePath string) ([]byte, bool) {
+	entry, ok := p.entryIndex[normalizeZipPath(filePath)]
+	if !ok || entry.removed || entry.isDir {
+		return nil, false
+	}
+	return entry.data, true
+}
+
+// htmlEntriesInOrder 返回所有 HTML 条目，spine 中的章节按阅读顺序在前，其余按路径排序在后
+func (p *Epub) htmlEntriesInOrder() []*zipEntry {
+	var ordered []*zipEntry
+	seen := make(map[*zipEntry]bool)
+	for _, norm := range p.SpinePaths() {
+		entry := p.entryIndex[norm]
+		if entry == nil || seen[entry] || !isHTMLEntry(entry) {
+			continue
+		}
+		seen[entry] = true
+		ordered = append(ordered, entry)
+	}
+
+	var rest []*zipEntry
+	for _, entry := range p.entryIndex {
+		if entry.removed || seen[entry] || !isHTMLEntry(entry) {
+			continue
+		}
+		seen[entry] = true
+		rest = append(rest, entry)
+	}
+	sort.Slice(rest, func(i, j int) bool {
+		return rest[i].header.Name < rest[j].header.Name
+	})
+	return append(ordered, rest...)
+}
+
+// pathFromHref 将 OPF 中的 href 转换为 ZIP 内路径
+func (p *Epub) pathFromHref(href string) string {
+	href = stripFragment(href)
+	if p.opfDir == "" {
+		return normalizeZipPath(href)
+	}
+	return normalizeZipPath(p.opfDir + "/" + href)
+}
+
+// stripFragment 去掉链接中的 #fragment 与 ?query 部分
+func stripFragment(href string) string {
+	if i := strings.IndexAny(href, "#?"); i >= 0 {
+		href = href[:i]
+	}
+	return href
+}
+
+// resolveHref 以 baseFile 所在目录为基准解析相对链接，返回 ZIP 内路径
+func resolveHref(baseFile, href string) string {
+	href = stripFragment(href)
+	if href == "" {
+		return normalizeZipPath(baseFile)
+	}
+	dir := path.Dir(normalizeZipPath(baseFile))
+	if dir == "." {
+		return normalizeZipPath(href)
+	}
+	return normalizeZipPath(dir + "/" + href)
+}
+
+type dcIdentifier struct {
+	ID    string `xml:"id,attr"`
+	Value string `xml:",chardata"`
+}
+
+type dcMetadata struct {
+	Titles      []string       `xml:"title"`
+	Creators    []string       `xml:"creator"`
+	Languages   []string       `xml:"language"`
+	Identifiers []dcIdentifier `xml:"identifier"`
+}
+
+// parseDCMetadata 解析 metadata 中的 Dublin Core 字段
+func parseDCMetadata(innerXML []byte) dcMetadata {
+	var meta dcMetadata
+	wrapped := bytes.NewBufferString(`<metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf">`)
+	wrapped.Write(innerXML)
+	wrapped.WriteString(`</metadata>`)
+	decoder := xml.NewDecoder(wrapped)
+	decoder.Strict = false
+	_ = decoder.Decode(&meta)
+	return meta
+}
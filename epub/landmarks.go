@@ -0,0 +1,556 @@
+package epub
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Landmark 描述一个结构性入口（EPUB3 nav landmarks / EPUB2 guide reference）
+// Type 使用 EPUB3 结构语义词汇（如 cover、toc、bodymatter），Href 为 ZIP 内路径，可带 #fragment
+type Landmark struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	Href  string `json:"href"`
+}
+
+// PageTarget 描述与纸书页码对应的位置（EPUB3 nav page-list / NCX pageList）
+// Href 为 ZIP 内路径，可带 #fragment
+type PageTarget struct {
+	Label string `json:"label"`
+	Href  string `json:"href"`
+}
+
+// EPUB3 landmarks 与 EPUB2 guide 的类型对照，未列出的类型两边同名
+var landmarkToGuide = map[string]string{
+	"titlepage":       "title-page",
+	"bodymatter":      "text",
+	"acknowledgments": "acknowledgements",
+	"endnotes":        "notes",
+}
+
+var guideToLandmark = func() map[string]string {
+	m := make(map[string]string, len(landmarkToGuide))
+	for k, v := range landmarkToGuide {
+		m[v] = k
+	}
+	return m
+}()
+
+// GuideReferences 返回 OPF guide 中的引用，Type 转换为 EPUB3 词汇
+func (p *Epub) GuideReferences() []Landmark {
+	if p.opfDoc == nil || p.opfDoc.Guide == nil {
+		return nil
+	}
+	landmarks := make([]Landmark, 0, len(p.opfDoc.Guide.References))
+	for _, ref := range p.opfDoc.Guide.References {
+		typ := ref.Type
+		if t, ok := guideToLandmark[typ]; ok {
+			typ = t
+		}
+		landmarks = append(landmarks, Landmark{
+			Type:  typ,
+			Title: ref.Title,
+			Href:  p.pathFromHref(ref.Href) + fragmentOf(ref.Href),
+		})
+	}
+	return landmarks
+}
+
+// Landmarks 返回导航文档中的 landmarks；没有 EPUB3 导航文档时返回 guide 中的引用
+func (p *Epub) Landmarks() ([]Landmark, error) {
+	navPath := p.navPath()
+	if navPath == "" {
+		return p.GuideReferences(), nil
+	}
+	doc, err := p.parseHTMLEntry(navPath)
+	if err != nil {
+		return nil, err
+	}
+	nav := findNav(doc, "landmarks")
+	if nav == nil {
+		return p.GuideReferences(), nil
+	}
+	var landmarks []Landmark
+	for _, a := range navLinks(nav) {
+		href := attrValue(a, "href")
+		landmarks = append(landmarks, Landmark{
+			Type:  attrValue(a, "epub:type"),
+			Title: strings.TrimSpace(nodeText(a)),
+			Href:  resolveHref(navPath, href) + fragmentOf(href),
+		})
+	}
+	return landmarks, nil
+}
+
+// SetLandmarks 同时写入 EPUB3 导航文档的 landmarks（存在导航文档时）与 EPUB2 的 guide
+func (p *Epub) SetLandmarks(landmarks []Landmark) error {
+	for _, l := range landmarks {
+		if l.Type == "" || l.Href == "" {
+			return fmt.Errorf("landmark type and href cannot be empty")
+		}
+	}
+
+	if p.opfDoc != nil {
+		if len(landmarks) == 0 {
+			p.opfDoc.Guide = nil
+		} else {
+			guide := &opfGuide{}
+			for _, l := range landmarks {
+				typ := l.Type
+				if t, ok := landmarkToGuide[typ]; ok {
+					typ = t
+				}
+				target, fragment := splitFragment(l.Href)
+				href, err := p.hrefForOPF(normalizeZipPath(target))
+				if err != nil {
+					return err
+				}
+				guide.References = append(guide.References, opfGuideReference{
+					Type:  typ,
+					Title: l.Title,
+					Href:  href + fragment,
+				})
+			}
+			p.opfDoc.Guide = guide
+		}
+	}
+
+	navPath := p.navPath()
+	if navPath == "" {
+		return nil
+	}
+	return p.updateNavList(navPath, "landmarks", "Landmarks", len(landmarks), func(i int) (string, string, string) {
+		return landmarks[i].Type, landmarks[i].Title, landmarks[i].Href
+	})
+}
+
+// SetGuideReferences 设置 guide，Type 使用 EPUB3 词汇；与 SetLandmarks 相同，两种表示会保持一致
+func (p *Epub) SetGuideReferences(refs []Landmark) error {
+	return p.SetLandmarks(refs)
+}
+
+// PageList 返回页码列表，优先读取导航文档的 page-list，其次读取 NCX 的 pageList
+func (p *Epub) PageList() ([]PageTarget, error) {
+	if navPath := p.navPath(); navPath != "" {
+		doc, err := p.parseHTMLEntry(navPath)
+		if err != nil {
+			return nil, err
+		}
+		if nav := findNav(doc, "page-list"); nav != nil {
+			var pages []PageTarget
+			for _, a := range navLinks(nav) {
+				href := attrValue(a, "href")
+				pages = append(pages, PageTarget{
+					Label: strings.TrimSpace(nodeText(a)),
+					Href:  resolveHref(navPath, href) + fragmentOf(href),
+				})
+			}
+			return pages, nil
+		}
+	}
+
+	ncxPath := p.ncxPath()
+	if ncxPath == "" {
+		return nil, nil
+	}
+	tree, err := p.parseXMLEntry(ncxPath)
+	if err != nil {
+		return nil, err
+	}
+	pageList := tree.root().child("pageList")
+	if pageList == nil {
+		return nil, nil
+	}
+	var pages []PageTarget
+	for _, target := range pageList.children {
+		if target.kind != xmlElementNode || target.localName() != "pageTarget" {
+			continue
+		}
+		var label, src string
+		if navLabel := target.child("navLabel"); navLabel != nil {
+			if text := navLabel.child("text"); text != nil {
+				label = strings.TrimSpace(text.text())
+			}
+		}
+		if content := target.child("content"); content != nil {
+			src, _ = content.attr("src")
+		}
+		pages = append(pages, PageTarget{
+			Label: label,
+			Href:  resolveHref(ncxPath, src) + fragmentOf(src),
+		})
+	}
+	return pages, nil
+}
+
+// SetPageList 同时写入导航文档的 page-list 与 NCX 的 pageList（两者存在时）
+func (p *Epub) SetPageList(pages []PageTarget) error {
+	for _, page := range pages {
+		if page.Label == "" || page.Href == "" {
+			return fmt.Errorf("page label and href cannot be empty")
+		}
+	}
+
+	if navPath := p.navPath(); navPath != "" {
+		err := p.updateNavList(navPath, "page-list", "Pages", len(pages), func(i int) (string, string, string) {
+			return "", pages[i].Label, pages[i].Href
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	ncxPath := p.ncxPath()
+	if ncxPath == "" {
+		return nil
+	}
+	tree, err := p.parseXMLEntry(ncxPath)
+	if err != nil {
+		return err
+	}
+	root := tree.root()
+
+	var targets []*xmlNode
+	if len(pages) > 0 {
+		playOrder := maxPlayOrder(root)
+		prefix := prefixOf(root.name)
+		pageList := &xmlNode{kind: xmlElementNode, name: prefix + "pageList"}
+		pageList.children = append(pageList.children, ncxLabel(prefix, "Pages"))
+		for i, page := range pages {
+			target, fragment := splitFragment(page.Href)
+			playOrder++
+			node := &xmlNode{kind: xmlElementNode, name: prefix + "pageTarget"}
+			node.setAttr("id", "page-"+strconv.Itoa(i+1))
+			node.setAttr("type", pageType(page.Label))
+			if _, err := strconv.Atoi(page.Label); err == nil {
+				node.setAttr("value", page.Label)
+			}
+			node.setAttr("playOrder", strconv.Itoa(playOrder))
+			content := &xmlNode{kind: xmlElementNode, name: prefix + "content"}
+			content.setAttr("src", relativeRef(ncxPath, normalizeZipPath(target))+fragment)
+			node.children = append(node.children, ncxLabel(prefix, page.Label), content)
+			pageList.children = append(pageList.children, node)
+		}
+		targets = append(targets, pageList)
+	}
+	root.syncElementChildren("pageList", targets)
+	return p.writeEntry(ncxPath, tree.bytes())
+}
+
+// removeNavigationReferences 删除 guide、landmarks 与 page-list 中指向 norm 的条目；
+// 只解析提到 norm 的导航文档与 NCX，它们无法解析时保持原样而不是让删除失败
+func (p *Epub) removeNavigationReferences(norm string) error {
+	pointsTo := func(href string) bool {
+		target, _ := splitFragment(href)
+		return normalizeZipPath(target) == norm
+	}
+
+	if p.opfDoc != nil && p.opfDoc.Guide != nil {
+		kept := make([]opfGuideReference, 0, len(p.opfDoc.Guide.References))
+		for _, ref := range p.opfDoc.Guide.References {
+			if !pointsTo(p.pathFromHref(ref.Href)) {
+				kept = append(kept, ref)
+			}
+		}
+		if len(kept) != len(p.opfDoc.Guide.References) {
+			p.opfDoc.Guide.References = kept
+		}
+	}
+
+	navPath, ncxPath := p.navPath(), p.ncxPath()
+	navRefs := navPath != "" && navPath != norm && p.mentionsFile(navPath, norm)
+	ncxRefs := ncxPath != "" && ncxPath != norm && p.mentionsFile(ncxPath, norm)
+	if navRefs {
+		if nav := p.findNavEntry(navPath, "landmarks"); nav != nil {
+			links := navLinks(nav)
+			var kept []*html.Node
+			for _, a := range links {
+				if !pointsTo(resolveHref(navPath, attrValue(a, "href"))) {
+					kept = append(kept, a)
+				}
+			}
+			if len(kept) != len(links) {
+				err := p.updateNavList(navPath, "landmarks", "", len(kept), func(i int) (string, string, string) {
+					href := attrValue(kept[i], "href")
+					return attrValue(kept[i], "epub:type"), strings.TrimSpace(nodeText(kept[i])), resolveHref(navPath, href) + fragmentOf(href)
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if !navRefs && !ncxRefs {
+		return nil
+	}
+	pages, err := p.PageList()
+	if err != nil {
+		// 导航文件损坏时保留原样，不影响文件的删除
+		return nil
+	}
+	kept := pages[:0]
+	for _, page := range pages {
+		if !pointsTo(page.Href) {
+			kept = append(kept, page)
+		}
+	}
+	if len(kept) != len(pages) {
+		return p.SetPageList(kept)
+	}
+	return nil
+}
+
+// updateNavList 用 count 个条目替换导航文档中 epub:type 为 navType 的 nav 列表；count 为 0 时删除该 nav
+func (p *Epub) updateNavList(navPath, navType, heading string, count int, item func(i int) (typ, title, href string)) error {
+	doc, err := p.parseHTMLEntry(navPath)
+	if err != nil {
+		return err
+	}
+	nav := findNav(doc, navType)
+
+	if count == 0 {
+		if nav == nil {
+			return nil
+		}
+		nav.Parent.RemoveChild(nav)
+	} else {
+		if nav == nil {
+			body := findElement(doc, atom.Body)
+			if body == nil {
+				return fmt.Errorf("body not found in navigation document %s", navPath)
+			}
+			nav = newElement(atom.Nav, "epub:type", navType, "hidden", "hidden")
+			h2 := newElement(atom.H2)
+			h2.AppendChild(&html.Node{Type: html.TextNode, Data: heading})
+			nav.AppendChild(h2)
+			body.AppendChild(nav)
+		}
+
+		ol := findElement(nav, atom.Ol)
+		if ol == nil {
+			ol = newElement(atom.Ol)
+			nav.AppendChild(ol)
+		}
+		for c := ol.FirstChild; c != nil; c = ol.FirstChild {
+			ol.RemoveChild(c)
+		}
+		for i := 0; i < count; i++ {
+			typ, title, href := item(i)
+			target, fragment := splitFragment(href)
+			a := newElement(atom.A, "href", relativeRef(navPath, normalizeZipPath(target))+fragment)
+			if typ != "" {
+				a.Attr = append([]html.Attribute{{Key: "epub:type", Val: typ}}, a.Attr...)
+			}
+			a.AppendChild(&html.Node{Type: html.TextNode, Data: title})
+			li := newElement(atom.Li)
+			li.AppendChild(a)
+			ol.AppendChild(li)
+		}
+	}
+
+	rendered, err := renderXHTMLDocument(doc, p.defaultDoctype())
+	if err != nil {
+		return err
+	}
+	return p.writeEntry(navPath, []byte(rendered))
+}
+
+// navPath 返回 EPUB3 导航文档在 ZIP 内的路径，不存在时返回空字符串
+func (p *Epub) navPath() string {
+	if p.opfDoc == nil {
+		return ""
+	}
+	for _, item := range p.opfDoc.Manifest.Items {
+		if hasProperty(item.Properties, "nav") {
+			norm := p.pathFromHref(item.Href)
+			if entry, ok := p.entryIndex[norm]; ok && !entry.removed {
+				return norm
+			}
+		}
+	}
+	return ""
+}
+
+// ncxPath 返回 NCX 在 ZIP 内的路径，不存在时返回空字符串
+func (p *Epub) ncxPath() string {
+	if p.opfDoc == nil {
+		return ""
+	}
+	for _, item := range p.opfDoc.Manifest.Items {
+		if item.ID == p.opfDoc.Spine.Toc || item.MediaType == "application/x-dtbncx+xml" {
+			norm := p.pathFromHref(item.Href)
+			if entry, ok := p.entryIndex[norm]; ok && !entry.removed {
+				return norm
+			}
+		}
+	}
+	return ""
+}
+
+func (p *Epub) parseHTMLEntry(norm string) (*html.Node, error) {
+	data, ok := p.ReadFile(norm)
+	if !ok {
+		return nil, fmt.Errorf("file does not exist: %s", norm)
+	}
+	doc, err := html.Parse(strings.NewReader(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", norm, err)
+	}
+	return doc, nil
+}
+
+func (p *Epub) parseXMLEntry(norm string) (*xmlNode, error) {
+	data, ok := p.ReadFile(norm)
+	if !ok {
+		return nil, fmt.Errorf("file does not exist: %s", norm)
+	}
+	tree, err := parseXMLTree(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", norm, err)
+	}
+	if tree.root() == nil {
+		return nil, fmt.Errorf("failed to parse %s: root element not found", norm)
+	}
+	return tree, nil
+}
+
+// writeEntry 替换已有文件的内容
+func (p *Epub) writeEntry(norm string, data []byte) error {
+	entry, ok := p.entryIndex[norm]
+	if !ok || entry.removed {
+		return fmt.Errorf("file does not exist: %s", norm)
+	}
+	entry.data = data
+	return nil
+}
+
+// mentionsFile 粗略判断 file 中是否出现 norm 的文件名（含 URL 编码形式），用于跳过与 norm 无关的文件
+func (p *Epub) mentionsFile(file, norm string) bool {
+	data, ok := p.ReadFile(file)
+	if !ok {
+		return false
+	}
+	base := path.Base(norm)
+	return bytes.Contains(data, []byte(base)) || bytes.Contains(data, []byte((&url.URL{Path: base}).EscapedPath()))
+}
+
+// findNavEntry 解析导航文档并查找指定类型的 nav，文档无法解析时返回 nil
+func (p *Epub) findNavEntry(navPath, navType string) *html.Node {
+	doc, err := p.parseHTMLEntry(navPath)
+	if err != nil {
+		return nil
+	}
+	return findNav(doc, navType)
+}
+
+// findNav 查找 epub:type 包含 navType 的 nav 元素
+func findNav(n *html.Node, navType string) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == atom.Nav && hasProperty(attrValue(n, "epub:type"), navType) {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findNav(c, navType); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// navLinks 返回 nav 下所有带 href 的链接
+func navLinks(nav *html.Node) []*html.Node {
+	var links []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A && attrValue(n, "href") != "" {
+			links = append(links, n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(nav)
+	return links
+}
+
+func newElement(a atom.Atom, attrs ...string) *html.Node {
+	n := &html.Node{Type: html.ElementNode, Data: a.String(), DataAtom: a}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		n.Attr = append(n.Attr, html.Attribute{Key: attrs[i], Val: attrs[i+1]})
+	}
+	return n
+}
+
+func attrValue(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		name := attr.Key
+		if attr.Namespace != "" {
+			name = attr.Namespace + ":" + attr.Key
+		}
+		if name == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// hasProperty 判断以空格分隔的属性列表中是否包含 prop
+func hasProperty(list, prop string) bool {
+	for _, p := range strings.Fields(list) {
+		if p == prop {
+			return true
+		}
+	}
+	return false
+}
+
+func fragmentOf(href string) string {
+	_, fragment := splitFragment(href)
+	return fragment
+}
+
+func ncxLabel(prefix, label string) *xmlNode {
+	text := &xmlNode{kind: xmlElementNode, name: prefix + "text"}
+	text.children = []*xmlNode{{kind: xmlTextNode, data: label}}
+	return &xmlNode{kind: xmlElementNode, name: prefix + "navLabel", children: []*xmlNode{text}}
+}
+
+// maxPlayOrder 返回 NCX navMap 中最大的 playOrder
+func maxPlayOrder(root *xmlNode) int {
+	maxOrder := 0
+	var walk func(*xmlNode)
+	walk = func(n *xmlNode) {
+		if n.kind == xmlElementNode && n.localName() == "navPoint" {
+			if v, ok := n.attr("playOrder"); ok {
+				if order, err := strconv.Atoi(v); err == nil && order > maxOrder {
+					maxOrder = order
+				}
+			}
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	if navMap := root.child("navMap"); navMap != nil {
+		walk(navMap)
+	}
+	return maxOrder
+}
+
+// pageType 根据页码标签推断 NCX pageTarget 的 type
+func pageType(label string) string {
+	if _, err := strconv.Atoi(label); err == nil {
+		return "normal"
+	}
+	if strings.Trim(strings.ToLower(label), "ivxlcdm") == "" {
+		return "front"
+	}
+	return "special"
+}
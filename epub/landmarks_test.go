@@ -0,0 +1,138 @@
+package epub
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// openNavigationBook 打开一本同时带有 EPUB3 导航文档与 NCX 的书，spine 中有 ch1、ch2
+func openNavigationBook(t *testing.T) *Epub {
+	t.Helper()
+	opf := strings.NewReplacer(
+		`<item id="ch1" href="Text/ch1.xhtml" media-type="application/xhtml+xml"/>`,
+		`<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+    <item id="ch1" href="Text/ch1.xhtml" media-type="application/xhtml+xml"/>
+    <item id="ch2" href="Text/ch2.xhtml" media-type="application/xhtml+xml"/>`,
+		`<spine>`, `<spine toc="ncx">`,
+		`<itemref idref="ch1"/>`, `<itemref idref="ch1"/><itemref idref="ch2"/>`,
+	).Replace(testOPF)
+	nav := `<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops"><head><title>Nav</title></head><body>
+<nav epub:type="toc"><ol><li><a href="Text/ch1.xhtml">One</a></li><li><a href="Text/ch2.xhtml">Two</a></li></ol></nav>
+</body></html>
+`
+	ncx := `<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1"><head/><docTitle><text>Test</text></docTitle>
+<navMap><navPoint id="n1" playOrder="1"><navLabel><text>One</text></navLabel><content src="Text/ch1.xhtml"/></navPoint>
+<navPoint id="n2" playOrder="2"><navLabel><text>Two</text></navLabel><content src="Text/ch2.xhtml"/></navPoint></navMap>
+</ncx>
+`
+	book, err := Open(writeTestEPUB(t, opf,
+		testFile{"OEBPS/nav.xhtml", nav},
+		testFile{"OEBPS/toc.ncx", ncx},
+		testFile{"OEBPS/Text/ch2.xhtml", testChapter},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return book
+}
+
+func TestLandmarks(t *testing.T) {
+	book := openNavigationBook(t)
+	landmarks := []Landmark{
+		{Type: "toc", Title: "Contents", Href: "OEBPS/nav.xhtml#toc"},
+		{Type: "bodymatter", Title: "Start", Href: "OEBPS/Text/ch1.xhtml"},
+		{Type: "endnotes", Title: "Notes", Href: "OEBPS/Text/ch2.xhtml#notes"},
+	}
+	if err := book.SetLandmarks(landmarks); err != nil {
+		t.Fatalf("SetLandmarks() error = %v", err)
+	}
+	got, err := book.Landmarks()
+	if err != nil || !slices.Equal(got, landmarks) {
+		t.Errorf("Landmarks() = %+v, %v, want %+v", got, err, landmarks)
+	}
+	if got := book.GuideReferences(); !slices.Equal(got, landmarks) {
+		t.Errorf("GuideReferences() = %+v, want %+v", got, landmarks)
+	}
+	var guideTypes []string
+	for _, ref := range book.opfDoc.Guide.References {
+		guideTypes = append(guideTypes, ref.Type+" "+ref.Href)
+	}
+	if want := []string{"toc nav.xhtml#toc", "text Text/ch1.xhtml", "notes Text/ch2.xhtml#notes"}; !slices.Equal(guideTypes, want) {
+		t.Errorf("guide = %q, want EPUB2 types %q", guideTypes, want)
+	}
+	nav, _ := book.ReadFile("OEBPS/nav.xhtml")
+	if !strings.Contains(string(nav), `<a epub:type="bodymatter" href="Text/ch1.xhtml">Start</a>`) {
+		t.Errorf("nav document:\n%s\nwant landmarks relative to the nav document", nav)
+	}
+
+	if err := book.RenameFile("OEBPS/Text/ch1.xhtml", "OEBPS/Text/start.xhtml"); err != nil {
+		t.Fatalf("RenameFile() error = %v", err)
+	}
+	if err := book.RemoveFileByName("OEBPS/Text/ch2.xhtml"); err != nil {
+		t.Fatalf("RemoveFileByName() error = %v", err)
+	}
+	want := []Landmark{landmarks[0], {Type: "bodymatter", Title: "Start", Href: "OEBPS/Text/start.xhtml"}}
+	if got, err := book.Landmarks(); err != nil || !slices.Equal(got, want) {
+		t.Errorf("Landmarks() after rename and remove = %+v, %v, want %+v", got, err, want)
+	}
+	if got := book.GuideReferences(); !slices.Equal(got, want) {
+		t.Errorf("GuideReferences() after rename and remove = %+v, want %+v", got, want)
+	}
+
+	if err := book.SetLandmarks(nil); err != nil {
+		t.Fatalf("SetLandmarks(nil) error = %v", err)
+	}
+	if nav, _ := book.ReadFile("OEBPS/nav.xhtml"); strings.Contains(string(nav), "landmarks") || book.opfDoc.Guide != nil {
+		t.Errorf("SetLandmarks(nil) kept the landmarks or guide:\n%s", nav)
+	}
+	if err := book.SetLandmarks([]Landmark{{Type: "toc"}}); err == nil {
+		t.Error("SetLandmarks() without href succeeded")
+	}
+}
+
+func TestPageList(t *testing.T) {
+	book := openNavigationBook(t)
+	pages := []PageTarget{
+		{Label: "iv", Href: "OEBPS/Text/ch1.xhtml#p-iv"},
+		{Label: "1", Href: "OEBPS/Text/ch1.xhtml#p1"},
+		{Label: "2", Href: "OEBPS/Text/ch2.xhtml#p2"},
+	}
+	if err := book.SetPageList(pages); err != nil {
+		t.Fatalf("SetPageList() error = %v", err)
+	}
+	if got, err := book.PageList(); err != nil || !slices.Equal(got, pages) {
+		t.Errorf("PageList() = %+v, %v, want %+v", got, err, pages)
+	}
+	ncx, _ := book.ReadFile("OEBPS/toc.ncx")
+	for _, want := range []string{
+		`<pageTarget id="page-1" type="front" playOrder="3">`,
+		`<pageTarget id="page-2" type="normal" value="1" playOrder="4">`,
+		`<content src="Text/ch2.xhtml#p2"/>`,
+	} {
+		if !strings.Contains(string(ncx), want) {
+			t.Errorf("NCX missing %q:\n%s", want, ncx)
+		}
+	}
+
+	// 没有 page-list 的导航文档时从 NCX 读取
+	if err := book.updateNavList(book.navPath(), "page-list", "", 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := book.PageList(); err != nil || !slices.Equal(got, pages) {
+		t.Errorf("PageList() from NCX = %+v, %v, want %+v", got, err, pages)
+	}
+
+	if err := book.RemoveFileByName("OEBPS/Text/ch2.xhtml"); err != nil {
+		t.Fatalf("RemoveFileByName() error = %v", err)
+	}
+	if got, err := book.PageList(); err != nil || !slices.Equal(got, pages[:2]) {
+		t.Errorf("PageList() after remove = %+v, %v, want %+v", got, err, pages[:2])
+	}
+	if err := book.SetPageList([]PageTarget{{Label: "1"}}); err == nil {
+		t.Error("SetPageList() without href succeeded")
+	}
+}
@@ -0,0 +1,236 @@
+package epub
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 书写方向
+const (
+	WritingHorizontal = "horizontal-tb"
+	WritingVerticalRL = "vertical-rl"
+)
+
+// layoutStylesheet 为 SetLayout 注入的竖排样式文件名，位于 OPF 所在目录的 Styles 下
+const layoutStylesheet = "epub-vertical.css"
+
+// verticalCSS 为竖排的默认样式：整页 vertical-rl，严格的标点换行与行末悬挂，
+// 数字与拉丁字母横向排列，注音（ruby）置于字的右侧
+const verticalCSS = `html {
+  -epub-writing-mode: vertical-rl;
+  -webkit-writing-mode: vertical-rl;
+  writing-mode: vertical-rl;
+}
+body {
+  -epub-line-break: strict;
+  -webkit-line-break: strict;
+  line-break: strict;
+  -webkit-hanging-punctuation: allow-end;
+  hanging-punctuation: allow-end;
+  -epub-text-orientation: mixed;
+  -webkit-text-orientation: mixed;
+  text-orientation: mixed;
+  text-align: justify;
+}
+.tcy, .text-combine {
+  -epub-text-combine: horizontal;
+  -webkit-text-combine: horizontal;
+  text-combine-upright: all;
+}
+.upright {
+  -epub-text-orientation: upright;
+  -webkit-text-orientation: upright;
+  text-orientation: upright;
+}
+ruby {
+  -epub-ruby-position: over;
+  -webkit-ruby-position: before;
+  ruby-position: over;
+}
+rt {
+  font-size: 50%;
+}
+img {
+  max-width: 100%;
+  max-height: 100%;
+}
+`
+
+// LayoutOptions 为 SetLayout 的排版设置
+type LayoutOptions struct {
+	// WritingMode 为 WritingVerticalRL 或 WritingHorizontal（默认）
+	WritingMode string `json:"writingMode"`
+	// PageProgressionDirection 为 "rtl" 或 "ltr"；为空时竖排使用 rtl，横排移除该属性
+	PageProgressionDirection string `json:"pageProgressionDirection,omitempty"`
+}
+
+var renditionLayoutRegex = regexp.MustCompile(`(?is)<meta\s[^>]*property\s*=\s*["']rendition:layout["']`)
+
+// namedMetaRegexes 缓存 namedMetaRegex 编译的正则，键为 meta 的 name
+var namedMetaRegexes sync.Map
+
+// Layout 返回书籍当前的书写方向与翻页方向；书写方向取自 primary-writing-mode 元数据
+func (p *Epub) Layout() LayoutOptions {
+	layout := LayoutOptions{WritingMode: WritingHorizontal}
+	if p.opfDoc == nil {
+		return layout
+	}
+	layout.PageProgressionDirection = p.opfDoc.Spine.PageProgressionDirection
+	if mode := p.namedMeta("primary-writing-mode"); strings.HasPrefix(mode, "vertical") {
+		layout.WritingMode = WritingVerticalRL
+	}
+	return layout
+}
+
+// SetLayout 切换书籍的排版方向。
+// 竖排：设置 spine 的 page-progression-direction，新增竖排样式并链接到所有 HTML 文件，
+// 写入 primary-writing-mode 元数据（EPUB3 同时声明 rendition:layout）；
+// 横排：只移除 SetLayout 注入的样式文件、对它的链接与 primary-writing-mode，书中原有的竖排样式保持不变
+func (p *Epub) SetLayout(opts LayoutOptions) error {
+	if p.opfDoc == nil {
+		return fmt.Errorf("content.opf not loaded")
+	}
+	mode := opts.WritingMode
+	if mode == "" {
+		mode = WritingHorizontal
+	}
+	if mode != WritingHorizontal && mode != WritingVerticalRL {
+		return fmt.Errorf("unsupported writing mode: %s", opts.WritingMode)
+	}
+	direction := strings.ToLower(opts.PageProgressionDirection)
+	if direction != "" && direction != "rtl" && direction != "ltr" {
+		return fmt.Errorf("unsupported page progression direction: %s", opts.PageProgressionDirection)
+	}
+	if direction == "" && mode == WritingVerticalRL {
+		direction = "rtl"
+	}
+	p.opfDoc.Spine.PageProgressionDirection = direction
+
+	cssPath := normalizeZipPath(path.Join(p.opfDir, "Styles", layoutStylesheet))
+	if mode == WritingVerticalRL {
+		if entry, ok := p.entryIndex[cssPath]; ok && !entry.removed {
+			entry.data = []byte(verticalCSS)
+		} else if _, err := p.addResource(cssPath, "text/css", "", []byte(verticalCSS)); err != nil {
+			return err
+		}
+		if err := p.linkStylesheet(cssPath, true); err != nil {
+			return err
+		}
+		p.setNamedMeta("primary-writing-mode", WritingVerticalRL)
+		if strings.HasPrefix(p.opfDoc.Version, "3") && !renditionLayoutRegex.Match(p.opfDoc.Metadata.InnerXML) {
+			p.appendMetadata(`<meta property="rendition:layout">reflowable</meta>`)
+		}
+		return nil
+	}
+
+	if entry, ok := p.entryIndex[cssPath]; ok && !entry.removed {
+		if err := p.linkStylesheet(cssPath, false); err != nil {
+			return err
+		}
+		if err := p.removeEntry(cssPath); err != nil {
+			return err
+		}
+	}
+	p.setNamedMeta("primary-writing-mode", "")
+	return nil
+}
+
+// linkStylesheet 在所有 HTML 文件的 head 末尾链接（add 为 true）或移除对 cssPath 的引用
+func (p *Epub) linkStylesheet(cssPath string, add bool) error {
+	for _, entry := range p.htmlEntriesInOrder() {
+		doc, err := html.Parse(strings.NewReader(string(entry.data)))
+		if err != nil {
+			return fmt.Errorf("failed to parse HTML (%s): %w", entry.header.Name, err)
+		}
+		head := findElement(doc, atom.Head)
+		if head == nil {
+			continue
+		}
+		var links []*html.Node
+		walkElements(head, func(n *html.Node) {
+			if n.DataAtom == atom.Link && resolveHref(entry.header.Name, attrValue(n, "href")) == cssPath {
+				links = append(links, n)
+			}
+		})
+		if add == (len(links) > 0) {
+			continue
+		}
+		if add {
+			head.AppendChild(newElement(atom.Link, "rel", "stylesheet", "type", "text/css",
+				"href", relativeRef(entry.header.Name, cssPath)))
+		} else {
+			for _, link := range links {
+				link.Parent.RemoveChild(link)
+			}
+		}
+		rendered, err := renderXHTMLDocument(doc, p.defaultDoctype())
+		if err != nil {
+			return fmt.Errorf("failed to render HTML (%s): %w", entry.header.Name, err)
+		}
+		entry.data = []byte(rendered)
+	}
+	return nil
+}
+
+// namedMetaRegex 匹配 <meta name="name" ...>
+func namedMetaRegex(name string) *regexp.Regexp {
+	if re, ok := namedMetaRegexes.Load(name); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(`(?is)<meta\s[^>]*name\s*=\s*["']` + regexp.QuoteMeta(name) + `["'][^>]*>`)
+	actual, _ := namedMetaRegexes.LoadOrStore(name, re)
+	return actual.(*regexp.Regexp)
+}
+
+// namedMeta 返回 OPF 中 <meta name="name" content="..."> 的 content
+func (p *Epub) namedMeta(name string) string {
+	meta := namedMetaRegex(name).Find(p.opfDoc.Metadata.InnerXML)
+	if m := metaContentRegex.FindSubmatch(meta); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
+
+// setNamedMeta 设置 OPF 中的 <meta name="name" content="...">，content 为空时删除
+func (p *Epub) setNamedMeta(name, content string) {
+	inner := string(p.opfDoc.Metadata.InnerXML)
+	loc := namedMetaRegex(name).FindStringIndex(inner)
+	switch {
+	case loc == nil && content == "":
+		return
+	case loc == nil:
+		p.appendMetadata(`<meta name="` + html.EscapeString(name) + `" content="` + html.EscapeString(content) + `"/>`)
+		return
+	case content == "":
+		// 连同前面的缩进一起删除
+		start := len(strings.TrimRight(inner[:loc[0]], " \t\r\n"))
+		inner = inner[:start] + inner[loc[1]:]
+	default:
+		meta := metaContentRegex.ReplaceAllString(inner[loc[0]:loc[1]], ` content="`+html.EscapeString(content)+`"`)
+		inner = inner[:loc[0]] + meta + inner[loc[1]:]
+	}
+	p.opfDoc.Metadata.InnerXML = []byte(inner)
+}
+
+// appendMetadata 在 OPF metadata 末尾追加一个元素，复用已有子元素的缩进
+func (p *Epub) appendMetadata(element string) {
+	inner := string(p.opfDoc.Metadata.InnerXML)
+	indent := "\n    "
+	if i := strings.IndexByte(inner, '\n'); i >= 0 {
+		j := i + 1
+		for j < len(inner) && (inner[j] == ' ' || inner[j] == '\t') {
+			j++
+		}
+		if j < len(inner) && inner[j] == '<' {
+			indent = inner[i:j]
+		}
+	}
+	trimmed := strings.TrimRight(inner, " \t\r\n")
+	p.opfDoc.Metadata.InnerXML = []byte(trimmed + indent + element + inner[len(trimmed):])
+}
@@ -0,0 +1,96 @@
+package epub
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetLayout(t *testing.T) {
+	book := openChapters(t, 2)
+	const cssPath = "OEBPS/Styles/" + layoutStylesheet
+	for i := 0; i < 2; i++ {
+		if err := book.SetLayout(LayoutOptions{WritingMode: WritingVerticalRL}); err != nil {
+			t.Fatalf("SetLayout(vertical) error = %v", err)
+		}
+	}
+	if got := book.Layout(); got != (LayoutOptions{WritingMode: WritingVerticalRL, PageProgressionDirection: "rtl"}) {
+		t.Errorf("Layout() = %+v, want vertical-rl and rtl", got)
+	}
+	if data, ok := book.ReadFile(cssPath); !ok || !strings.Contains(string(data), "writing-mode: vertical-rl") {
+		t.Errorf("vertical stylesheet = %q, %v", data, ok)
+	}
+	for _, norm := range book.SpinePaths() {
+		data, _ := book.ReadFile(norm)
+		if strings.Count(string(data), `href="../Styles/epub-vertical.css"`) != 1 {
+			t.Errorf("%s does not link the stylesheet once:\n%s", norm, data)
+		}
+	}
+	meta := string(book.opfDoc.Metadata.InnerXML)
+	if strings.Count(meta, `<meta name="primary-writing-mode" content="vertical-rl"/>`) != 1 ||
+		strings.Count(meta, `<meta property="rendition:layout">reflowable</meta>`) != 1 {
+		t.Errorf("metadata = %s, want primary-writing-mode and rendition:layout once", meta)
+	}
+
+	// 保存后重新打开，排版设置保持不变
+	output := filepath.Join(t.TempDir(), "vertical.epub")
+	if err := book.Save(output); err != nil {
+		t.Fatal(err)
+	}
+	book, err := Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := book.Layout(); got.WritingMode != WritingVerticalRL || got.PageProgressionDirection != "rtl" {
+		t.Errorf("Layout() after reopening = %+v", got)
+	}
+
+	if err := book.SetLayout(LayoutOptions{}); err != nil {
+		t.Fatalf("SetLayout(horizontal) error = %v", err)
+	}
+	if got := book.Layout(); got != (LayoutOptions{WritingMode: WritingHorizontal}) {
+		t.Errorf("Layout() = %+v, want horizontal without a page progression direction", got)
+	}
+	if _, ok := book.ReadFile(cssPath); ok {
+		t.Error("vertical stylesheet was not removed")
+	}
+	for _, norm := range book.SpinePaths() {
+		if data, _ := book.ReadFile(norm); strings.Contains(string(data), layoutStylesheet) {
+			t.Errorf("%s still links the stylesheet:\n%s", norm, data)
+		}
+	}
+	if meta := string(book.opfDoc.Metadata.InnerXML); strings.Contains(meta, "primary-writing-mode") {
+		t.Errorf("metadata = %s, want primary-writing-mode removed", meta)
+	}
+	for _, item := range book.opfDoc.Manifest.Items {
+		if strings.Contains(item.Href, layoutStylesheet) {
+			t.Errorf("manifest still lists %s", item.Href)
+		}
+	}
+}
+
+func TestSetLayoutOptions(t *testing.T) {
+	epub2 := strings.Replace(testOPF, `version="3.0"`, `version="2.0"`, 1)
+	book, err := Open(writeTestEPUB(t, epub2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := book.SetLayout(LayoutOptions{WritingMode: WritingVerticalRL, PageProgressionDirection: "LTR"}); err != nil {
+		t.Fatalf("SetLayout() error = %v", err)
+	}
+	if got := book.Layout(); got.PageProgressionDirection != "ltr" {
+		t.Errorf("Layout() = %+v, want the explicit ltr", got)
+	}
+	if meta := string(book.opfDoc.Metadata.InnerXML); strings.Contains(meta, "rendition:layout") {
+		t.Errorf("EPUB2 metadata = %s, want no rendition:layout", meta)
+	}
+	if err := book.SetLayout(LayoutOptions{PageProgressionDirection: "rtl"}); err != nil || book.Layout().PageProgressionDirection != "rtl" {
+		t.Errorf("SetLayout(horizontal, rtl) = %v, layout %+v", err, book.Layout())
+	}
+
+	for _, opts := range []LayoutOptions{{WritingMode: "vertical-lr"}, {PageProgressionDirection: "up"}} {
+		if err := book.SetLayout(opts); err == nil {
+			t.Errorf("SetLayout(%+v) succeeded, want error", opts)
+		}
+	}
+}
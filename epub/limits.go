@@ -0,0 +1,222 @@
+package epub
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// 打开 EPUB 时的默认安全限制，OpenOptions 中对应字段为 0 时使用
+const (
+	DefaultMaxTotalSize        int64   = 1 << 30   // 解压后总大小 1 GiB
+	DefaultMaxEntrySize        int64   = 256 << 20 // 单个文件 256 MiB
+	DefaultMaxEntries                  = 20000
+	DefaultMaxCompressionRatio float64 = 100
+
+	// 小于该大小的文件不检查压缩比，避免误伤高度重复的小文本
+	compressionRatioMinSize = 1 << 20
+)
+
+// LimitKind 表示触发的安全限制类型
+type LimitKind string
+
+const (
+	LimitTotalSize        LimitKind = "total size"
+	LimitEntrySize        LimitKind = "entry size"
+	LimitEntryCount       LimitKind = "entry count"
+	LimitCompressionRatio LimitKind = "compression ratio"
+)
+
+var (
+	// ErrLimitExceeded 表示 EPUB 超出了 OpenOptions 的安全限制
+	ErrLimitExceeded = errors.New("epub exceeds safety limit")
+	// ErrUnsafePath 表示 zip 条目路径不安全（绝对路径、.. 等）
+	ErrUnsafePath = errors.New("unsafe zip entry path")
+	// ErrDuplicateEntry 表示 zip 中存在重名条目
+	ErrDuplicateEntry = errors.New("duplicate zip entry")
+)
+
+// LimitError 描述超出的安全限制，Name 为触发限制的条目（条目数限制时为空）
+type LimitError struct {
+	Kind  LimitKind
+	Name  string
+	Limit float64
+	Value float64
+}
+
+func (e *LimitError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("epub %s exceeds limit: %.6g > %.6g", e.Kind, e.Value, e.Limit)
+	}
+	return fmt.Sprintf("epub %s exceeds limit (%s): %.6g > %.6g", e.Kind, e.Name, e.Value, e.Limit)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// UnsafePathError 描述不安全的条目路径
+type UnsafePathError struct {
+	Name   string
+	Reason string
+}
+
+func (e *UnsafePathError) Error() string {
+	return fmt.Sprintf("unsafe zip entry path (%s): %s", e.Name, e.Reason)
+}
+
+func (e *UnsafePathError) Unwrap() error {
+	return ErrUnsafePath
+}
+
+// DuplicateEntryError 描述重名的条目，Name 为规范化后的路径
+type DuplicateEntryError struct {
+	Name string
+}
+
+func (e *DuplicateEntryError) Error() string {
+	return fmt.Sprintf("duplicate zip entry: %s", e.Name)
+}
+
+func (e *DuplicateEntryError) Unwrap() error {
+	return ErrDuplicateEntry
+}
+
+// openLimits 为 OpenOptions 填充默认值后的限制，负数表示不限制
+type openLimits struct {
+	totalSize int64
+	entrySize int64
+	entries   int
+	ratio     float64
+
+	read int64
+}
+
+func newOpenLimits(opts OpenOptions) *openLimits {
+	l := &openLimits{
+		totalSize: opts.MaxTotalSize,
+		entrySize: opts.MaxEntrySize,
+		entries:   opts.MaxEntries,
+		ratio:     opts.MaxCompressionRatio,
+	}
+	if l.totalSize == 0 {
+		l.totalSize = DefaultMaxTotalSize
+	}
+	if l.entrySize == 0 {
+		l.entrySize = DefaultMaxEntrySize
+	}
+	if l.entries == 0 {
+		l.entries = DefaultMaxEntries
+	}
+	if l.ratio == 0 {
+		l.ratio = DefaultMaxCompressionRatio
+	}
+	return l
+}
+
+// checkEntries 在读取任何内容之前检查条目数、路径与重名
+func (l *openLimits) checkEntries(files []*zip.File) error {
+	if l.entries > 0 && len(files) > l.entries {
+		return &LimitError{Kind: LimitEntryCount, Limit: float64(l.entries), Value: float64(len(files))}
+	}
+	seen := make(map[string]bool, len(files))
+	for _, f := range files {
+		if err := checkEntryPath(f.Name); err != nil {
+			return err
+		}
+		name := normalizeZipPath(f.Name)
+		if seen[name] {
+			return &DuplicateEntryError{Name: name}
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// checkEntryPath 拒绝绝对路径、盘符、.. 路径段与控制字符
+func checkEntryPath(name string) error {
+	slashed := strings.ReplaceAll(name, "\\", "/")
+	switch {
+	case name == "":
+		return &UnsafePathError{Name: name, Reason: "empty name"}
+	case strings.ContainsRune(name, 0):
+		return &UnsafePathError{Name: name, Reason: "contains NUL"}
+	case strings.HasPrefix(slashed, "/"):
+		return &UnsafePathError{Name: name, Reason: "absolute path"}
+	case len(slashed) >= 2 && slashed[1] == ':':
+		return &UnsafePathError{Name: name, Reason: "volume name"}
+	}
+	for _, segment := range strings.Split(slashed, "/") {
+		if segment == ".." {
+			return &UnsafePathError{Name: name, Reason: "path traversal"}
+		}
+	}
+	return nil
+}
+
+// readEntry 读取条目内容：先用 zip 头中的大小拒绝明显超限的条目，
+// 再按大小与压缩比上限截断读取，因为头中的大小不可信
+func (l *openLimits) readEntry(f *zip.File) ([]byte, error) {
+	if l.entrySize > 0 && f.UncompressedSize64 > uint64(l.entrySize) {
+		return nil, &LimitError{Kind: LimitEntrySize, Name: f.Name, Limit: float64(l.entrySize), Value: float64(f.UncompressedSize64)}
+	}
+	compressed := f.CompressedSize64
+	if compressed == 0 {
+		compressed = 1
+	}
+	if l.ratio > 0 && f.UncompressedSize64 >= compressionRatioMinSize {
+		if ratio := float64(f.UncompressedSize64) / float64(compressed); ratio > l.ratio {
+			return nil, &LimitError{Kind: LimitCompressionRatio, Name: f.Name, Limit: l.ratio, Value: ratio}
+		}
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip entry (%s): %w", f.Name, err)
+	}
+	defer func() { _ = rc.Close() }()
+
+	max := int64(-1)
+	if l.entrySize > 0 {
+		max = l.entrySize
+	}
+	if l.totalSize > 0 && (max < 0 || l.totalSize-l.read < max) {
+		max = l.totalSize - l.read
+	}
+	// 超过压缩比上限所允许的大小后无需继续解压
+	if l.ratio > 0 {
+		ratioMax := int64(math.Min(l.ratio*float64(compressed), math.MaxInt64/2))
+		if ratioMax < compressionRatioMinSize {
+			ratioMax = compressionRatioMinSize
+		}
+		if max < 0 || ratioMax < max {
+			max = ratioMax
+		}
+	}
+	var reader io.Reader = rc
+	if max >= 0 {
+		reader = io.LimitReader(rc, max+1)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file content (%s): %w", f.Name, err)
+	}
+	size := int64(len(data))
+	if l.entrySize > 0 && size > l.entrySize {
+		return nil, &LimitError{Kind: LimitEntrySize, Name: f.Name, Limit: float64(l.entrySize), Value: float64(size)}
+	}
+	l.read += size
+	if l.totalSize > 0 && l.read > l.totalSize {
+		return nil, &LimitError{Kind: LimitTotalSize, Name: f.Name, Limit: float64(l.totalSize), Value: float64(l.read)}
+	}
+	if l.ratio > 0 && size >= compressionRatioMinSize {
+		if ratio := float64(size) / float64(compressed); ratio > l.ratio {
+			return nil, &LimitError{Kind: LimitCompressionRatio, Name: f.Name, Limit: l.ratio, Value: ratio}
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,53 @@
+package epub
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestOpenLimits(t *testing.T) {
+	bomb := testFile{"OEBPS/bomb.txt", strings.Repeat("0", 2<<20)}
+	text := testFile{"OEBPS/notes.txt", strings.Repeat("note ", 400)}
+	tests := []struct {
+		name      string
+		opts      OpenOptions
+		extra     []testFile
+		wantKind  LimitKind // 期望的 LimitError 类型
+		wantError error     // 期望 errors.Is 匹配的错误
+	}{
+		{name: "defaults", extra: []testFile{text}},
+		{name: "compression ratio", extra: []testFile{bomb}, wantKind: LimitCompressionRatio},
+		{name: "ratio disabled", opts: OpenOptions{MaxCompressionRatio: -1}, extra: []testFile{bomb}},
+		{name: "ratio raised", opts: OpenOptions{MaxCompressionRatio: 1e6}, extra: []testFile{bomb}},
+		{name: "entry size", opts: OpenOptions{MaxEntrySize: 1024}, extra: []testFile{text}, wantKind: LimitEntrySize},
+		{name: "total size", opts: OpenOptions{MaxTotalSize: 2048}, extra: []testFile{text}, wantKind: LimitTotalSize},
+		{name: "entry count", opts: OpenOptions{MaxEntries: 3}, wantKind: LimitEntryCount},
+		{name: "path traversal", extra: []testFile{{"../evil.txt", "x"}}, wantError: ErrUnsafePath},
+		{name: "absolute path", extra: []testFile{{"/evil.txt", "x"}}, wantError: ErrUnsafePath},
+		{name: "volume name", extra: []testFile{{"C:/evil.txt", "x"}}, wantError: ErrUnsafePath},
+		{name: "duplicate entry", extra: []testFile{{"OEBPS/Text/ch1.xhtml", testChapter}}, wantError: ErrDuplicateEntry},
+		{name: "duplicate after normalization", extra: []testFile{{"OEBPS\\Text\\ch1.xhtml", testChapter}}, wantError: ErrDuplicateEntry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := OpenWithOptions(writeTestEPUB(t, "", tt.extra...), tt.opts)
+			switch {
+			case tt.wantKind != "":
+				var limitErr *LimitError
+				if !errors.As(err, &limitErr) || limitErr.Kind != tt.wantKind {
+					t.Fatalf("OpenWithOptions() error = %v, want %s limit", err, tt.wantKind)
+				}
+				if !errors.Is(err, ErrLimitExceeded) {
+					t.Errorf("error %v does not match ErrLimitExceeded", err)
+				}
+			case tt.wantError != nil:
+				if !errors.Is(err, tt.wantError) {
+					t.Fatalf("OpenWithOptions() error = %v, want %v", err, tt.wantError)
+				}
+			case err != nil:
+				t.Fatalf("OpenWithOptions() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,382 @@
+package epub
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"gopkg.in/yaml.v3"
+)
+
+// MarkdownImportOptions 控制 Markdown 导入；书名、作者、语言与封面优先取此处，
+// 其次取各文件 front matter 中第一个非空的值
+type MarkdownImportOptions struct {
+	Title    string
+	Author   string
+	Language string // 默认 zh-CN
+	Cover    string // 封面图片路径
+	// TOCTitle 为导航文档的标题，默认 "目录"
+	TOCTitle string
+}
+
+// markdownFrontMatter 为 Markdown 文件开头 --- 之间的 YAML 元数据
+type markdownFrontMatter struct {
+	Title    string `yaml:"title"`
+	Author   string `yaml:"author"`
+	Language string `yaml:"language"`
+	Lang     string `yaml:"lang"`
+	Cover    string `yaml:"cover"`
+}
+
+type markdownChapter struct {
+	source string // 源文件的绝对路径
+	norm   string // 章节在 EPUB 中的路径
+	meta   markdownFrontMatter
+	body   []byte
+
+	doc        *html.Node        // 渲染并解析后的文档
+	headingIDs map[string]string // goldmark 生成的标题 id -> heading-N
+}
+
+// ImportMarkdownDir 按路径字典序导入目录（含子目录）下的 .md/.markdown 文件，
+// 以 . 开头的文件与目录会被跳过；需要固定顺序时可使用 01-xxx.md 这样的文件名
+func ImportMarkdownDir(dir string, opts MarkdownImportOptions) (*Epub, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && isMarkdownFile(p) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read markdown directory: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no markdown files found in %s", dir)
+	}
+	sort.Strings(files)
+	return ImportMarkdown(files, opts)
+}
+
+// ImportMarkdown 将有序的 Markdown 文件转换为 EPUB：每个文件一个章节，按 CommonMark 渲染为 XHTML，
+// 引用的本地图片加入书中（找不到的跳过），指向其它已导入 .md 文件的链接改写为对应章节，并根据 h1-h3 生成目录
+func ImportMarkdown(files []string, opts MarkdownImportOptions) (*Epub, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no markdown files to import")
+	}
+
+	chapters := make([]*markdownChapter, 0, len(files))
+	bySource := make(map[string]*markdownChapter)
+	title, author, language := opts.Title, opts.Author, opts.Language
+	cover := opts.Cover
+	if cover != "" {
+		abs, err := filepath.Abs(cover)
+		if err != nil {
+			return nil, err
+		}
+		cover = abs
+	}
+	for i, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(abs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read markdown file: %w", err)
+		}
+		meta, body, err := splitFrontMatter(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid front matter (%s): %w", file, err)
+		}
+		if meta.Language == "" {
+			meta.Language = meta.Lang
+		}
+		if title == "" && meta.Title != "" {
+			// 作为书名的 title 不再作为该章节的标题
+			title, meta.Title = meta.Title, ""
+		}
+		if author == "" {
+			author = meta.Author
+		}
+		if language == "" {
+			language = meta.Language
+		}
+		if cover == "" && meta.Cover != "" {
+			cover = resolveLocalPath(filepath.Dir(abs), meta.Cover)
+		}
+		ch := &markdownChapter{source: abs, norm: chapterPath(i + 1), meta: meta, body: body}
+		chapters = append(chapters, ch)
+		bySource[abs] = ch
+	}
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(files[0]), filepath.Ext(files[0]))
+	}
+
+	book, err := New(BookMetadata{Title: title, Author: author, Language: language})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := book.addResource(newBookDir+"/Styles/style.css", "text/css", "", []byte(defaultImportCSS)); err != nil {
+		return nil, err
+	}
+
+	md := goldmark.New(
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		goldmark.WithRendererOptions(gmhtml.WithXHTML(), gmhtml.WithUnsafe()),
+	)
+	images := make(map[string]string) // 源文件绝对路径 -> EPUB 内路径
+
+	if cover != "" {
+		norm, err := book.importImage(cover, images)
+		if err != nil {
+			return nil, err
+		}
+		if err := book.setCoverImage(norm); err != nil {
+			return nil, err
+		}
+		coverPage := path.Join(newBookDir, "Text", "cover.xhtml")
+		body := fmt.Sprintf(`<div class="cover"><img src="%s" alt="%s"/></div>`,
+			html.EscapeString(relativeRef(coverPage, norm)), html.EscapeString(title))
+		if err := book.AddChapterWithOptions(coverPage, chapterDocument(title, body), ChapterOptions{SpineIndex: -1, Title: title}); err != nil {
+			return nil, err
+		}
+	}
+
+	// 先渲染所有章节并统一标题 id，跨文件链接可能指向后面的章节
+	for _, ch := range chapters {
+		var rendered bytes.Buffer
+		if err := md.Convert(ch.body, &rendered); err != nil {
+			return nil, fmt.Errorf("failed to render markdown (%s): %w", ch.source, err)
+		}
+		doc, err := html.Parse(strings.NewReader(chapterDocument("", rendered.String())))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse rendered markdown (%s): %w", ch.source, err)
+		}
+		ch.doc = doc
+		ch.headingIDs = assignHeadingIDs(doc)
+	}
+
+	var flat []*TOCEntry
+	for _, ch := range chapters {
+		doc := ch.doc
+		dir := filepath.Dir(ch.source)
+		var headings []*TOCEntry
+		var walkErr error
+		walkElements(doc, func(n *html.Node) {
+			if walkErr != nil {
+				return
+			}
+			switch n.DataAtom {
+			case atom.Img:
+				src := attrValue(n, "src")
+				if src == "" || isExternalRef(src) || strings.HasPrefix(src, "data:") {
+					return
+				}
+				// 与 AddChapterFromFile 一致，找不到的本地图片跳过并保留原引用
+				norm, err := book.importImage(resolveLocalPath(dir, src), images)
+				if errors.Is(err, fs.ErrNotExist) {
+					return
+				}
+				if err != nil {
+					walkErr = fmt.Errorf("image %s: %w", src, err)
+					return
+				}
+				setAttr(n, "src", relativeRef(ch.norm, norm))
+			case atom.A:
+				href := attrValue(n, "href")
+				if href == "" || isExternalRef(href) {
+					return
+				}
+				if strings.HasPrefix(href, "#") {
+					if id, ok := ch.headingIDs[href[1:]]; ok {
+						setAttr(n, "href", "#"+id)
+					}
+					return
+				}
+				file, fragment := splitFragment(href)
+				target, ok := bySource[resolveLocalPath(dir, file)]
+				if !ok {
+					return
+				}
+				if id, ok := target.headingIDs[strings.TrimPrefix(fragment, "#")]; ok {
+					fragment = "#" + id
+				}
+				setAttr(n, "href", relativeRef(ch.norm, target.norm)+fragment)
+			case atom.H1, atom.H2, atom.H3:
+				text := strings.Join(strings.Fields(nodeText(n)), " ")
+				if text == "" {
+					return
+				}
+				level, _ := strconv.Atoi(n.Data[1:])
+				headings = append(headings, &TOCEntry{Title: text, Href: ch.norm + "#" + attrValue(n, "id"), Level: level})
+			}
+		})
+		if walkErr != nil {
+			return nil, fmt.Errorf("failed to import %s: %w", ch.source, walkErr)
+		}
+
+		name := ch.meta.Title
+		if name == "" && len(headings) > 0 {
+			name = headings[0].Title
+		}
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(ch.source), filepath.Ext(ch.source))
+		}
+		if titleNode := findElement(doc, atom.Title); titleNode != nil {
+			titleNode.AppendChild(&html.Node{Type: html.TextNode, Data: name})
+		}
+		if len(headings) == 0 || ch.meta.Title != "" && headings[0].Title != ch.meta.Title {
+			// front matter 的标题或没有标题的章节作为一级目录，章节内的标题挂在其下
+			entry := &TOCEntry{Title: name, Href: ch.norm, Level: 1}
+			for _, h := range headings {
+				h.Level++
+			}
+			headings = append([]*TOCEntry{entry}, headings...)
+		}
+		flat = append(flat, headings...)
+
+		content, err := renderXHTMLDocument(doc, book.defaultDoctype())
+		if err != nil {
+			return nil, fmt.Errorf("failed to render HTML (%s): %w", ch.source, err)
+		}
+		if err := book.AddChapterWithOptions(ch.norm, content, ChapterOptions{SpineIndex: -1, Title: name, Raw: true}); err != nil {
+			return nil, err
+		}
+	}
+
+	tocTitle := opts.TOCTitle
+	if tocTitle == "" {
+		tocTitle = "目录"
+	}
+	if err := book.setTOC(nestTOC(flat), tocTitle); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// splitFrontMatter 拆分文件开头 --- 与 --- 之间的 YAML front matter
+func splitFrontMatter(data []byte) (markdownFrontMatter, []byte, error) {
+	var meta markdownFrontMatter
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if !strings.HasPrefix(text, "---\n") {
+		return meta, []byte(text), nil
+	}
+	rest := text[len("---\n"):]
+	end := strings.Index(rest, "\n---")
+	if strings.HasPrefix(rest, "---") {
+		end = -1
+	}
+	if end < 0 {
+		return meta, []byte(text), nil
+	}
+	after := rest[end+len("\n---"):]
+	if i := strings.IndexByte(after, '\n'); i >= 0 {
+		if strings.TrimSpace(after[:i]) != "" {
+			return meta, []byte(text), nil
+		}
+		after = after[i+1:]
+	} else if strings.TrimSpace(after) != "" {
+		return meta, []byte(text), nil
+	} else {
+		after = ""
+	}
+	if err := yaml.Unmarshal([]byte(rest[:end]), &meta); err != nil {
+		return meta, nil, err
+	}
+	return meta, []byte(after), nil
+}
+
+// importImage 将本地图片加入 OEBPS/Images，同一源文件只导入一次，返回 EPUB 内路径
+func (p *Epub) importImage(source string, imported map[string]string) (string, error) {
+	if norm, ok := imported[source]; ok {
+		return norm, nil
+	}
+	if !isImageFile(source, "") {
+		return "", fmt.Errorf("unsupported image type: %s", source)
+	}
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image: %w", err)
+	}
+	norm := p.uniquePath(path.Join(newBookDir, "Images", filepath.Base(source)))
+	if err := p.addImageFile(norm, data); err != nil {
+		return "", err
+	}
+	imported[source] = norm
+	return norm, nil
+}
+
+// resolveLocalPath 将 Markdown 中的相对引用（可能经过 URL 编码）解析为本地绝对路径
+func resolveLocalPath(dir, ref string) string {
+	if unescaped, err := url.PathUnescape(ref); err == nil {
+		ref = unescaped
+	}
+	if filepath.IsAbs(ref) {
+		return filepath.Clean(ref)
+	}
+	return filepath.Join(dir, filepath.FromSlash(ref))
+}
+
+// assignHeadingIDs 为所有标题设置 heading-N 形式的 id，返回原 id 到新 id 的映射；
+// goldmark 只用 ASCII 字母数字生成 id，中文标题会得到 "heading"、"-1" 这样无法区分的 id
+func assignHeadingIDs(doc *html.Node) map[string]string {
+	var headings []*html.Node
+	walkElements(doc, func(n *html.Node) {
+		switch n.DataAtom {
+		case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+			headings = append(headings, n)
+		}
+	})
+	// 原标题 id 会被替换，不参与冲突检查
+	ids := make(map[string]*html.Node)
+	walkElements(doc, func(n *html.Node) {
+		if id := attrValue(n, "id"); id != "" && !slices.Contains(headings, n) {
+			ids[id] = n
+		}
+	})
+	mapping := make(map[string]string)
+	for i, n := range headings {
+		id := uniqueID(ids, "heading-"+strconv.Itoa(i+1))
+		ids[id] = n
+		if old := attrValue(n, "id"); old != "" {
+			if _, ok := mapping[old]; !ok {
+				mapping[old] = id
+			}
+		}
+		setAttr(n, "id", id)
+	}
+	return mapping
+}
+
+func isMarkdownFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package epub
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeMarkdownFiles 在临时目录中写入文件并返回目录
+func writeMarkdownFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestImportMarkdown(t *testing.T) {
+	dir := writeMarkdownFiles(t, map[string]string{
+		"01-intro.md": "---\ntitle: The Book\nauthor: Ann\nlanguage: en\n---\n" +
+			"# Intro\n\nSee [details](02-next.md#details), [below](#sub) and [site](https://example.com/).\n\n" +
+			"![pic](img/pic.png) ![again](img/pic.png) ![gone](missing.png)\n\n## Sub\n",
+		"02-next.md":  "---\ntitle: Part Two\n---\n# Next\n\n## Details\n\nBack to [intro](01-intro.md).\n",
+		"img/pic.png": "\x89PNG\r\n\x1a\nfake",
+		".hidden.md":  "# Hidden\n",
+	})
+	book, err := ImportMarkdownDir(dir, MarkdownImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportMarkdownDir() error = %v", err)
+	}
+
+	info := book.Info()
+	if info.Title != "The Book" || len(info.Creators) != 1 || info.Creators[0] != "Ann" || info.Language != "en" {
+		t.Errorf("Info() = %+v, want the front matter of the first file", info)
+	}
+
+	toc, err := book.TOC()
+	if err != nil {
+		t.Fatalf("TOC() error = %v", err)
+	}
+	if got, want := formatTOC(toc), "Intro(1)[Sub(2)] Part Two(1)[Next(2)[Details(3)]]"; got != want {
+		t.Errorf("TOC() = %s, want %s", got, want)
+	}
+	if details := toc[1].Children[0].Children[0]; !strings.HasSuffix(details.Href, "chapter-0002.xhtml#heading-2") {
+		t.Errorf("TOC href = %s, want the Details heading id", details.Href)
+	}
+
+	if data, ok := book.ReadFile("OEBPS/Images/pic.png"); !ok || string(data) != "\x89PNG\r\n\x1a\nfake" {
+		t.Errorf("ReadFile(pic.png) = %q, %v, want the imported image", data, ok)
+	}
+	spine := book.SpinePaths()
+	if len(spine) != 2 {
+		t.Fatalf("SpinePaths() = %q, want 2 chapters", spine)
+	}
+	chapters := make([]string, len(spine))
+	for i, norm := range spine {
+		data, _ := book.ReadFile(norm)
+		chapters[i] = string(data)
+	}
+	checks := []struct {
+		chapter int
+		want    string
+	}{
+		{0, `<h1 id="heading-1">Intro</h1>`},
+		{0, `href="chapter-0002.xhtml#heading-2"`},
+		{0, `href="#heading-2"`},
+		{0, `href="https://example.com/"`},
+		{0, `src="../Images/pic.png" alt="pic"`},
+		{0, `src="../Images/pic.png" alt="again"`},
+		{0, `src="missing.png"`},
+		{0, `<title>Intro</title>`},
+		{1, `<h2 id="heading-2">Details</h2>`},
+		{1, `href="chapter-0001.xhtml"`},
+		{1, `<title>Part Two</title>`},
+	}
+	for _, c := range checks {
+		if !strings.Contains(chapters[c.chapter], c.want) {
+			t.Errorf("chapter %d missing %q:\n%s", c.chapter, c.want, chapters[c.chapter])
+		}
+	}
+	if strings.Contains(chapters[0], "title: The Book") || strings.Contains(strings.Join(chapters, ""), "Hidden") {
+		t.Error("front matter or hidden files were imported as content")
+	}
+}
+
+func TestImportMarkdownImageErrors(t *testing.T) {
+	dir := writeMarkdownFiles(t, map[string]string{
+		"book.md":   "# Book\n\n![notes](notes.txt)\n",
+		"notes.txt": "not an image",
+	})
+	_, err := ImportMarkdown([]string{filepath.Join(dir, "book.md")}, MarkdownImportOptions{})
+	if err == nil || !strings.Contains(err.Error(), "book.md") || !strings.Contains(err.Error(), "notes.txt") {
+		t.Errorf("ImportMarkdown() error = %v, want the chapter and image named", err)
+	}
+}
@@ -0,0 +1,151 @@
+package epub
+
+import (
+	"archive/zip"
+	"crypto/rand"
+	"fmt"
+	"html"
+	"path"
+	"strings"
+	"time"
+)
+
+// BookMetadata 为新建 EPUB 时写入 OPF 的基础元数据
+type BookMetadata struct {
+	Title      string
+	Author     string
+	Language   string // 默认 zh-CN
+	Identifier string // 默认生成 urn:uuid
+}
+
+// newBookDir 为新建 EPUB 的内容目录
+const newBookDir = "OEBPS"
+
+// 导入的章节使用的默认样式
+const defaultImportCSS = `body { margin: 0 5%; line-height: 1.6; }
+h1, h2, h3 { text-align: center; margin: 1.5em 0 1em; }
+p { text-indent: 2em; margin: 0.3em 0; }
+img { max-width: 100%; }
+`
+
+// New 创建只包含 mimetype、container.xml 与 OPF 的空白 EPUB3，章节可通过 AddChapterWithOptions 添加
+func New(meta BookMetadata) (*Epub, error) {
+	if meta.Title == "" {
+		return nil, fmt.Errorf("book title cannot be empty")
+	}
+	if meta.Language == "" {
+		meta.Language = "zh-CN"
+	}
+	if meta.Identifier == "" {
+		id, err := newUUID()
+		if err != nil {
+			return nil, err
+		}
+		meta.Identifier = "urn:uuid:" + id
+	}
+
+	var creator string
+	if meta.Author != "" {
+		creator = fmt.Sprintf("\n    <dc:creator>%s</dc:creator>", html.EscapeString(meta.Author))
+	}
+	opfPath := path.Join(newBookDir, "content.opf")
+	opf := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="bookid">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="bookid">%s</dc:identifier>
+    <dc:title>%s</dc:title>%s
+    <dc:language>%s</dc:language>
+    <meta property="dcterms:modified">%s</meta>
+  </metadata>
+  <manifest>
+  </manifest>
+  <spine>
+  </spine>
+</package>
+`, html.EscapeString(meta.Identifier), html.EscapeString(meta.Title), creator,
+		html.EscapeString(meta.Language), time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+	container := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="%s" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`, opfPath)
+
+	p := &Epub{
+		entryIndex: make(map[string]*zipEntry),
+	}
+	now := time.Now()
+	files := []struct {
+		name   string
+		data   string
+		method uint16
+	}{
+		{"mimetype", epubMimetype, zip.Store},
+		{"META-INF/container.xml", container, zip.Deflate},
+		{opfPath, opf, zip.Deflate},
+	}
+	for _, f := range files {
+		header := zip.FileHeader{Name: f.name, Method: f.method, Modified: now}
+		if err := p.addLoadedEntry(&zipEntry{header: header, data: []byte(f.data)}); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.finishLoad(OpenOptions{}); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// newUUID 生成随机的 UUID v4
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate identifier: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// chapterPath 返回导入时第 n 个章节的路径
+func chapterPath(n int) string {
+	return path.Join(newBookDir, "Text", fmt.Sprintf("chapter-%04d.xhtml", n))
+}
+
+// chapterDocument 生成导入章节的 HTML，body 为已转义的内容
+func chapterDocument(title, body string) string {
+	var b strings.Builder
+	b.WriteString("<html><head><title>")
+	b.WriteString(html.EscapeString(title))
+	b.WriteString(`</title><link rel="stylesheet" type="text/css" href="../Styles/style.css"/></head><body>`)
+	b.WriteString(body)
+	b.WriteString("</body></html>")
+	return b.String()
+}
+
+// setCoverImage 将已加入 manifest 的图片设为封面：加上 cover-image 属性，并写入 EPUB2 兼容的 <meta name="cover">
+func (p *Epub) setCoverImage(norm string) error {
+	href, err := p.hrefForOPF(norm)
+	if err != nil {
+		return err
+	}
+	var id string
+	for i := range p.opfDoc.Manifest.Items {
+		item := &p.opfDoc.Manifest.Items[i]
+		if item.Href != href {
+			continue
+		}
+		if strings.HasPrefix(p.opfDoc.Version, "3") && !hasProperty(item.Properties, "cover-image") {
+			item.Properties = strings.TrimSpace(item.Properties + " cover-image")
+		}
+		id = item.ID
+		break
+	}
+	if id == "" {
+		return fmt.Errorf("cover image not found in manifest: %s", norm)
+	}
+
+	p.setNamedMeta("cover", id)
+	return nil
+}
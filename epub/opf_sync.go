@@ -0,0 +1,260 @@
+package epub
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// syncOPFTree 将 opfDoc 相对于 opfOrig 的改动写回 opfTree，
+// 未改动的部分（注释、未知属性与元素、命名空间前缀、格式）保持原样
+func (p *Epub) syncOPFTree() error {
+	root := p.opfTree.root()
+	if root == nil {
+		return fmt.Errorf("package element not found in content.opf")
+	}
+	cur, orig := p.opfDoc, p.opfOrig
+	if orig == nil {
+		orig = &opfPackage{}
+	}
+
+	for _, attr := range []struct {
+		name      string
+		cur, orig string
+	}{
+		{"version", cur.Version, orig.Version},
+		{"unique-identifier", cur.UniqueIdentifier, orig.UniqueIdentifier},
+		{"prefix", cur.Prefix, orig.Prefix},
+		{"xml:lang", cur.XMLLang, orig.XMLLang},
+		{"xmlns", cur.XMLNS, orig.XMLNS},
+		{"xmlns:dc", cur.XMLNSDC, orig.XMLNSDC},
+		{"xmlns:opf", cur.XMLNSOPF, orig.XMLNSOPF},
+		{"xmlns:dcterms", cur.XMLNSDCTerms, orig.XMLNSDCTerms},
+	} {
+		if attr.cur != attr.orig {
+			root.setAttr(attr.name, attr.cur)
+		}
+	}
+
+	if !bytes.Equal(cur.Metadata.InnerXML, orig.Metadata.InnerXML) {
+		metadata := p.opfSection(root, "metadata")
+		children, err := parseXMLFragment(cur.Metadata.InnerXML)
+		if err != nil {
+			return fmt.Errorf("failed to parse OPF metadata: %w", err)
+		}
+		metadata.children = children
+	}
+
+	if !manifestEqual(cur.Manifest.Items, orig.Manifest.Items) {
+		syncManifest(p.opfSection(root, "manifest"), cur.Manifest.Items, orig.Manifest.Items)
+	}
+
+	spine := p.opfSection(root, "spine")
+	if cur.Spine.Toc != orig.Spine.Toc {
+		spine.setAttr("toc", cur.Spine.Toc)
+	}
+	if cur.Spine.PageProgressionDirection != orig.Spine.PageProgressionDirection {
+		spine.setAttr("page-progression-direction", cur.Spine.PageProgressionDirection)
+	}
+	if !spineEqual(cur.Spine.Items, orig.Spine.Items) {
+		syncSpine(spine, cur.Spine.Items, orig.Spine.Items)
+	}
+
+	var curGuide, origGuide []opfGuideReference
+	if cur.Guide != nil {
+		curGuide = cur.Guide.References
+	}
+	if orig.Guide != nil {
+		origGuide = orig.Guide.References
+	}
+	if (cur.Guide == nil) != (orig.Guide == nil) || !guideEqual(curGuide, origGuide) {
+		if cur.Guide == nil {
+			root.syncElementChildren("guide", nil)
+		} else {
+			syncGuide(p.opfSection(root, "guide"), curGuide)
+		}
+	}
+	return nil
+}
+
+// opfSection 返回 package 下的指定元素，不存在时按 package 的前缀创建
+func (p *Epub) opfSection(root *xmlNode, local string) *xmlNode {
+	if section := root.child(local); section != nil {
+		return section
+	}
+	section := &xmlNode{kind: xmlElementNode, name: prefixOf(root.name) + local}
+	root.syncElementChildren(local, []*xmlNode{section})
+	return section
+}
+
+func syncManifest(manifest *xmlNode, items, origItems []opfManifestItem) {
+	existing := make(map[string]*xmlNode)
+	for _, c := range manifest.children {
+		if c.kind == xmlElementNode && c.localName() == "item" {
+			if id, ok := c.attr("id"); ok {
+				existing[id] = c
+			}
+		}
+	}
+	origByID := make(map[string]opfManifestItem, len(origItems))
+	for _, item := range origItems {
+		origByID[item.ID] = item
+	}
+
+	nodes := make([]*xmlNode, 0, len(items))
+	for _, item := range items {
+		node, reused := existing[item.ID]
+		if reused {
+			delete(existing, item.ID)
+		} else {
+			node = &xmlNode{kind: xmlElementNode, name: prefixOf(manifest.name) + "item"}
+		}
+		orig := origByID[item.ID]
+		for _, attr := range []struct {
+			name      string
+			cur, orig string
+		}{
+			{"id", item.ID, orig.ID},
+			{"href", item.Href, orig.Href},
+			{"media-type", item.MediaType, orig.MediaType},
+			{"properties", item.Properties, orig.Properties},
+			{"fallback", item.Fallback, orig.Fallback},
+			{"media-overlay", item.MediaOverlay, orig.MediaOverlay},
+		} {
+			if !reused || attr.cur != attr.orig {
+				node.setAttr(attr.name, attr.cur)
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	manifest.syncElementChildren("item", nodes)
+}
+
+func syncSpine(spine *xmlNode, items, origItems []opfSpineItem) {
+	existing := make(map[string][]*xmlNode)
+	for _, c := range spine.children {
+		if c.kind == xmlElementNode && c.localName() == "itemref" {
+			idref, _ := c.attr("idref")
+			existing[idref] = append(existing[idref], c)
+		}
+	}
+	origByIDRef := make(map[string]opfSpineItem, len(origItems))
+	for _, item := range origItems {
+		if _, ok := origByIDRef[item.IDRef]; !ok {
+			origByIDRef[item.IDRef] = item
+		}
+	}
+
+	nodes := make([]*xmlNode, 0, len(items))
+	for _, item := range items {
+		var node *xmlNode
+		reused := len(existing[item.IDRef]) > 0
+		if reused {
+			node = existing[item.IDRef][0]
+			existing[item.IDRef] = existing[item.IDRef][1:]
+		} else {
+			node = &xmlNode{kind: xmlElementNode, name: prefixOf(spine.name) + "itemref"}
+		}
+		orig := origByIDRef[item.IDRef]
+		for _, attr := range []struct {
+			name      string
+			cur, orig string
+		}{
+			{"idref", item.IDRef, orig.IDRef},
+			{"linear", item.Linear, orig.Linear},
+			{"properties", item.Properties, orig.Properties},
+		} {
+			if !reused || attr.cur != attr.orig {
+				node.setAttr(attr.name, attr.cur)
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	spine.syncElementChildren("itemref", nodes)
+}
+
+func syncGuide(guide *xmlNode, refs []opfGuideReference) {
+	existing := make(map[opfGuideReference]*xmlNode)
+	for _, c := range guide.children {
+		if c.kind == xmlElementNode && c.localName() == "reference" {
+			typ, _ := c.attr("type")
+			title, _ := c.attr("title")
+			href, _ := c.attr("href")
+			existing[opfGuideReference{Type: typ, Title: title, Href: href}] = c
+		}
+	}
+
+	nodes := make([]*xmlNode, 0, len(refs))
+	for _, ref := range refs {
+		if node, ok := existing[ref]; ok {
+			delete(existing, ref)
+			nodes = append(nodes, node)
+			continue
+		}
+		node := &xmlNode{kind: xmlElementNode, name: prefixOf(guide.name) + "reference"}
+		node.setAttr("type", ref.Type)
+		node.setAttr("title", ref.Title)
+		node.setAttr("href", ref.Href)
+		nodes = append(nodes, node)
+	}
+	guide.syncElementChildren("reference", nodes)
+}
+
+func guideEqual(a, b []opfGuideReference) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func manifestEqual(a, b []opfManifestItem) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func spineEqual(a, b []opfSpineItem) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// prefixOf 返回元素名的前缀（含冒号），如 "opf:manifest" -> "opf:"
+func prefixOf(name string) string {
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		return name[:i+1]
+	}
+	return ""
+}
+
+// clonePackage 深拷贝 opfPackage
+func clonePackage(doc *opfPackage) *opfPackage {
+	if doc == nil {
+		return nil
+	}
+	c := *doc
+	c.Metadata.InnerXML = append([]byte(nil), doc.Metadata.InnerXML...)
+	c.Manifest.Items = append([]opfManifestItem(nil), doc.Manifest.Items...)
+	c.Spine.Items = append([]opfSpineItem(nil), doc.Spine.Items...)
+	if doc.Guide != nil {
+		c.Guide = &opfGuide{References: append([]opfGuideReference(nil), doc.Guide.References...)}
+	}
+	return &c
+}
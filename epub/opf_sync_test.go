@@ -0,0 +1,117 @@
+package epub
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// roundTripOPF 保留了 encoding/xml 无法往返的内容：注释、未知元素与属性、自定义命名空间前缀
+const roundTripOPF = `<?xml version="1.0" encoding="UTF-8"?>
+<!-- generated by hand -->
+<package xmlns="http://www.idpf.org/2007/opf" xmlns:x="urn:example" version="3.0" unique-identifier="uid" x:flag="keep">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf">
+    <dc:identifier id="uid">urn:uuid:test</dc:identifier>
+    <dc:title>Test</dc:title>
+    <dc:language>en</dc:language>
+    <meta name="custom" content="value"/>
+  </metadata>
+  <manifest>
+    <!-- chapters -->
+    <item id="ch1" href="Text/ch1.xhtml" media-type="application/xhtml+xml" x:extra="1"/>
+  </manifest>
+  <spine>
+    <itemref idref="ch1"/>
+  </spine>
+  <x:unknown attr="a">text</x:unknown>
+</package>
+`
+
+func TestSyncOPFTreeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(p *Epub)
+		want    []string // 保存后的 OPF 中应包含的片段
+		notWant []string
+		same    bool // 保存后的 OPF 应与原文完全相同
+	}{
+		{
+			name: "unchanged",
+			edit: func(p *Epub) {},
+			same: true,
+		},
+		{
+			name: "spine direction",
+			edit: func(p *Epub) { p.opfDoc.Spine.PageProgressionDirection = "rtl" },
+			want: []string{
+				`<spine page-progression-direction="rtl">`,
+				`<!-- generated by hand -->`,
+				`x:flag="keep"`,
+				`x:extra="1"`,
+				`<x:unknown attr="a">text</x:unknown>`,
+			},
+		},
+		{
+			name: "manifest properties",
+			edit: func(p *Epub) { p.opfDoc.Manifest.Items[0].Properties = "nav" },
+			want: []string{
+				`<item id="ch1" href="Text/ch1.xhtml" media-type="application/xhtml+xml" x:extra="1" properties="nav"/>`,
+				`<!-- chapters -->`,
+			},
+		},
+		{
+			name: "metadata",
+			edit: func(p *Epub) { p.setNamedMeta("custom", "") },
+			want: []string{
+				`<dc:language>en</dc:language>`,
+				`xmlns:opf="http://www.idpf.org/2007/opf"`,
+			},
+			notWant: []string{`name="custom"`},
+		},
+		{
+			name: "spine item added",
+			edit: func(p *Epub) {
+				p.opfDoc.Spine.Items = append(p.opfDoc.Spine.Items, opfSpineItem{IDRef: "ch1", Linear: "no"})
+			},
+			want: []string{
+				`<itemref idref="ch1"/>`,
+				`<itemref idref="ch1" linear="no"/>`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Open(writeTestEPUB(t, roundTripOPF))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.edit(p)
+			outputPath := filepath.Join(t.TempDir(), "out.epub")
+			if err := p.Save(outputPath); err != nil {
+				t.Fatal(err)
+			}
+			saved, err := Open(outputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, ok := saved.ReadFile("OEBPS/content.opf")
+			if !ok {
+				t.Fatal("content.opf missing after save")
+			}
+			got := string(data)
+			if tt.same && got != roundTripOPF {
+				t.Errorf("OPF changed:\n%s", got)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("OPF missing %q:\n%s", want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("OPF still contains %q:\n%s", notWant, got)
+				}
+			}
+		})
+	}
+}
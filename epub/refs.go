@@ -0,0 +1,175 @@
+package epub
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	refAttrDoubleRegex = regexp.MustCompile(`(?i)(\s(?:href|src|xlink:href|poster)\s*=\s*")([^"]*)(")`)
+	refAttrSingleRegex = regexp.MustCompile(`(?i)(\s(?:href|src|xlink:href|poster)\s*=\s*')([^']*)(')`)
+	cssURLRegex        = regexp.MustCompile(`(?i)(url\(\s*["']?)([^"')]+?)(["']?\s*\))`)
+	cssImportRegex     = regexp.MustCompile(`(?i)(@import\s+["'])([^"']+)(["'])`)
+)
+
+// RenameFile 重命名 EPUB 内的文件，并更新 manifest、guide 以及所有 HTML/NCX/CSS 中指向它的链接
+// （包括导航文档中的目录、landmarks 与 page-list）；被移动的文件自身的相对链接也会被修正
+func (p *Epub) RenameFile(oldPath, newPath string) error {
+	oldNorm := normalizeZipPath(oldPath)
+	newNorm := normalizeZipPath(newPath)
+	if oldNorm == newNorm {
+		return nil
+	}
+	entry, ok := p.entryIndex[oldNorm]
+	if !ok || entry.removed || entry.isDir {
+		return fmt.Errorf("file does not exist: %s", oldPath)
+	}
+	if existing, ok := p.entryIndex[newNorm]; ok && !existing.removed {
+		return fmt.Errorf("file already exists: %s", newPath)
+	}
+	if oldNorm == p.opfPath || oldNorm == "mimetype" || strings.HasPrefix(oldNorm, "META-INF/") {
+		return fmt.Errorf("cannot rename %s", oldPath)
+	}
+	newHref, err := p.hrefForOPF(newNorm)
+	if err != nil {
+		return err
+	}
+	if err := p.ensureDirectories(newNorm); err != nil {
+		return err
+	}
+
+	delete(p.entryIndex, oldNorm)
+	entry.header.Name = newNorm
+	p.entryIndex[newNorm] = entry
+
+	for i, item := range p.opfDoc.Manifest.Items {
+		if p.pathFromHref(item.Href) == oldNorm {
+			p.opfDoc.Manifest.Items[i].Href = newHref
+		}
+	}
+
+	return p.rewriteReferences(map[string]string{oldNorm: newNorm})
+}
+
+// rewriteReferences 按 旧路径 -> 新路径 的映射改写所有文本文件与 OPF guide 中的链接；
+// 映射中的文件若已被移动（当前名为新路径），其内部的相对链接按旧位置解析后重新计算
+func (p *Epub) rewriteReferences(mapping map[string]string) error {
+	if len(mapping) == 0 {
+		return nil
+	}
+	movedFrom := make(map[string]string, len(mapping))
+	for oldNorm, newNorm := range mapping {
+		movedFrom[newNorm] = oldNorm
+	}
+
+	for _, entry := range p.entries {
+		if entry.removed || entry.isDir || !isTextEntry(entry) || p.isOPFEntry(entry) {
+			continue
+		}
+		current := normalizeZipPath(entry.header.Name)
+		base := current
+		if oldNorm, ok := movedFrom[current]; ok {
+			base = oldNorm
+		}
+
+		rewrite := func(ref string) string {
+			return rewriteRef(ref, base, current, mapping)
+		}
+		data := string(entry.data)
+		var updated string
+		if strings.EqualFold(path.Ext(current), ".css") {
+			updated = replaceRefs(data, rewrite, cssURLRegex, cssImportRegex)
+		} else {
+			updated = replaceRefs(data, rewrite, refAttrDoubleRegex, refAttrSingleRegex, cssURLRegex)
+		}
+		if updated != data {
+			entry.data = []byte(updated)
+		}
+	}
+
+	if p.opfDoc != nil && p.opfDoc.Guide != nil {
+		for i, ref := range p.opfDoc.Guide.References {
+			p.opfDoc.Guide.References[i].Href = rewriteRef(ref.Href, p.opfPath, p.opfPath, mapping)
+		}
+	}
+	return nil
+}
+
+func replaceRefs(data string, rewrite func(string) string, patterns ...*regexp.Regexp) string {
+	for _, re := range patterns {
+		data = re.ReplaceAllStringFunc(data, func(match string) string {
+			m := re.FindStringSubmatch(match)
+			updated := rewrite(m[2])
+			if updated == m[2] {
+				return match
+			}
+			return m[1] + updated + m[3]
+		})
+	}
+	return data
+}
+
+// rewriteRef 解析 base 文件中的链接 ref，目标在 mapping 中时指向新路径，
+// 并以 current 文件所在目录为基准重新计算相对路径；无需修改时原样返回
+func rewriteRef(ref, base, current string, mapping map[string]string) string {
+	if ref == "" || strings.HasPrefix(ref, "#") || isExternalRef(ref) {
+		return ref
+	}
+	target, fragment := splitFragment(ref)
+	escaped := strings.Contains(target, "%")
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	resolved := resolveHref(base, target)
+
+	newTarget, moved := mapping[resolved]
+	if !moved && base == current {
+		return ref
+	}
+	if !moved {
+		newTarget = resolved
+	}
+
+	rel := relativeRef(current, newTarget)
+	if escaped {
+		rel = (&url.URL{Path: rel}).EscapedPath()
+	}
+	return rel + fragment
+}
+
+// relativeRef 计算从 fromFile 指向 toFile 的相对链接
+func relativeRef(fromFile, toFile string) string {
+	dir := normalizeZipPath(path.Dir(fromFile))
+	if dir == "." {
+		dir = ""
+	}
+	return calculateRelativePath(dir, toFile)
+}
+
+// splitFragment 将链接拆分为路径与 #fragment 两部分
+func splitFragment(ref string) (string, string) {
+	if i := strings.IndexByte(ref, '#'); i >= 0 {
+		return ref[:i], ref[i:]
+	}
+	return ref, ""
+}
+
+// isExternalRef 判断链接是否为带协议的外部地址（http:、data:、mailto: 等）或绝对路径
+func isExternalRef(ref string) bool {
+	if strings.HasPrefix(ref, "/") {
+		return true
+	}
+	if i := strings.IndexByte(ref, ':'); i > 0 {
+		scheme := ref[:i]
+		for _, r := range scheme {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '+' || r == '-' || r == '.') {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
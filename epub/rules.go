@@ -0,0 +1,326 @@
+package epub
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"gopkg.in/yaml.v3"
+)
+
+// 规则动作
+const (
+	RuleRemoveChapter   = "remove-chapter"   // 章节文本匹配时删除整个章节
+	RuleRemoveElement   = "remove-element"   // 删除匹配 Selector 的元素（可用 Contains/Pattern 过滤）
+	RuleRemoveParagraph = "remove-paragraph" // 删除包含指定文本的段落，Selector 默认为 p
+	RuleReplace         = "replace"          // 对文本节点做正则替换，可用 Selector 限定范围
+	RuleRemoveAttribute = "remove-attribute" // 删除元素上的属性，Selector 默认为全部元素
+)
+
+// RuleSet 为规则文件的内容，支持 YAML 与 JSON（JSON 作为 YAML 的子集解析）
+//
+//	rules:
+//	  - name: 去掉推广段落
+//	    action: remove-paragraph
+//	    contains: 关注公众号
+//	  - name: 去掉水印
+//	    action: replace
+//	    files: ["Text/*.xhtml"]
+//	    pattern: '本书由.{0,20}整理'
+type RuleSet struct {
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// Rule 描述一条清理规则，规则按顺序执行
+type Rule struct {
+	Name   string `json:"name" yaml:"name"`
+	Action string `json:"action" yaml:"action"`
+
+	// Files 为作用的文件，按 path.Match 匹配 zip 内路径、相对 OPF 的路径或文件名；为空时作用于所有章节
+	Files []string `json:"files,omitempty" yaml:"files,omitempty"`
+	// Selector 为元素选择器（CSS 子集），用于元素级动作或限定替换范围
+	Selector string `json:"selector,omitempty" yaml:"selector,omitempty"`
+
+	Contains    string `json:"contains,omitempty" yaml:"contains,omitempty"`       // 文本包含
+	Pattern     string `json:"pattern,omitempty" yaml:"pattern,omitempty"`         // 文本正则
+	Replacement string `json:"replacement,omitempty" yaml:"replacement,omitempty"` // replace 的替换内容，支持 $1
+	Attribute   string `json:"attribute,omitempty" yaml:"attribute,omitempty"`     // remove-attribute 的属性名
+}
+
+// RuleHits 为单条规则的命中统计
+type RuleHits struct {
+	Name   string         `json:"name"`
+	Action string         `json:"action"`
+	Hits   int            `json:"hits"`
+	Files  map[string]int `json:"files,omitempty"` // 文件 -> 命中次数
+}
+
+// RuleReport 为 ApplyRules 的结果，Rules 与规则顺序一致
+type RuleReport struct {
+	Rules           []RuleHits `json:"rules"`
+	RemovedChapters []string   `json:"removedChapters,omitempty"`
+}
+
+type compiledRule struct {
+	Rule
+	selector selector
+	pattern  *regexp.Regexp
+}
+
+// LoadRules 读取 YAML 或 JSON 格式的规则文件
+func LoadRules(filePath string) (*RuleSet, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+	return ParseRules(data)
+}
+
+// ParseRules 解析 YAML 或 JSON 格式的规则并校验
+func ParseRules(data []byte) (*RuleSet, error) {
+	rs := &RuleSet{}
+	if err := yaml.Unmarshal(data, rs); err != nil {
+		return nil, fmt.Errorf("failed to parse rules: %w", err)
+	}
+	if _, err := rs.compile(); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+func (rs *RuleSet) compile() ([]compiledRule, error) {
+	rules := make([]compiledRule, 0, len(rs.Rules))
+	for i, rule := range rs.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		c := compiledRule{Rule: rule}
+		selectorText := rule.Selector
+		switch rule.Action {
+		case RuleRemoveChapter:
+			if rule.Contains == "" && rule.Pattern == "" && rule.Selector == "" {
+				return nil, fmt.Errorf("rule %s: contains, pattern or selector is required", rule.Name)
+			}
+		case RuleRemoveElement:
+			if rule.Selector == "" {
+				return nil, fmt.Errorf("rule %s: selector is required", rule.Name)
+			}
+		case RuleRemoveParagraph:
+			if rule.Contains == "" && rule.Pattern == "" {
+				return nil, fmt.Errorf("rule %s: contains or pattern is required", rule.Name)
+			}
+			if selectorText == "" {
+				selectorText = "p"
+			}
+		case RuleReplace:
+			if rule.Pattern == "" {
+				return nil, fmt.Errorf("rule %s: pattern is required", rule.Name)
+			}
+		case RuleRemoveAttribute:
+			if rule.Attribute == "" {
+				return nil, fmt.Errorf("rule %s: attribute is required", rule.Name)
+			}
+			if selectorText == "" {
+				selectorText = "*"
+			}
+		default:
+			return nil, fmt.Errorf("rule %s: unknown action %q", rule.Name, rule.Action)
+		}
+		if selectorText != "" {
+			sel, err := parseSelector(selectorText)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+			}
+			c.selector = sel
+		}
+		if rule.Pattern != "" {
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: invalid pattern: %w", rule.Name, err)
+			}
+			c.pattern = re
+		}
+		for _, pattern := range rule.Files {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("rule %s: invalid file pattern %q: %w", rule.Name, pattern, err)
+			}
+		}
+		rules = append(rules, c)
+	}
+	return rules, nil
+}
+
+// ApplyRules 按顺序对章节执行规则，返回每条规则的命中报告；
+// 每个章节只解析与序列化一次，没有命中的章节保持原样
+func (p *Epub) ApplyRules(rs *RuleSet) (*RuleReport, error) {
+	rules, err := rs.compile()
+	if err != nil {
+		return nil, err
+	}
+	report := &RuleReport{Rules: make([]RuleHits, len(rules))}
+	for i, rule := range rules {
+		report.Rules[i] = RuleHits{Name: rule.Name, Action: rule.Action, Files: map[string]int{}}
+	}
+
+	navPath := p.navPath()
+	for _, entry := range p.htmlEntriesInOrder() {
+		norm := normalizeZipPath(entry.header.Name)
+		if norm == navPath {
+			continue
+		}
+		var doc *html.Node
+		changed, removeChapter := false, false
+		for i, rule := range rules {
+			if !rule.matchFile(norm, p.opfDir) {
+				continue
+			}
+			if doc == nil {
+				doc, err = html.Parse(strings.NewReader(string(entry.data)))
+				if err != nil {
+					return report, fmt.Errorf("failed to parse HTML (%s): %w", entry.header.Name, err)
+				}
+			}
+			hits := rule.apply(doc)
+			if hits == 0 {
+				continue
+			}
+			report.Rules[i].Hits += hits
+			report.Rules[i].Files[norm] += hits
+			if rule.Action == RuleRemoveChapter {
+				removeChapter = true
+				break
+			}
+			changed = true
+		}
+
+		if removeChapter {
+			if err := p.removeEntry(norm); err != nil {
+				return report, err
+			}
+			report.RemovedChapters = append(report.RemovedChapters, norm)
+			continue
+		}
+		if changed {
+			rendered, err := renderXHTMLDocument(doc, p.defaultDoctype())
+			if err != nil {
+				return report, fmt.Errorf("failed to render HTML (%s): %w", entry.header.Name, err)
+			}
+			entry.data = []byte(rendered)
+		}
+	}
+	return report, nil
+}
+
+func (r *compiledRule) matchFile(norm, opfDir string) bool {
+	if len(r.Files) == 0 {
+		return true
+	}
+	rel := strings.TrimPrefix(norm, opfDir+"/")
+	for _, pattern := range r.Files {
+		for _, name := range []string{norm, rel, path.Base(norm)} {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchText 判断文本是否满足 Contains 与 Pattern（两者都设置时需同时满足）
+func (r *compiledRule) matchText(text string) bool {
+	if r.Contains != "" && !strings.Contains(text, r.Contains) {
+		return false
+	}
+	if r.pattern != nil && !r.pattern.MatchString(text) {
+		return false
+	}
+	return true
+}
+
+// apply 在文档上执行规则，返回命中次数；remove-chapter 命中时不修改文档
+func (r *compiledRule) apply(doc *html.Node) int {
+	body := findElement(doc, atom.Body)
+	if body == nil {
+		body = doc
+	}
+	switch r.Action {
+	case RuleRemoveChapter:
+		if r.selector != nil && len(r.selectElements(body)) == 0 {
+			return 0
+		}
+		if (r.Contains != "" || r.pattern != nil) && !r.matchText(nodeText(body)) {
+			return 0
+		}
+		return 1
+	case RuleRemoveElement, RuleRemoveParagraph:
+		hits := 0
+		for _, n := range r.selectElements(body) {
+			if n.Parent == nil || !r.matchText(nodeText(n)) {
+				continue
+			}
+			n.Parent.RemoveChild(n)
+			hits++
+		}
+		return hits
+	case RuleReplace:
+		scopes := []*html.Node{body}
+		if r.selector != nil {
+			scopes = r.selectElements(body)
+		}
+		hits := 0
+		for _, scope := range scopes {
+			hits += r.replaceText(scope)
+		}
+		return hits
+	case RuleRemoveAttribute:
+		hits := 0
+		for _, n := range r.selectElements(body) {
+			before := len(n.Attr)
+			removeAttr(n, r.Attribute)
+			hits += before - len(n.Attr)
+		}
+		return hits
+	}
+	return 0
+}
+
+// selectElements 返回匹配选择器的元素，已被选中元素的后代不再重复返回
+func (r *compiledRule) selectElements(root *html.Node) []*html.Node {
+	var matches []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && r.selector.match(n) {
+			matches = append(matches, n)
+			if r.Action != RuleRemoveAttribute {
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	return matches
+}
+
+func (r *compiledRule) replaceText(n *html.Node) int {
+	if n.Type == html.ElementNode && (n.DataAtom == atom.Script || n.DataAtom == atom.Style) {
+		return 0
+	}
+	if n.Type == html.TextNode {
+		count := len(r.pattern.FindAllStringIndex(n.Data, -1))
+		if count > 0 {
+			n.Data = r.pattern.ReplaceAllString(n.Data, r.Replacement)
+		}
+		return count
+	}
+	hits := 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		hits += r.replaceText(c)
+	}
+	return hits
+}
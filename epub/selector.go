@@ -0,0 +1,346 @@
+package epub
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// selector 是 CSS 选择器的一个子集：类型、#id、.class、[attr]、[attr=v]、[attr*=v]、[attr^=v]、[attr$=v]、[attr~=v]，
+// 支持后代（空格）与子代（>）组合以及逗号分隔的多个选择器；伪类与伪元素不受支持，解析时报错
+type selector []selectorChain
+
+type selectorChain struct {
+	parts      []compoundSelector
+	combinator []byte // combinator[i] 连接 parts[i] 与 parts[i+1]，取值 ' ' 或 '>'
+}
+
+type compoundSelector struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   []attrSelector
+}
+
+type attrSelector struct {
+	name  string
+	op    string
+	value string
+}
+
+func parseSelector(s string) (selector, error) {
+	var groups []string
+	start := 0
+	err := scanTopLevel(s, func(i int) {
+		if s[i] == ',' {
+			groups = append(groups, s[start:i])
+			start = i + 1
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %w", s, err)
+	}
+	groups = append(groups, s[start:])
+
+	var sel selector
+	for _, group := range groups {
+		group = strings.TrimSpace(group)
+		if group == "" {
+			return nil, fmt.Errorf("invalid selector %q: empty group", s)
+		}
+		chain, err := parseSelectorChain(group)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %w", s, err)
+		}
+		sel = append(sel, chain)
+	}
+	return sel, nil
+}
+
+func parseSelectorChain(s string) (selectorChain, error) {
+	var chain selectorChain
+	var tokens []string
+	start := 0
+	flush := func(end int) {
+		if token := s[start:end]; token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	err := scanTopLevel(s, func(i int) {
+		switch s[i] {
+		case ' ', '\t', '\n', '\r', '\f':
+			flush(i)
+			start = i + 1
+		case '>':
+			flush(i)
+			tokens = append(tokens, ">")
+			start = i + 1
+		}
+	})
+	if err != nil {
+		return chain, err
+	}
+	flush(len(s))
+
+	pendingChild := false
+	for _, token := range tokens {
+		if token == ">" {
+			if len(chain.parts) == 0 || pendingChild {
+				return chain, fmt.Errorf("unexpected '>'")
+			}
+			pendingChild = true
+			continue
+		}
+		compound, err := parseCompound(token)
+		if err != nil {
+			return chain, err
+		}
+		if len(chain.parts) > 0 {
+			if pendingChild {
+				chain.combinator = append(chain.combinator, '>')
+			} else {
+				chain.combinator = append(chain.combinator, ' ')
+			}
+		}
+		pendingChild = false
+		chain.parts = append(chain.parts, compound)
+	}
+	if pendingChild || len(chain.parts) == 0 {
+		return chain, fmt.Errorf("incomplete selector")
+	}
+	return chain, nil
+}
+
+func parseCompound(s string) (compoundSelector, error) {
+	var c compoundSelector
+	i := 0
+	readName := func() string {
+		start := i
+		for i < len(s) && !strings.ContainsRune("#.[:", rune(s[i])) {
+			i++
+		}
+		return s[start:i]
+	}
+	if !strings.ContainsRune("#.[:", rune(s[0])) {
+		c.tag = strings.ToLower(readName())
+		if c.tag == "*" {
+			c.tag = ""
+		}
+	}
+	for i < len(s) {
+		switch s[i] {
+		case '#':
+			i++
+			c.id = readName()
+		case '.':
+			i++
+			c.classes = append(c.classes, readName())
+		case '[':
+			end := closingBracket(s[i:])
+			if end < 0 {
+				return c, fmt.Errorf("unclosed '['")
+			}
+			attr, err := parseAttrSelector(s[i+1 : i+end])
+			if err != nil {
+				return c, err
+			}
+			c.attrs = append(c.attrs, attr)
+			i += end + 1
+		case ':':
+			return c, unsupportedPseudo(s[i:])
+		default:
+			return c, fmt.Errorf("unexpected %q", s[i])
+		}
+	}
+	return c, nil
+}
+
+// unsupportedPseudo 返回指出 s 开头的伪类或伪元素（含括号内的参数）不受支持的错误
+func unsupportedPseudo(s string) error {
+	kind, end := "pseudo-class", 1
+	if strings.HasPrefix(s, "::") {
+		kind, end = "pseudo-element", 2
+	}
+	for end < len(s) && isCSSNameByte(s[end]) {
+		end++
+	}
+	if end < len(s) && s[end] == '(' {
+		depth := 0
+		for ; end < len(s); end++ {
+			if s[end] == '(' {
+				depth++
+			} else if s[end] == ')' {
+				if depth--; depth == 0 {
+					end++
+					break
+				}
+			}
+		}
+	}
+	return fmt.Errorf("unsupported %s %q", kind, s[:end])
+}
+
+func parseAttrSelector(s string) (attrSelector, error) {
+	// 属性名中不会出现 '='，第一个 '=' 即为运算符，值中的 '=' 不影响解析
+	if idx := strings.IndexByte(s, '='); idx > 0 {
+		nameEnd, op := idx, "="
+		if strings.IndexByte("*^$~", s[idx-1]) >= 0 {
+			nameEnd, op = idx-1, s[idx-1:idx+1]
+		}
+		name := strings.TrimSpace(s[:nameEnd])
+		if name == "" {
+			return attrSelector{}, fmt.Errorf("empty attribute name in %q", s)
+		}
+		value := strings.TrimSpace(s[idx+1:])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		return attrSelector{name: name, op: op, value: value}, nil
+	}
+	if strings.TrimSpace(s) == "" {
+		return attrSelector{}, fmt.Errorf("empty attribute selector")
+	}
+	return attrSelector{name: strings.TrimSpace(s)}, nil
+}
+
+// scanTopLevel 依次对 s 中位于方括号、圆括号与引号之外的字节调用 fn，
+// 使 [title="a, b"]、[alt="x > y"]、:not(a, b) 中的逗号、'>' 与空格不被当作分隔符
+func scanTopLevel(s string, fn func(i int)) error {
+	var quote byte
+	brackets, parens := 0, 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case brackets+parens > 0 && (c == '"' || c == '\''):
+			quote = c
+		case c == '[':
+			brackets++
+		case c == ']' && brackets > 0:
+			brackets--
+		case c == '(':
+			parens++
+		case c == ')' && parens > 0:
+			parens--
+		case brackets+parens == 0:
+			fn(i)
+		}
+	}
+	if quote != 0 {
+		return fmt.Errorf("unclosed quote")
+	}
+	if brackets > 0 {
+		return fmt.Errorf("unclosed '['")
+	}
+	if parens > 0 {
+		return fmt.Errorf("unclosed '('")
+	}
+	return nil
+}
+
+// closingBracket 返回以 '[' 开头的 s 中与之匹配的 ']' 的下标，跳过引号内的内容，未闭合时返回 -1
+func closingBracket(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// match 判断元素是否匹配选择器
+func (sel selector) match(n *html.Node) bool {
+	for _, chain := range sel {
+		if chain.match(n, len(chain.parts)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+func (chain selectorChain) match(n *html.Node, i int) bool {
+	if !chain.parts[i].match(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	for p := n.Parent; p != nil && p.Type == html.ElementNode; p = p.Parent {
+		if chain.match(p, i-1) {
+			return true
+		}
+		if chain.combinator[i-1] == '>' {
+			return false
+		}
+	}
+	return false
+}
+
+func (c compoundSelector) match(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	if c.tag != "" && !strings.EqualFold(n.Data, c.tag) {
+		return false
+	}
+	if c.id != "" && attrValue(n, "id") != c.id {
+		return false
+	}
+	for _, class := range c.classes {
+		if !hasProperty(attrValue(n, "class"), class) {
+			return false
+		}
+	}
+	for _, attr := range c.attrs {
+		value, ok := lookupAttr(n, attr.name)
+		if !ok {
+			return false
+		}
+		switch attr.op {
+		case "=":
+			ok = value == attr.value
+		case "*=":
+			ok = strings.Contains(value, attr.value)
+		case "^=":
+			ok = strings.HasPrefix(value, attr.value)
+		case "$=":
+			ok = strings.HasSuffix(value, attr.value)
+		case "~=":
+			ok = hasProperty(value, attr.value)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func lookupAttr(n *html.Node, key string) (string, bool) {
+	for _, attr := range n.Attr {
+		name := attr.Key
+		if attr.Namespace != "" {
+			name = attr.Namespace + ":" + attr.Key
+		}
+		if strings.EqualFold(name, key) {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
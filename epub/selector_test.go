@@ -0,0 +1,121 @@
+package epub
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const selectorTestHTML = `<html><body>
+<div id="d1" class="note main">
+  <p id="p1" title="a, b">one</p>
+  <span id="s1"><p id="p2">two</p></span>
+</div>
+<p id="p3" class="main">three</p>
+<img id="i1" alt="x > y" src="a.png"/>
+<a id="a1" href="#fn1" epub:type="noteref">1</a>
+<a id="a2" href="http://example.com/">link</a>
+</body></html>`
+
+func TestParseSelector(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(selectorTestHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		selector string
+		want     []string // 按文档顺序匹配的元素 id
+		wantErr  bool
+	}{
+		{selector: "p", want: []string{"p1", "p2", "p3"}},
+		{selector: "#p3", want: []string{"p3"}},
+		{selector: ".main", want: []string{"d1", "p3"}},
+		{selector: "div.note.main", want: []string{"d1"}},
+		{selector: "div p", want: []string{"p1", "p2"}},
+		{selector: "div > p", want: []string{"p1"}},
+		{selector: "div>p", want: []string{"p1"}},
+		{selector: "div > span > p", want: []string{"p2"}},
+		{selector: "p, img", want: []string{"p1", "p2", "p3", "i1"}},
+		{selector: `[title="a, b"]`, want: []string{"p1"}},
+		{selector: `p[title='a, b'], img`, want: []string{"p1", "i1"}},
+		{selector: `img[alt="x > y"]`, want: []string{"i1"}},
+		{selector: `div [title="a, b"]`, want: []string{"p1"}},
+		{selector: `a[href^="#fn"]`, want: []string{"a1"}},
+		{selector: `a[href$=".com/"]`, want: []string{"a2"}},
+		{selector: `a[href*=example]`, want: []string{"a2"}},
+		{selector: `[class~=note]`, want: []string{"d1"}},
+		{selector: `a[epub:type=noteref]`, want: []string{"a1"}},
+		{selector: "*#s1", want: []string{"s1"}},
+		{selector: "", wantErr: true},
+		{selector: "p,", wantErr: true},
+		{selector: "> p", wantErr: true},
+		{selector: "div >", wantErr: true},
+		{selector: "div > > p", wantErr: true},
+		{selector: `[title="a`, wantErr: true},
+		{selector: "p[title", wantErr: true},
+		{selector: "[]", wantErr: true},
+		{selector: "p:first-child", wantErr: true},
+		{selector: "a::before", wantErr: true},
+		{selector: ":not(p, img)", wantErr: true},
+		{selector: "p:nth-child(2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			matches, err := QueryAll(doc, tt.selector)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("QueryAll(%q) succeeded, want error", tt.selector)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("QueryAll(%q) error: %v", tt.selector, err)
+			}
+			var got []string
+			for _, n := range matches {
+				got = append(got, attrValue(n, "id"))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("QueryAll(%q) = %v, want %v", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnsupportedPseudo(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     string // 错误信息应包含的内容
+	}{
+		{"p:first-child", `unsupported pseudo-class ":first-child"`},
+		{"div > li:nth-child(2n+1).x", `unsupported pseudo-class ":nth-child(2n+1)"`},
+		{"p:not(.a, .b)", `unsupported pseudo-class ":not(.a, .b)"`},
+		{"a::before", `unsupported pseudo-element "::before"`},
+		{":root", `unsupported pseudo-class ":root"`},
+	}
+	doc, err := html.Parse(strings.NewReader(selectorTestHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		if _, err := QueryAll(doc, tt.selector); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("QueryAll(%q) error = %v, want %s", tt.selector, err, tt.want)
+		}
+		if _, err := RemoveAll(doc, tt.selector); err == nil {
+			t.Errorf("RemoveAll(%q) succeeded, want error", tt.selector)
+		}
+	}
+
+	rules := filepath.Join(t.TempDir(), "rules.yaml")
+	data := "rules:\n  - name: first paragraph\n    action: remove-element\n    selector: 'p:first-child'\n"
+	if err := os.WriteFile(rules, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRules(rules); err == nil || !strings.Contains(err.Error(), `":first-child"`) {
+		t.Errorf("LoadRules() error = %v, want the unsupported pseudo-class named", err)
+	}
+}
@@ -0,0 +1,106 @@
+package epub
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Snapshot 保存 Epub 在某一时刻的状态，可通过 Restore 回滚。
+// 文件内容在修改时总是整体替换而不会原地改写，因此快照只复制条目的元信息，
+// 内容与 Epub 共享（写时复制）；OPF 的结构体与树会被深拷贝
+type Snapshot struct {
+	owner *Epub
+
+	entries    []zipEntry
+	entryIndex map[string]int // 路径 -> entries 下标
+
+	opfPath   string
+	opfDir    string
+	opfDoc    *opfPackage
+	opfTree   *xmlNode
+	opfOrig   *opfPackage
+	idCounter int
+
+	protection Protection
+}
+
+// Snapshot 记录当前状态，之后的修改可通过 Restore 撤销；同一快照可以多次 Restore
+func (p *Epub) Snapshot() *Snapshot {
+	s := &Snapshot{
+		owner:      p,
+		entries:    make([]zipEntry, len(p.entries)),
+		entryIndex: make(map[string]int, len(p.entryIndex)),
+		opfPath:    p.opfPath,
+		opfDir:     p.opfDir,
+		opfDoc:     clonePackage(p.opfDoc),
+		opfTree:    p.opfTree.clone(),
+		opfOrig:    clonePackage(p.opfOrig),
+		idCounter:  p.idCounter,
+		protection: p.protection,
+	}
+	positions := make(map[*zipEntry]int, len(p.entries))
+	for i, entry := range p.entries {
+		s.entries[i] = *entry
+		positions[entry] = i
+	}
+	for name, entry := range p.entryIndex {
+		if i, ok := positions[entry]; ok {
+			s.entryIndex[name] = i
+		}
+	}
+	return s
+}
+
+// Restore 将 Epub 恢复到 s 记录的状态，s 必须由同一个 Epub 的 Snapshot 创建
+func (p *Epub) Restore(s *Snapshot) error {
+	if s == nil {
+		return fmt.Errorf("snapshot cannot be nil")
+	}
+	if s.owner != p {
+		return fmt.Errorf("snapshot belongs to a different epub")
+	}
+
+	entries := make([]*zipEntry, len(s.entries))
+	for i := range s.entries {
+		entry := s.entries[i]
+		entries[i] = &entry
+	}
+	index := make(map[string]*zipEntry, len(s.entryIndex))
+	for name, i := range s.entryIndex {
+		index[name] = entries[i]
+	}
+
+	p.entries = entries
+	p.entryIndex = index
+	p.opfPath = s.opfPath
+	p.opfDir = s.opfDir
+	p.opfDoc = clonePackage(s.opfDoc)
+	p.opfTree = s.opfTree.clone()
+	p.opfOrig = clonePackage(s.opfOrig)
+	p.idCounter = s.idCounter
+	p.protection = s.protection
+	return nil
+}
+
+// Transaction 执行 fn，fn 返回错误或 panic 时将 Epub 回滚到调用前的状态（panic 会在回滚后继续抛出）；
+// 回滚失败时其错误与 fn 的错误一并返回，或附在 panic 的值上
+func (p *Epub) Transaction(fn func(p *Epub) error) (err error) {
+	snapshot := p.Snapshot()
+	defer func() {
+		r := recover()
+		if r == nil && err == nil {
+			return
+		}
+		if rerr := p.Restore(snapshot); rerr != nil {
+			rerr = fmt.Errorf("failed to restore snapshot: %w", rerr)
+			if r != nil {
+				panic(fmt.Errorf("%v (%w)", r, rerr))
+			}
+			err = errors.Join(err, rerr)
+		}
+		if r != nil {
+			panic(r)
+		}
+	}()
+	return fn(p)
+}
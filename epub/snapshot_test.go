@@ -0,0 +1,162 @@
+package epub
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// epubState 为比较回滚结果而记录的 Epub 状态
+type epubState struct {
+	files     map[string]string
+	opfDoc    *opfPackage
+	opfTree   *xmlNode
+	idCounter int
+}
+
+func captureState(p *Epub) epubState {
+	files := make(map[string]string)
+	for name, entry := range p.entryIndex {
+		if !entry.removed {
+			files[name] = string(entry.data)
+		}
+	}
+	return epubState{files: files, opfDoc: clonePackage(p.opfDoc), opfTree: p.opfTree.clone(), idCounter: p.idCounter}
+}
+
+func checkState(t *testing.T, p *Epub, want epubState) {
+	t.Helper()
+	got := captureState(p)
+	if !reflect.DeepEqual(got.files, want.files) {
+		t.Errorf("files = %v, want %v", keys(got.files), keys(want.files))
+	}
+	if !reflect.DeepEqual(got.opfDoc, want.opfDoc) || !reflect.DeepEqual(got.opfTree, want.opfTree) {
+		t.Errorf("OPF changed: %+v", got.opfDoc.Manifest.Items)
+	}
+	if got.idCounter != want.idCounter {
+		t.Errorf("idCounter = %d, want %d", got.idCounter, want.idCounter)
+	}
+}
+
+func keys(m map[string]string) []string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	return names
+}
+
+// modify 新增章节、改写与删除已有章节，覆盖条目、OPF 与 idCounter 的修改
+func modify(t *testing.T, p *Epub) {
+	t.Helper()
+	if err := p.AddChapter("OEBPS/Text/ch2.xhtml", "<p>new</p>", -1); err != nil {
+		t.Fatalf("AddChapter() error = %v", err)
+	}
+	if err := p.writeEntry("OEBPS/Text/ch2.xhtml", []byte("changed")); err != nil {
+		t.Fatalf("writeEntry() error = %v", err)
+	}
+	if err := p.RemoveFileByName("OEBPS/Text/ch1.xhtml"); err != nil {
+		t.Fatalf("RemoveFileByName() error = %v", err)
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	book, err := Open(writeTestEPUB(t, ""))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	want := captureState(book)
+	snapshot := book.Snapshot()
+	for i := 0; i < 2; i++ {
+		modify(t, book)
+		if got := captureState(book); reflect.DeepEqual(got.files, want.files) || got.idCounter == want.idCounter ||
+			reflect.DeepEqual(got.opfDoc, want.opfDoc) {
+			t.Fatal("modify() left the entries, OPF or idCounter unchanged")
+		}
+		if err := book.Restore(snapshot); err != nil {
+			t.Fatalf("Restore() error = %v", err)
+		}
+		checkState(t, book, want)
+	}
+
+	other, err := Open(writeTestEPUB(t, ""))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err := other.Restore(snapshot); err == nil {
+		t.Error("Restore() with another epub's snapshot succeeded")
+	}
+	if err := book.Restore(nil); err == nil {
+		t.Error("Restore(nil) succeeded")
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	book, err := Open(writeTestEPUB(t, ""))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	want := captureState(book)
+	errFailed := errors.New("failed")
+
+	err = book.Transaction(func(p *Epub) error {
+		modify(t, p)
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("Transaction() error = %v, want %v", err, errFailed)
+	}
+	checkState(t, book, want)
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recover() = %v, want the original panic", r)
+			}
+		}()
+		book.Transaction(func(p *Epub) error {
+			modify(t, p)
+			panic("boom")
+		})
+	}()
+	checkState(t, book, want)
+
+	if err := book.Transaction(func(p *Epub) error { modify(t, p); return nil }); err != nil {
+		t.Fatalf("Transaction() error = %v", err)
+	}
+	if _, ok := book.ReadFile("OEBPS/Text/ch2.xhtml"); !ok {
+		t.Error("successful transaction was rolled back")
+	}
+}
+
+func TestApplyCustomizeFailure(t *testing.T) {
+	input := writeTestEPUB(t, "")
+	book, err := Open(input)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	want := captureState(book)
+	errFailed := errors.New("failed")
+	opts := ProcessOptions{
+		ReplaceHTML: func(name, html string) (string, error) { return html + "<!-- replaced -->", nil },
+		Customize: func(p *Epub) error {
+			modify(t, p)
+			return errFailed
+		},
+	}
+	if err := book.Apply(opts); !errors.Is(err, errFailed) {
+		t.Fatalf("Apply() error = %v, want %v", err, errFailed)
+	}
+	checkState(t, book, want)
+
+	opts.InputPath = input
+	opts.OutputPath = filepath.Join(t.TempDir(), "out.epub")
+	if err := Process(opts); !errors.Is(err, errFailed) {
+		t.Fatalf("Process() error = %v, want %v", err, errFailed)
+	}
+	if _, err := os.Stat(opts.OutputPath); !os.IsNotExist(err) {
+		t.Errorf("Process() wrote %s after a failed Customize", opts.OutputPath)
+	}
+}
@@ -0,0 +1,247 @@
+package epub
+
+import (
+	"compress/flate"
+	"hash/crc32"
+	"path"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 估算阅读时间使用的阅读速度
+const (
+	CJKCharsPerMinute   = 400 // 中日韩文字每分钟字数
+	LatinWordsPerMinute = 230 // 拉丁文字每分钟词数
+)
+
+// TextStats 为文本计数，CJK 字符逐字计数，拉丁文字按空白分词
+type TextStats struct {
+	CJKChars       int     `json:"cjkChars"`
+	LatinWords     int     `json:"latinWords"`
+	Words          int     `json:"words"` // CJKChars + LatinWords
+	ReadingMinutes float64 `json:"readingMinutes"`
+}
+
+// ChapterStats 为单个章节的统计
+type ChapterStats struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	TextStats
+	Images     int   `json:"images"`
+	ImageBytes int64 `json:"imageBytes"` // 章节引用的图片大小之和
+}
+
+// EntryStats 为单个文件的大小统计
+type EntryStats struct {
+	Name           string `json:"name"`
+	MediaType      string `json:"mediaType,omitempty"`
+	Size           int64  `json:"size"`
+	CompressedSize int64  `json:"compressedSize"` // zip 中的压缩大小，新增或修改过的文件为重新压缩的估算值
+}
+
+// Stats 为整本书的统计
+type Stats struct {
+	TextStats
+	Chapters []ChapterStats `json:"chapters"`
+
+	Images          int   `json:"images"`
+	ImageBytes      int64 `json:"imageBytes"`
+	Stylesheets     int   `json:"stylesheets"`
+	StylesheetBytes int64 `json:"stylesheetBytes"`
+	Fonts           int   `json:"fonts"`
+	FontBytes       int64 `json:"fontBytes"`
+
+	Size           int64        `json:"size"`
+	CompressedSize int64        `json:"compressedSize"`
+	Entries        []EntryStats `json:"entries"`
+}
+
+// Stats 统计每个章节与整本书的字数、阅读时间、图片，以及样式、字体与各文件的大小
+func (p *Epub) Stats() *Stats {
+	stats := &Stats{}
+	mediaTypes := make(map[string]string)
+	if p.opfDoc != nil {
+		for _, item := range p.opfDoc.Manifest.Items {
+			mediaTypes[p.pathFromHref(item.Href)] = item.MediaType
+		}
+	}
+
+	navPath := p.navPath()
+	for _, entry := range p.htmlEntriesInOrder() {
+		norm := normalizeZipPath(entry.header.Name)
+		if norm == navPath {
+			continue
+		}
+		doc, err := html.Parse(strings.NewReader(string(entry.data)))
+		if err != nil {
+			continue
+		}
+		chapter := ChapterStats{
+			Name:      entry.header.Name,
+			Title:     chapterTitle(doc),
+			TextStats: CountText(nodeText(doc)),
+		}
+		for _, src := range imageSources(doc) {
+			chapter.Images++
+			if image, ok := p.entryIndex[resolveHref(norm, src)]; ok && !image.removed {
+				chapter.ImageBytes += int64(len(image.data))
+			}
+		}
+		stats.CJKChars += chapter.CJKChars
+		stats.LatinWords += chapter.LatinWords
+		stats.Chapters = append(stats.Chapters, chapter)
+	}
+	stats.TextStats = newTextStats(stats.CJKChars, stats.LatinWords)
+
+	for _, entry := range p.entries {
+		if entry.removed || entry.isDir {
+			continue
+		}
+		norm := normalizeZipPath(entry.header.Name)
+		mediaType := mediaTypes[norm]
+		size := int64(len(entry.data))
+		switch {
+		case isImageFile(norm, mediaType):
+			stats.Images++
+			stats.ImageBytes += size
+		case mediaType == "text/css" || strings.EqualFold(path.Ext(norm), ".css"):
+			stats.Stylesheets++
+			stats.StylesheetBytes += size
+		case isFontFile(norm, mediaType):
+			stats.Fonts++
+			stats.FontBytes += size
+		}
+
+		compressed := entryCompressedSize(entry, norm)
+		stats.Size += size
+		stats.CompressedSize += compressed
+		stats.Entries = append(stats.Entries, EntryStats{
+			Name:           entry.header.Name,
+			MediaType:      mediaType,
+			Size:           size,
+			CompressedSize: compressed,
+		})
+	}
+	return stats
+}
+
+// CountText 统计文本中的 CJK 字符数与拉丁词数并估算阅读时间
+func CountText(text string) TextStats {
+	cjk, words := 0, 0
+	// 拉丁词为以空白（或 CJK 字符）分隔、且含字母或数字的片段，因此 don't、e-mail、3.14 都是一个词
+	alnum := false
+	endWord := func() {
+		if alnum {
+			words++
+		}
+		alnum = false
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			cjk++
+			endWord()
+		case unicode.IsSpace(r):
+			endWord()
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			alnum = true
+		}
+	}
+	endWord()
+	return newTextStats(cjk, words)
+}
+
+func newTextStats(cjk, words int) TextStats {
+	return TextStats{
+		CJKChars:       cjk,
+		LatinWords:     words,
+		Words:          cjk + words,
+		ReadingMinutes: float64(cjk)/CJKCharsPerMinute + float64(words)/LatinWordsPerMinute,
+	}
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// imageSources 返回文档中 <img src> 与 SVG <image href> 引用的图片
+func imageSources(doc *html.Node) []string {
+	var sources []string
+	walkElements(doc, func(n *html.Node) {
+		switch {
+		case n.DataAtom == atom.Img:
+			if src := attrValue(n, "src"); src != "" && !isExternalRef(src) {
+				sources = append(sources, src)
+			}
+		case n.DataAtom == atom.Image || n.Data == "image":
+			href := attrValue(n, "xlink:href")
+			if href == "" {
+				href = attrValue(n, "href")
+			}
+			if href != "" && !isExternalRef(href) {
+				sources = append(sources, href)
+			}
+		}
+	})
+	return sources
+}
+
+func isImageFile(name, mediaType string) bool {
+	if strings.HasPrefix(mediaType, "image/") {
+		return true
+	}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp", ".svg", ".bmp":
+		return true
+	}
+	return false
+}
+
+func isFontFile(name, mediaType string) bool {
+	if strings.HasPrefix(mediaType, "font/") || strings.HasPrefix(mediaType, "application/font-") ||
+		strings.HasPrefix(mediaType, "application/x-font-") || mediaType == "application/vnd.ms-opentype" {
+		return true
+	}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".ttf", ".otf", ".woff", ".woff2":
+		return true
+	}
+	return false
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.n += int64(len(b))
+	return len(b), nil
+}
+
+// entryCompressedSize 返回条目在 zip 中的压缩大小：从 zip 读入且内容未变（长度与 CRC-32 都与头中一致）的条目
+// 直接使用头中记录的大小（含不压缩存储的条目），新增或修改过的条目按保存时的压缩方式估算
+func entryCompressedSize(entry *zipEntry, norm string) int64 {
+	size := int64(len(entry.data))
+	if h := &entry.header; h.CompressedSize64 > 0 && h.UncompressedSize64 == uint64(size) && h.CRC32 == crc32.ChecksumIEEE(entry.data) {
+		return int64(h.CompressedSize64)
+	}
+	if norm == "mimetype" || size == 0 {
+		return size
+	}
+	return deflatedSize(entry.data)
+}
+
+// deflatedSize 返回数据按默认级别 deflate 压缩后的大小
+func deflatedSize(data []byte) int64 {
+	counter := &countingWriter{}
+	w, err := flate.NewWriter(counter, flate.DefaultCompression)
+	if err != nil {
+		return int64(len(data))
+	}
+	_, _ = w.Write(data)
+	_ = w.Close()
+	return counter.n
+}
@@ -0,0 +1,71 @@
+package epub
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCountText(t *testing.T) {
+	tests := []struct {
+		text       string
+		cjk, words int
+	}{
+		{"", 0, 0},
+		{"hello world", 0, 2},
+		{"don't", 0, 1},
+		{"e-mail", 0, 1},
+		{"3.14", 0, 1},
+		{"Hello, world!", 0, 2},
+		{"a — b", 0, 2},
+		{" -- ... ", 0, 0},
+		{"中文abc def", 2, 2},
+		{"第1章", 2, 1},
+		{"ひらがなカタカナ 한국어", 11, 0},
+	}
+	for _, tt := range tests {
+		got := CountText(tt.text)
+		if got.CJKChars != tt.cjk || got.LatinWords != tt.words || got.Words != tt.cjk+tt.words {
+			t.Errorf("CountText(%q) = %d CJK, %d words, want %d, %d", tt.text, got.CJKChars, got.LatinWords, tt.cjk, tt.words)
+		}
+	}
+}
+
+func TestStatsCompressedSize(t *testing.T) {
+	notes := testFile{"OEBPS/notes.txt", strings.Repeat("a", 4096)}
+	book, err := Open(writeTestEPUB(t, "", notes))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	entrySize := func(name string) EntryStats {
+		for _, entry := range book.Stats().Entries {
+			if entry.Name == name {
+				return entry
+			}
+		}
+		t.Fatalf("Stats() has no entry %s", name)
+		return EntryStats{}
+	}
+
+	before := entrySize(notes.name)
+	if before.Size != 4096 || before.CompressedSize >= before.Size {
+		t.Fatalf("unmodified entry = %+v, want the deflated size from the zip header", before)
+	}
+	if mimetype := entrySize("mimetype"); mimetype.CompressedSize != mimetype.Size {
+		t.Errorf("stored mimetype = %+v, want compressed size equal to size", mimetype)
+	}
+
+	// 长度不变但内容改变的条目必须重新估算，而不是沿用头中的压缩大小
+	random := make([]byte, 4096)
+	seed := uint32(1)
+	for i := range random {
+		seed = seed*1664525 + 1013904223
+		random[i] = byte(seed >> 24)
+	}
+	if err := book.writeEntry(notes.name, random); err != nil {
+		t.Fatalf("writeEntry() error = %v", err)
+	}
+	after := entrySize(notes.name)
+	if after.Size != before.Size || after.CompressedSize <= before.CompressedSize*4 {
+		t.Errorf("modified entry = %+v, want it re-deflated (was %+v)", after, before)
+	}
+}
@@ -0,0 +1,143 @@
+package epub
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ChapterText 描述单个章节的纯文本内容
+type ChapterText struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+// ChapterTexts 按 spine 顺序提取所有 HTML 章节的纯文本
+func (p *Epub) ChapterTexts() []ChapterText {
+	entries := p.htmlEntriesInOrder()
+	texts := make([]ChapterText, 0, len(entries))
+	for _, entry := range entries {
+		doc, err := html.Parse(strings.NewReader(string(entry.data)))
+		if err != nil {
+			continue
+		}
+		texts = append(texts, ChapterText{
+			Name:  entry.header.Name,
+			Title: chapterTitle(doc),
+			Text:  nodeText(doc),
+		})
+	}
+	return texts
+}
+
+// HTMLToText 将 HTML 转换为纯文本，块级元素之间以换行分隔
+func HTMLToText(htmlContent string) string {
+	doc, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return ""
+	}
+	return nodeText(doc)
+}
+
+// nodeText 提取节点下的文本，忽略 head/script/style，块级元素换行
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(collapseSpace(n.Data))
+			return
+		case html.ElementNode:
+			switch n.DataAtom {
+			case atom.Head, atom.Script, atom.Style, atom.Title:
+				return
+			case atom.Br:
+				b.WriteString("\n")
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.Type == html.ElementNode && isBlockElement(n.DataAtom) {
+			b.WriteString("\n")
+		}
+	}
+	walk(n)
+
+	lines := strings.Split(b.String(), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// chapterTitle 优先使用第一个 h1-h3，其次使用 <title>
+func chapterTitle(doc *html.Node) string {
+	var heading, title string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if heading != "" {
+			return
+		}
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.H1, atom.H2, atom.H3:
+				heading = strings.Join(strings.Fields(nodeText(n)), " ")
+				return
+			case atom.Title:
+				if title == "" && n.FirstChild != nil {
+					title = strings.TrimSpace(n.FirstChild.Data)
+				}
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	if heading != "" {
+		return heading
+	}
+	return title
+}
+
+func collapseSpace(s string) string {
+	if strings.TrimSpace(s) == "" {
+		if s == "" {
+			return ""
+		}
+		return " "
+	}
+	fields := strings.Fields(s)
+	out := strings.Join(fields, " ")
+	if isSpaceByte(s[0]) {
+		out = " " + out
+	}
+	if isSpaceByte(s[len(s)-1]) {
+		out += " "
+	}
+	return out
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isBlockElement(a atom.Atom) bool {
+	switch a {
+	case atom.P, atom.Div, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Li, atom.Ul, atom.Ol, atom.Blockquote, atom.Pre, atom.Section, atom.Article,
+		atom.Aside, atom.Header, atom.Footer, atom.Nav, atom.Table, atom.Tr, atom.Td, atom.Th,
+		atom.Dt, atom.Dd, atom.Dl, atom.Figure, atom.Figcaption, atom.Hr, atom.Body:
+		return true
+	}
+	return false
+}
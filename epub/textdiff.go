@@ -0,0 +1,247 @@
+package epub
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 行级 diff 的编辑操作
+const (
+	diffEqual = iota
+	diffDelete
+	diffInsert
+)
+
+type diffOp struct {
+	kind int
+	a, b int // 在 a、b 中的行号（从 0 开始）
+}
+
+// diffLines 使用线性空间的 Myers 算法（middle snake 分治）计算从 a 到 b 的最短编辑序列，
+// 内存占用为 O(n+m)
+func diffLines(a, b []string) []diffOp {
+	d := &lineDiffer{a: a, b: b}
+	d.diff(0, len(a), 0, len(b))
+	return d.ops
+}
+
+type lineDiffer struct {
+	a, b []string
+	ops  []diffOp
+}
+
+// diff 计算 a[a0:a1] 到 b[b0:b1] 的编辑序列并按顺序追加到 ops
+func (d *lineDiffer) diff(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.ops = append(d.ops, diffOp{kind: diffEqual, a: a0, b: b0})
+		a0++
+		b0++
+	}
+	suffix := 0
+	for a1-suffix > a0 && b1-suffix > b0 && d.a[a1-suffix-1] == d.b[b1-suffix-1] {
+		suffix++
+	}
+	a1 -= suffix
+	b1 -= suffix
+
+	switch {
+	case a0 == a1:
+		d.insert(a0, b0, b1)
+	case b0 == b1:
+		d.delete(a0, a1, b0)
+	default:
+		x, y, ok := d.bisect(a0, a1, b0, b1)
+		if ok {
+			d.diff(a0, x, b0, y)
+			d.diff(x, a1, y, b1)
+		} else {
+			d.delete(a0, a1, b0)
+			d.insert(a1, b0, b1)
+		}
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.ops = append(d.ops, diffOp{kind: diffEqual, a: a1 + i, b: b1 + i})
+	}
+}
+
+func (d *lineDiffer) delete(a0, a1, b int) {
+	for x := a0; x < a1; x++ {
+		d.ops = append(d.ops, diffOp{kind: diffDelete, a: x, b: b})
+	}
+}
+
+func (d *lineDiffer) insert(a, b0, b1 int) {
+	for y := b0; y < b1; y++ {
+		d.ops = append(d.ops, diffOp{kind: diffInsert, a: a, b: y})
+	}
+}
+
+// bisect 同时从两端搜索最短编辑路径，在两条路径重叠处返回分割点（相对 a、b 的下标）；
+// 调用前需去掉公共前后缀且两段均非空，此时分割点严格位于两端之间
+func (d *lineDiffer) bisect(a0, a1, b0, b1 int) (int, int, bool) {
+	n, m := a1-a0, b1-b0
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	size := 2*maxD + 2
+	v1 := make([]int, size)
+	v2 := make([]int, size)
+	for i := range v1 {
+		v1[i], v2[i] = -1, -1
+	}
+	v1[offset+1], v2[offset+1] = 0, 0
+	delta := n - m
+	front := delta%2 != 0
+	k1start, k1end, k2start, k2end := 0, 0, 0, 0
+
+	for step := 0; step < maxD; step++ {
+		// 正向
+		for k1 := -step + k1start; k1 <= step-k1end; k1 += 2 {
+			i := offset + k1
+			var x1 int
+			if k1 == -step || (k1 != step && v1[i-1] < v1[i+1]) {
+				x1 = v1[i+1]
+			} else {
+				x1 = v1[i-1] + 1
+			}
+			y1 := x1 - k1
+			for x1 < n && y1 < m && d.a[a0+x1] == d.b[b0+y1] {
+				x1++
+				y1++
+			}
+			v1[i] = x1
+			switch {
+			case x1 > n:
+				k1end += 2
+			case y1 > m:
+				k1start += 2
+			case front:
+				if j := offset + delta - k1; j >= 0 && j < size && v2[j] != -1 {
+					if x1 >= n-v2[j] {
+						return d.split(a0, a1, b0, b1, x1, y1)
+					}
+				}
+			}
+		}
+		// 反向
+		for k2 := -step + k2start; k2 <= step-k2end; k2 += 2 {
+			i := offset + k2
+			var x2 int
+			if k2 == -step || (k2 != step && v2[i-1] < v2[i+1]) {
+				x2 = v2[i+1]
+			} else {
+				x2 = v2[i-1] + 1
+			}
+			y2 := x2 - k2
+			for x2 < n && y2 < m && d.a[a1-x2-1] == d.b[b1-y2-1] {
+				x2++
+				y2++
+			}
+			v2[i] = x2
+			switch {
+			case x2 > n:
+				k2end += 2
+			case y2 > m:
+				k2start += 2
+			case !front:
+				if j := offset + delta - k2; j >= 0 && j < size && v1[j] != -1 {
+					x1 := v1[j]
+					y1 := offset + x1 - j
+					if x1 >= n-x2 {
+						return d.split(a0, a1, b0, b1, x1, y1)
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// split 将 bisect 找到的相对分割点换算为绝对下标，分割点落在端点时视为失败，避免无限递归
+func (d *lineDiffer) split(a0, a1, b0, b1, x, y int) (int, int, bool) {
+	x, y = a0+x, b0+y
+	if (x == a0 && y == b0) || (x == a1 && y == b1) {
+		return 0, 0, false
+	}
+	return x, y, true
+}
+
+// UnifiedDiff 以 unified 格式输出两段文本的行级差异，context 为上下文行数；文本相同时返回空字符串
+func UnifiedDiff(nameA, nameB, textA, textB string, context int) string {
+	if textA == textB {
+		return ""
+	}
+	a, b := splitLines(textA), splitLines(textB)
+	ops := diffLines(a, b)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+	for start := 0; start < len(ops); {
+		// 找到下一处改动
+		for start < len(ops) && ops[start].kind == diffEqual {
+			start++
+		}
+		if start >= len(ops) {
+			break
+		}
+		// 合并间隔不超过 2*context 的改动为一个 hunk
+		end := start
+		for i := start; i < len(ops); i++ {
+			if ops[i].kind != diffEqual {
+				end = i
+				continue
+			}
+			if i-end > 2*context {
+				break
+			}
+		}
+		from := start - context
+		if from < 0 {
+			from = 0
+		}
+		to := end + context + 1
+		if to > len(ops) {
+			to = len(ops)
+		}
+
+		hunk := ops[from:to]
+		aStart, bStart := hunk[0].a, hunk[0].b
+		aCount, bCount := 0, 0
+		var body strings.Builder
+		for _, op := range hunk {
+			switch op.kind {
+			case diffEqual:
+				aCount++
+				bCount++
+				body.WriteString(" " + a[op.a] + "\n")
+			case diffDelete:
+				aCount++
+				body.WriteString("-" + a[op.a] + "\n")
+			case diffInsert:
+				bCount++
+				body.WriteString("+" + b[op.b] + "\n")
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		out.WriteString(body.String())
+		start = to
+	}
+	return out.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
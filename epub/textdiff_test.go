@@ -0,0 +1,90 @@
+package epub
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  string // 每个字符为一行
+		edits int    // 最短编辑序列中的插入与删除数
+	}{
+		{"both empty", "", "", 0},
+		{"identical", "abc", "abc", 0},
+		{"insert all", "", "abc", 3},
+		{"delete all", "abc", "", 3},
+		{"replace all", "abc", "xyz", 6},
+		{"insert middle", "ac", "abc", 1},
+		{"delete middle", "abc", "ac", 1},
+		{"myers paper", "abcabba", "cbabac", 5},
+		{"common prefix and suffix", "xxabyy", "xxbayy", 2},
+		{"repeated lines", "aaaa", "aa", 2},
+		{"swap blocks", "abcdef", "defabc", 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := strings.Split(tt.a, ""), strings.Split(tt.b, "")
+			ops := diffLines(a, b)
+
+			// 编辑序列必须按顺序覆盖 a 与 b 的每一行
+			x, y, edits := 0, 0, 0
+			for _, op := range ops {
+				if op.a != x || op.b != y {
+					t.Fatalf("op %+v out of order at a=%d b=%d", op, x, y)
+				}
+				switch op.kind {
+				case diffEqual:
+					if a[x] != b[y] {
+						t.Fatalf("equal op on different lines %q and %q", a[x], b[y])
+					}
+					x++
+					y++
+				case diffDelete:
+					x++
+					edits++
+				case diffInsert:
+					y++
+					edits++
+				}
+			}
+			if x != len(a) || y != len(b) {
+				t.Fatalf("ops end at a=%d b=%d, want a=%d b=%d", x, y, len(a), len(b))
+			}
+			if edits != tt.edits {
+				t.Errorf("diffLines() has %d edits, want %d", edits, tt.edits)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string
+	}{
+		{"identical", "a\nb\n", "a\nb\n", 3, ""},
+		{
+			name: "change in middle", a: "1\n2\n3\n4\n5\n", b: "1\n2\nx\n4\n5\n", context: 1,
+			want: "--- a\n+++ b\n@@ -2,3 +2,3 @@\n 2\n-3\n+x\n 4\n",
+		},
+		{
+			name: "separate hunks", a: "1\n2\n3\n4\n5\n6\n7\n", b: "x\n2\n3\n4\n5\n6\ny\n", context: 1,
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n-1\n+x\n 2\n@@ -6,2 +6,2 @@\n 6\n-7\n+y\n",
+		},
+		{
+			name: "insert into empty", a: "", b: "a\n", context: 3,
+			want: "--- a\n+++ b\n@@ -0,0 +1 @@\n+a\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff("a", "b", tt.a, tt.b, tt.context); got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
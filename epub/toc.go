@@ -0,0 +1,519 @@
+package epub
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// TOCEntry 为目录中的一项，Href 为 ZIP 内路径，可带 #fragment
+type TOCEntry struct {
+	Title    string      `json:"title"`
+	Href     string      `json:"href"`
+	Level    int         `json:"level"`
+	Children []*TOCEntry `json:"children,omitempty"`
+}
+
+// TOCOptions 控制 GenerateTOC 识别标题的方式。
+// Selectors 与 Patterns 都为空时使用 h1-h6，层级取标签数字
+type TOCOptions struct {
+	// Selectors 为标题元素选择器，第 i 个选择器匹配的元素层级为 i+1
+	Selectors []string
+	// Patterns 为标题文本正则（如 `^第[一二三四五六七八九十百千0-9]+章`），第 i 个正则的层级为 i+1；
+	// 只检查标题与不含块级子元素的 p/div，且文本不超过 MaxTitleLength
+	Patterns []string
+	// MaxTitleLength 为 Patterns 匹配的最大字数，默认 50
+	MaxTitleLength int
+	// MaxDepth 为目录的最大层级，为 0 时不限制
+	MaxDepth int
+	// Title 为新建导航文档时使用的标题，默认 "Contents"
+	Title string
+}
+
+type tocRule struct {
+	selector selector
+	pattern  *regexp.Regexp
+	level    int
+}
+
+// GenerateTOC 按 spine 顺序从章节标题生成嵌套目录，为缺少 id 的标题补上 heading-N 锚点，
+// 并写入导航文档（toc nav）与 NCX；EPUB3 缺少导航文档或书中缺少 NCX 时会新建。
+// 没有识别到标题的章节以章节标题作为一级目录
+func (p *Epub) GenerateTOC(opts TOCOptions) ([]*TOCEntry, error) {
+	var rules []tocRule
+	for i, s := range opts.Selectors {
+		sel, err := parseSelector(s)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, tocRule{selector: sel, level: i + 1})
+	}
+	for i, pattern := range opts.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid heading pattern %q: %w", pattern, err)
+		}
+		rules = append(rules, tocRule{pattern: re, level: i + 1})
+	}
+	maxTitle := opts.MaxTitleLength
+	if maxTitle <= 0 {
+		maxTitle = 50
+	}
+
+	var flat []*TOCEntry
+	navPath := p.navPath()
+	for _, norm := range p.SpinePaths() {
+		entry, ok := p.entryIndex[norm]
+		if !ok || entry.removed || norm == navPath || !isHTMLEntry(entry) {
+			continue
+		}
+		doc, err := html.Parse(strings.NewReader(string(entry.data)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse HTML (%s): %w", entry.header.Name, err)
+		}
+
+		ids := make(map[string]*html.Node)
+		walkElements(doc, func(n *html.Node) {
+			if id := attrValue(n, "id"); id != "" {
+				ids[id] = n
+			}
+		})
+		changed := false
+		found := 0
+		walkElements(doc, func(n *html.Node) {
+			level := headingLevel(n, rules, maxTitle)
+			if level == 0 || (opts.MaxDepth > 0 && level > opts.MaxDepth) {
+				return
+			}
+			title := strings.Join(strings.Fields(nodeText(n)), " ")
+			if title == "" {
+				return
+			}
+			id := attrValue(n, "id")
+			if id == "" {
+				id = uniqueID(ids, "heading-"+strconv.Itoa(found+1))
+				setAttr(n, "id", id)
+				ids[id] = n
+				changed = true
+			}
+			found++
+			flat = append(flat, &TOCEntry{Title: title, Href: norm + "#" + id, Level: level})
+		})
+
+		if found == 0 {
+			if title := chapterTitle(doc); title != "" {
+				flat = append(flat, &TOCEntry{Title: title, Href: norm, Level: 1})
+			}
+			continue
+		}
+		if changed {
+			rendered, err := renderXHTMLDocument(doc, p.defaultDoctype())
+			if err != nil {
+				return nil, fmt.Errorf("failed to render HTML (%s): %w", entry.header.Name, err)
+			}
+			entry.data = []byte(rendered)
+		}
+	}
+
+	toc := nestTOC(flat)
+	if err := p.setTOC(toc, opts.Title); err != nil {
+		return nil, err
+	}
+	return toc, nil
+}
+
+// SetTOC 用给定的目录替换导航文档与 NCX 中的目录，缺少时会新建
+func (p *Epub) SetTOC(entries []*TOCEntry) error {
+	return p.setTOC(entries, "")
+}
+
+// TOC 读取书中现有的目录：优先使用导航文档的 toc nav，其次使用 NCX 的 navMap；没有目录时返回 nil
+func (p *Epub) TOC() ([]*TOCEntry, error) {
+	if navPath := p.navPath(); navPath != "" {
+		doc, err := p.parseHTMLEntry(navPath)
+		if err != nil {
+			return nil, err
+		}
+		if nav := findNav(doc, "toc"); nav != nil {
+			if ol := findElement(nav, atom.Ol); ol != nil {
+				if entries := readNavTOC(navPath, ol, 1); len(entries) > 0 {
+					return entries, nil
+				}
+			}
+		}
+	}
+	if ncxPath := p.ncxPath(); ncxPath != "" {
+		tree, err := p.parseXMLEntry(ncxPath)
+		if err != nil {
+			return nil, err
+		}
+		if navMap := tree.root().child("navMap"); navMap != nil {
+			return readNCXTOC(ncxPath, navMap, 1), nil
+		}
+	}
+	return nil, nil
+}
+
+// readNavTOC 读取 ol 下的 li，li 中的 a（或无链接的 span）为标题，嵌套的 ol 为子目录
+func readNavTOC(navPath string, ol *html.Node, level int) []*TOCEntry {
+	var entries []*TOCEntry
+	for li := ol.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.DataAtom != atom.Li {
+			continue
+		}
+		entry := &TOCEntry{Level: level}
+		for c := li.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.DataAtom {
+			case atom.A, atom.Span:
+				if entry.Title == "" {
+					entry.Title = strings.Join(strings.Fields(nodeText(c)), " ")
+					if href := attrValue(c, "href"); href != "" && !isExternalRef(href) {
+						entry.Href = resolveHref(navPath, href) + fragmentOf(href)
+					}
+				}
+			case atom.Ol:
+				entry.Children = append(entry.Children, readNavTOC(navPath, c, level+1)...)
+			}
+		}
+		if entry.Title != "" || len(entry.Children) > 0 {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// readNCXTOC 读取 navPoint，navLabel/text 为标题，content 的 src 为链接
+func readNCXTOC(ncxPath string, parent *xmlNode, level int) []*TOCEntry {
+	var entries []*TOCEntry
+	for _, c := range parent.children {
+		if c.kind != xmlElementNode || c.localName() != "navPoint" {
+			continue
+		}
+		entry := &TOCEntry{Level: level}
+		if label := c.child("navLabel"); label != nil {
+			if text := label.child("text"); text != nil {
+				entry.Title = strings.Join(strings.Fields(text.text()), " ")
+			}
+		}
+		if content := c.child("content"); content != nil {
+			if src, _ := content.attr("src"); src != "" && !isExternalRef(src) {
+				entry.Href = resolveHref(ncxPath, src) + fragmentOf(src)
+			}
+		}
+		entry.Children = readNCXTOC(ncxPath, c, level+1)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func (p *Epub) setTOC(entries []*TOCEntry, title string) error {
+	if title == "" {
+		title = "Contents"
+	}
+	for _, entry := range flattenTOC(entries) {
+		if entry.Title == "" || entry.Href == "" {
+			return fmt.Errorf("toc title and href cannot be empty")
+		}
+	}
+
+	navPath := p.navPath()
+	if navPath == "" && strings.HasPrefix(p.opfDoc.Version, "3") {
+		created, err := p.createNav(title)
+		if err != nil {
+			return err
+		}
+		navPath = created
+	}
+	if navPath != "" {
+		if err := p.writeNavTOC(navPath, entries, title); err != nil {
+			return err
+		}
+	}
+
+	ncxPath := p.ncxPath()
+	if ncxPath == "" {
+		created, err := p.createNCX()
+		if err != nil {
+			return err
+		}
+		ncxPath = created
+	}
+	return p.writeNCXTOC(ncxPath, entries)
+}
+
+// headingLevel 返回元素作为标题的层级，不是标题时返回 0
+func headingLevel(n *html.Node, rules []tocRule, maxTitle int) int {
+	if len(rules) == 0 {
+		switch n.DataAtom {
+		case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+			return int(n.Data[1] - '0')
+		}
+		return 0
+	}
+	for _, rule := range rules {
+		if rule.selector != nil {
+			if rule.selector.match(n) {
+				return rule.level
+			}
+			continue
+		}
+		switch n.DataAtom {
+		case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		default:
+			if !isNoteCandidate(n) || n.DataAtom == atom.Li || n.DataAtom == atom.Dd || n.DataAtom == atom.Blockquote {
+				continue
+			}
+		}
+		text := strings.TrimSpace(strings.Join(strings.Fields(nodeText(n)), " "))
+		if text != "" && utf8.RuneCountInString(text) <= maxTitle && rule.pattern.MatchString(text) {
+			return rule.level
+		}
+	}
+	return 0
+}
+
+// nestTOC 按层级把平铺的标题组织成树，层级跳跃时挂到最近的上级；
+// 组织后 Level 改为条目在树中的深度，与 TOC 读回的结果一致
+func nestTOC(flat []*TOCEntry) []*TOCEntry {
+	var roots []*TOCEntry
+	var stack []*TOCEntry
+	var levels []int // stack 中条目的原始层级
+	for _, entry := range flat {
+		for len(levels) > 0 && levels[len(levels)-1] >= entry.Level {
+			stack, levels = stack[:len(stack)-1], levels[:len(levels)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, entry)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, entry)
+		}
+		levels = append(levels, entry.Level)
+		stack = append(stack, entry)
+		entry.Level = len(stack)
+	}
+	return roots
+}
+
+func flattenTOC(entries []*TOCEntry) []*TOCEntry {
+	var flat []*TOCEntry
+	for _, entry := range entries {
+		flat = append(flat, entry)
+		flat = append(flat, flattenTOC(entry.Children)...)
+	}
+	return flat
+}
+
+func tocDepth(entries []*TOCEntry) int {
+	depth := 0
+	for _, entry := range entries {
+		if d := 1 + tocDepth(entry.Children); d > depth {
+			depth = d
+		}
+	}
+	return depth
+}
+
+// writeNavTOC 替换导航文档中 toc nav 的内容
+func (p *Epub) writeNavTOC(navPath string, entries []*TOCEntry, title string) error {
+	doc, err := p.parseHTMLEntry(navPath)
+	if err != nil {
+		return err
+	}
+	nav := findNav(doc, "toc")
+	if nav == nil {
+		body := findElement(doc, atom.Body)
+		if body == nil {
+			return fmt.Errorf("body not found in navigation document %s", navPath)
+		}
+		nav = newElement(atom.Nav, "epub:type", "toc", "id", "toc")
+		h1 := newElement(atom.H1)
+		h1.AppendChild(&html.Node{Type: html.TextNode, Data: title})
+		nav.AppendChild(h1)
+		body.InsertBefore(nav, body.FirstChild)
+	}
+	if ol := findElement(nav, atom.Ol); ol != nil {
+		nav.RemoveChild(ol)
+	}
+	nav.AppendChild(navTOCList(navPath, entries))
+
+	rendered, err := renderXHTMLDocument(doc, p.defaultDoctype())
+	if err != nil {
+		return err
+	}
+	return p.writeEntry(navPath, []byte(rendered))
+}
+
+func navTOCList(navPath string, entries []*TOCEntry) *html.Node {
+	ol := newElement(atom.Ol)
+	for _, entry := range entries {
+		target, fragment := splitFragment(entry.Href)
+		a := newElement(atom.A, "href", relativeRef(navPath, normalizeZipPath(target))+fragment)
+		a.AppendChild(&html.Node{Type: html.TextNode, Data: entry.Title})
+		li := newElement(atom.Li)
+		li.AppendChild(a)
+		if len(entry.Children) > 0 {
+			li.AppendChild(navTOCList(navPath, entry.Children))
+		}
+		ol.AppendChild(li)
+	}
+	return ol
+}
+
+// writeNCXTOC 替换 NCX navMap 中的 navPoint，并重新编号 playOrder 与更新 dtb:depth
+func (p *Epub) writeNCXTOC(ncxPath string, entries []*TOCEntry) error {
+	tree, err := p.parseXMLEntry(ncxPath)
+	if err != nil {
+		return err
+	}
+	root := tree.root()
+	prefix := prefixOf(root.name)
+	navMap := root.child("navMap")
+	if navMap == nil {
+		navMap = &xmlNode{kind: xmlElementNode, name: prefix + "navMap"}
+		root.syncElementChildren("navMap", []*xmlNode{navMap})
+	}
+
+	// 沿用 navMap 的缩进风格，原文件没有换行缩进时生成紧凑的结构
+	indent := ""
+	for i, c := range root.children {
+		if c == navMap {
+			indent = root.indentBefore(i)
+		}
+	}
+	if k := strings.LastIndexByte(indent, '\n'); k >= 0 {
+		indent = indent[k:] + "  "
+	}
+
+	playOrder := 0
+	var build func(entries []*TOCEntry, indent string) []*xmlNode
+	build = func(entries []*TOCEntry, indent string) []*xmlNode {
+		childIndent := ""
+		if indent != "" {
+			childIndent = indent + "  "
+		}
+		var nodes []*xmlNode
+		for _, entry := range entries {
+			playOrder++
+			target, fragment := splitFragment(entry.Href)
+			node := &xmlNode{kind: xmlElementNode, name: prefix + "navPoint"}
+			node.setAttr("id", "navPoint-"+strconv.Itoa(playOrder))
+			node.setAttr("playOrder", strconv.Itoa(playOrder))
+			content := &xmlNode{kind: xmlElementNode, name: prefix + "content"}
+			content.setAttr("src", relativeRef(ncxPath, normalizeZipPath(target))+fragment)
+
+			children := []*xmlNode{ncxLabel(prefix, entry.Title), content}
+			children = append(children, build(entry.Children, childIndent)...)
+			for _, child := range children {
+				if childIndent != "" {
+					node.children = append(node.children, &xmlNode{kind: xmlTextNode, data: childIndent})
+				}
+				node.children = append(node.children, child)
+			}
+			if indent != "" {
+				node.children = append(node.children, &xmlNode{kind: xmlTextNode, data: indent})
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+	// 保留 navInfo、navLabel 等非 navPoint 元素，按同样的缩进重排 navMap 的子节点
+	var children []*xmlNode
+	for _, c := range navMap.children {
+		if c.kind == xmlTextNode && strings.TrimSpace(c.data) == "" || c.kind == xmlElementNode && c.localName() == "navPoint" {
+			continue
+		}
+		children = append(children, c)
+	}
+	navMap.children = nil
+	for _, c := range append(children, build(entries, indent)...) {
+		if indent != "" {
+			navMap.children = append(navMap.children, &xmlNode{kind: xmlTextNode, data: indent})
+		}
+		navMap.children = append(navMap.children, c)
+	}
+	if indent != "" {
+		navMap.children = append(navMap.children, &xmlNode{kind: xmlTextNode, data: strings.TrimSuffix(indent, "  ")})
+	}
+
+	// page-list 的 playOrder 排在目录之后
+	if pageList := root.child("pageList"); pageList != nil {
+		for _, c := range pageList.children {
+			if c.kind == xmlElementNode && c.localName() == "pageTarget" {
+				playOrder++
+				c.setAttr("playOrder", strconv.Itoa(playOrder))
+			}
+		}
+	}
+	if head := root.child("head"); head != nil {
+		for _, c := range head.children {
+			if name, _ := c.attr("name"); c.kind == xmlElementNode && name == "dtb:depth" {
+				c.setAttr("content", strconv.Itoa(max(1, tocDepth(entries))))
+			}
+		}
+	}
+	return p.writeEntry(ncxPath, tree.bytes())
+}
+
+// createNav 新建 EPUB3 导航文档，返回其路径
+func (p *Epub) createNav(title string) (string, error) {
+	norm := p.uniquePath(path.Join(p.opfDir, "nav.xhtml"))
+	doc := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<!DOCTYPE html>
+<html xmlns="%s" xmlns:epub="%s"><head><title>%s</title></head><body></body></html>`,
+		xhtmlNamespace, opsNamespace, html.EscapeString(title))
+	if _, err := p.addResource(norm, "application/xhtml+xml", "nav", []byte(doc)); err != nil {
+		return "", err
+	}
+	return norm, nil
+}
+
+// createNCX 新建 NCX 并设置为 spine 的 toc，返回其路径
+func (p *Epub) createNCX() (string, error) {
+	norm := p.uniquePath(path.Join(p.opfDir, "toc.ncx"))
+	info := p.Info()
+	doc := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+  <head>
+    <meta name="dtb:uid" content="%s"/>
+    <meta name="dtb:depth" content="1"/>
+    <meta name="dtb:totalPageCount" content="0"/>
+    <meta name="dtb:maxPageNumber" content="0"/>
+  </head>
+  <docTitle>
+    <text>%s</text>
+  </docTitle>
+  <navMap>
+  </navMap>
+</ncx>
+`, html.EscapeString(info.Identifier), html.EscapeString(info.Title))
+	id, err := p.addResource(norm, "application/x-dtbncx+xml", "", []byte(doc))
+	if err != nil {
+		return "", err
+	}
+	p.opfDoc.Spine.Toc = id
+	return norm, nil
+}
+
+// uniquePath 在路径已被占用时追加数字后缀
+func (p *Epub) uniquePath(norm string) string {
+	norm = normalizeZipPath(norm)
+	ext := path.Ext(norm)
+	base := strings.TrimSuffix(norm, ext)
+	candidate := norm
+	for i := 2; ; i++ {
+		if entry, ok := p.entryIndex[candidate]; !ok || entry.removed {
+			return candidate
+		}
+		candidate = base + "-" + strconv.Itoa(i) + ext
+	}
+}
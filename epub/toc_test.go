@@ -0,0 +1,102 @@
+package epub
+
+import (
+	"strings"
+	"testing"
+)
+
+// chapterWithBody 返回 body 为 body 的章节
+func chapterWithBody(body string) []byte {
+	return []byte(strings.Replace(testChapter, "<p>Hello</p>", body, 1))
+}
+
+func TestGenerateTOC(t *testing.T) {
+	tests := []struct {
+		name     string
+		chapters []string
+		opts     TOCOptions
+		want     string
+	}{
+		{
+			name:     "headings",
+			chapters: []string{`<h1>Part</h1><h2 id="keep">A</h2><h3>A.1</h3><h2>B</h2><h4>Deep</h4>`, `<p>no headings</p>`},
+			want:     "Part(1)[A(2)[A.1(3)] B(2)[Deep(3)]] Chapter(1)",
+		},
+		{
+			name:     "max depth",
+			chapters: []string{`<h1>Part</h1><h2>A</h2>`, `<h1>Next</h1>`},
+			opts:     TOCOptions{MaxDepth: 1},
+			want:     "Part(1) Next(1)",
+		},
+		{
+			name:     "selectors",
+			chapters: []string{`<p class="vol">Volume</p><p class="ch">One</p><h1>ignored</h1>`, `<p class="ch">Two</p>`},
+			opts:     TOCOptions{Selectors: []string{"p.vol", "p.ch"}},
+			want:     "Volume(1)[One(2) Two(2)]",
+		},
+		{
+			name: "patterns",
+			chapters: []string{`<p>第一卷 风起</p><div>第一章 开始</div><p>正文提到第二章的内容，但太长了` + strings.Repeat("字", 60) + `</p>`,
+				`<h2>第二章 继续</h2><div><p>第三章 嵌套</p></div>`},
+			opts: TOCOptions{Patterns: []string{`^第.+卷`, `^第.+章`}},
+			want: "第一卷 风起(1)[第一章 开始(2) 第二章 继续(2) 第三章 嵌套(2)]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := openChapters(t, len(tt.chapters))
+			for i, body := range tt.chapters {
+				if err := book.writeEntry(book.SpinePaths()[i], chapterWithBody(body)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			entries, err := book.GenerateTOC(tt.opts)
+			if err != nil {
+				t.Fatalf("GenerateTOC() error = %v", err)
+			}
+			if got := formatTOC(entries); got != tt.want {
+				t.Errorf("GenerateTOC() = %s, want %s", got, tt.want)
+			}
+			if book.navPath() == "" || book.ncxPath() == "" {
+				t.Errorf("GenerateTOC() did not create the nav document and NCX")
+			}
+			toc, err := book.TOC()
+			if err != nil || formatTOC(toc) != tt.want {
+				t.Errorf("TOC() = %s, %v, want %s", formatTOC(toc), err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateTOCAnchors(t *testing.T) {
+	book := openChapters(t, 1)
+	if err := book.writeEntry("OEBPS/Text/ch1.xhtml", chapterWithBody(`<h1>One</h1><h2 id="keep">Two</h2><p id="heading-3">x</p><h2>Three</h2>`)); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := book.GenerateTOC(TOCOptions{})
+	if err != nil {
+		t.Fatalf("GenerateTOC() error = %v", err)
+	}
+	var hrefs []string
+	for _, e := range append([]*TOCEntry{entries[0]}, entries[0].Children...) {
+		hrefs = append(hrefs, e.Href)
+	}
+	want := "OEBPS/Text/ch1.xhtml#heading-1 OEBPS/Text/ch1.xhtml#keep OEBPS/Text/ch1.xhtml#heading-3-2"
+	if got := strings.Join(hrefs, " "); got != want {
+		t.Errorf("TOC hrefs = %s, want %s", got, want)
+	}
+	data, _ := book.ReadFile("OEBPS/Text/ch1.xhtml")
+	for _, w := range []string{`<h1 id="heading-1">One</h1>`, `<h2 id="keep">Two</h2>`, `<h2 id="heading-3-2">Three</h2>`} {
+		if !strings.Contains(string(data), w) {
+			t.Errorf("chapter missing %q:\n%s", w, data)
+		}
+	}
+
+	// 再次生成时复用已有的锚点，章节内容不变
+	if _, err := book.GenerateTOC(TOCOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := book.ReadFile("OEBPS/Text/ch1.xhtml"); string(again) != string(data) {
+		t.Errorf("second GenerateTOC() rewrote the chapter:\n%s", again)
+	}
+}
@@ -0,0 +1,228 @@
+package epub
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// HeadingPattern 为 TXT 导入时识别章节标题的正则及其层级（1 为最高层，超过 3 的按 3 处理）
+type HeadingPattern struct {
+	Pattern string
+	Level   int
+}
+
+const cnNumber = `[零〇一二两三四五六七八九十百千万0-9０-９]+`
+
+// DefaultTextHeadings 为默认的章节标题规则：卷/部/集、章/回、节，以及 Chapter N 等常见写法
+var DefaultTextHeadings = []HeadingPattern{
+	{Pattern: `^第` + cnNumber + `[卷部集](?:[\s:：·、].*)?$`, Level: 1},
+	{Pattern: `^卷` + cnNumber + `(?:[\s:：·、].*)?$`, Level: 1},
+	{Pattern: `^第` + cnNumber + `[章回](?:[\s:：·、].*)?$`, Level: 2},
+	{Pattern: `^(?i:chapter)\s+(?:[0-9]+|[ivxlcdm]+)\b.*$`, Level: 2},
+	{Pattern: `^(?:序章|序言|序|楔子|引子|前言|尾声|后记|番外)(?:[\s:：·、].*)?$`, Level: 2},
+	{Pattern: `^第` + cnNumber + `节(?:[\s:：·、].*)?$`, Level: 3},
+}
+
+// TextImportOptions 控制 TXT 导入
+type TextImportOptions struct {
+	Title    string // 书名，为空时从文件头或文件名解析
+	Author   string // 作者，为空时从文件头或文件名解析
+	Language string // 默认 zh-CN
+	Encoding string // 强制使用的编码，为空时自动检测
+
+	// Headings 为章节标题规则，为空时使用 DefaultTextHeadings
+	Headings []HeadingPattern
+	// MaxTitleLength 为标题行的最大字数，超过的行视为正文，默认 40
+	MaxTitleLength int
+	// PrefaceTitle 为第一个标题之前内容的章节名，默认 "前言"
+	PrefaceTitle string
+}
+
+var (
+	headerTitleRegex  = regexp.MustCompile(`^(?:书名|書名|标题|標題)\s*[:：]\s*(.+)$`)
+	headerAuthorRegex = regexp.MustCompile(`^(?:作者|作\s+者|著者)\s*[:：]\s*(.+)$`)
+	bracketTitleRegex = regexp.MustCompile(`^《(.+?)》`)
+	nameAuthorRegex   = regexp.MustCompile(`^(.+?)\s*(?:作者\s*[:：]|(?i:\s+by\s+))\s*(.+)$`)
+	nameSplitRegex    = regexp.MustCompile(`^(.+?)\s+[-–—]+\s+(.+)$`)
+)
+
+// ImportText 读取 TXT 小说并生成 EPUB，见 ImportTextData
+func ImportText(filePath string, opts TextImportOptions) (*Epub, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read text file: %w", err)
+	}
+	return ImportTextData(filepath.Base(filePath), data, opts)
+}
+
+// ImportTextData 将 TXT 内容转换为 EPUB：检测编码并转为 UTF-8，按标题规则切分章节，
+// 每行作为一个段落，并生成目录。书名与作者优先取 opts，其次取文件头的"书名：/作者："行，最后从文件名解析
+func ImportTextData(name string, data []byte, opts TextImportOptions) (*Epub, error) {
+	encoding := opts.Encoding
+	if encoding == "" {
+		encoding, _ = DetectEncoding(data)
+	}
+	canonical := canonicalEncoding(encoding)
+	if canonical == "" {
+		return nil, fmt.Errorf("unsupported encoding: %q", encoding)
+	}
+	utf8Data, err := toUTF8(data, canonical)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.ReplaceAll(string(utf8Data), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	headings := opts.Headings
+	if len(headings) == 0 {
+		headings = DefaultTextHeadings
+	}
+	type compiledHeading struct {
+		re    *regexp.Regexp
+		level int
+	}
+	var rules []compiledHeading
+	for _, h := range headings {
+		re, err := regexp.Compile(h.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid heading pattern %q: %w", h.Pattern, err)
+		}
+		// 章节标题只生成 h1-h3，目录层级与之一致
+		level := min(max(h.Level, 1), 3)
+		rules = append(rules, compiledHeading{re: re, level: level})
+	}
+	maxTitle := opts.MaxTitleLength
+	if maxTitle <= 0 {
+		maxTitle = 40
+	}
+
+	type section struct {
+		title string
+		level int
+		lines []string
+	}
+	var sections []*section
+	preface := &section{}
+	current := preface
+	title, author := opts.Title, opts.Author
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(line, "\u3000\ufeff"))
+		if line == "" {
+			continue
+		}
+		if current == preface && len(preface.lines) < 30 {
+			if m := headerTitleRegex.FindStringSubmatch(line); m != nil {
+				if title == "" {
+					title = strings.Trim(strings.TrimSpace(m[1]), "《》")
+				}
+				continue
+			}
+			if m := headerAuthorRegex.FindStringSubmatch(line); m != nil {
+				if author == "" {
+					author = strings.TrimSpace(m[1])
+				}
+				continue
+			}
+		}
+		level := 0
+		if utf8.RuneCountInString(line) <= maxTitle {
+			for _, rule := range rules {
+				if rule.re.MatchString(line) {
+					level = rule.level
+					break
+				}
+			}
+		}
+		if level > 0 {
+			current = &section{title: strings.Join(strings.Fields(line), " "), level: level}
+			sections = append(sections, current)
+			continue
+		}
+		current.lines = append(current.lines, line)
+	}
+
+	// 文件头中单独一行的《书名》
+	if title == "" && len(preface.lines) > 0 {
+		if m := bracketTitleRegex.FindStringSubmatch(preface.lines[0]); m != nil && strings.TrimSpace(preface.lines[0]) == m[0] {
+			title = m[1]
+			preface.lines = preface.lines[1:]
+		}
+	}
+	nameTitle, nameAuthor := parseBookFileName(name)
+	if title == "" {
+		title = nameTitle
+	}
+	if author == "" {
+		author = nameAuthor
+	}
+
+	book, err := New(BookMetadata{Title: title, Author: author, Language: opts.Language})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := book.addResource(newBookDir+"/Styles/style.css", "text/css", "", []byte(defaultImportCSS)); err != nil {
+		return nil, err
+	}
+
+	if len(preface.lines) > 0 {
+		prefaceTitle := opts.PrefaceTitle
+		if prefaceTitle == "" {
+			prefaceTitle = "前言"
+		}
+		if len(sections) == 0 {
+			prefaceTitle = title
+		}
+		preface.title, preface.level = prefaceTitle, 1
+		sections = append([]*section{preface}, sections...)
+	}
+
+	var flat []*TOCEntry
+	for i, sec := range sections {
+		var body strings.Builder
+		fmt.Fprintf(&body, "<h%d>%s</h%d>", sec.level, html.EscapeString(sec.title), sec.level)
+		for _, line := range sec.lines {
+			body.WriteString("<p>")
+			body.WriteString(html.EscapeString(line))
+			body.WriteString("</p>")
+		}
+		norm := chapterPath(i + 1)
+		err := book.AddChapterWithOptions(norm, chapterDocument(sec.title, body.String()), ChapterOptions{SpineIndex: -1, Title: sec.title})
+		if err != nil {
+			return nil, err
+		}
+		flat = append(flat, &TOCEntry{Title: sec.title, Href: norm, Level: sec.level})
+	}
+	if err := book.setTOC(nestTOC(flat), "目录"); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// parseBookFileName 从 "《书名》作者：xxx.txt"、"书名 作者：xxx.txt"、"书名 - 作者.txt" 等文件名中解析书名与作者，
+// 连字符两侧没有空格时（如 "three-body.txt"、"my_novel.txt"）整个文件名都是书名
+func parseBookFileName(name string) (string, string) {
+	base := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	base = strings.TrimSpace(base)
+	if m := bracketTitleRegex.FindStringSubmatch(base); m != nil {
+		rest := strings.TrimSpace(base[len(m[0]):])
+		if am := headerAuthorRegex.FindStringSubmatch(rest); am != nil {
+			return m[1], strings.TrimSpace(am[1])
+		}
+		return m[1], strings.Trim(rest, " _-—()（）")
+	}
+	if m := nameAuthorRegex.FindStringSubmatch(base); m != nil {
+		return m[1], m[2]
+	}
+	if m := nameSplitRegex.FindStringSubmatch(base); m != nil {
+		return m[1], m[2]
+	}
+	if base == "" {
+		base = "Untitled"
+	}
+	return base, ""
+}
@@ -0,0 +1,118 @@
+package epub
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseBookFileName(t *testing.T) {
+	tests := []struct {
+		name, title, author string
+	}{
+		{"three-body.txt", "three-body", ""},
+		{"my_novel.txt", "my_novel", ""},
+		{"dir/Some Book.txt", "Some Book", ""},
+		{"三体 - 刘慈欣.txt", "三体", "刘慈欣"},
+		{"三体 — 刘慈欣.txt", "三体", "刘慈欣"},
+		{"三体 作者：刘慈欣.txt", "三体", "刘慈欣"},
+		{"The Book by Some One.txt", "The Book", "Some One"},
+		{"《三体》作者：刘慈欣.txt", "三体", "刘慈欣"},
+		{"《三体》_刘慈欣.txt", "三体", "刘慈欣"},
+		{"《三-体》.txt", "三-体", ""},
+		{".txt", "Untitled", ""},
+	}
+	for _, tt := range tests {
+		title, author := parseBookFileName(tt.name)
+		if title != tt.title || author != tt.author {
+			t.Errorf("parseBookFileName(%q) = %q, %q, want %q, %q", tt.name, title, author, tt.title, tt.author)
+		}
+	}
+}
+
+func TestImportTextData(t *testing.T) {
+	text := strings.Join([]string{
+		"书名：《测试之书》",
+		"作者：张三",
+		"",
+		"　　这是前言。",
+		"第一卷 起始",
+		"第一章 开端",
+		"　　第一章的正文。",
+		"第二章",
+		"第二章的正文。",
+		"第1节 小节",
+		"第三章 " + strings.Repeat("长", 50),
+		"Chapter 3 The End",
+		"The end.",
+	}, "\r\n")
+	book, err := ImportTextData("ignored - name.txt", []byte(text), TextImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportTextData() error = %v", err)
+	}
+	info := book.Info()
+	if info.Title != "测试之书" || len(info.Creators) != 1 || info.Creators[0] != "张三" {
+		t.Errorf("Info() title = %q, creators = %q, want header title and author", info.Title, info.Creators)
+	}
+
+	toc, err := book.TOC()
+	if err != nil {
+		t.Fatalf("TOC() error = %v", err)
+	}
+	want := "前言(1) 第一卷 起始(1)[第一章 开端(2) 第二章(2)[第1节 小节(3)] Chapter 3 The End(2)]"
+	if got := formatTOC(toc); got != want {
+		t.Errorf("TOC() = %s, want %s", got, want)
+	}
+
+	spine := book.SpinePaths()
+	if len(spine) != 6 {
+		t.Fatalf("SpinePaths() = %q, want 6 chapters", spine)
+	}
+	checks := map[int][]string{
+		0: {"<h1>前言</h1>", "<p>这是前言。</p>"},
+		2: {"<h2>第一章 开端</h2>", "<p>第一章的正文。</p>"},
+		4: {"<h3>第1节 小节</h3>", "<p>第三章 " + strings.Repeat("长", 50) + "</p>"},
+		5: {"<h2>Chapter 3 The End</h2>", "<p>The end.</p>"},
+	}
+	for i, wants := range checks {
+		data, _ := book.ReadFile(spine[i])
+		for _, w := range wants {
+			if !strings.Contains(string(data), w) {
+				t.Errorf("chapter %d missing %q:\n%s", i, w, data)
+			}
+		}
+	}
+	if data, _ := book.ReadFile(spine[0]); strings.Contains(string(data), "书名") || strings.Contains(string(data), "作者") {
+		t.Errorf("preface keeps the header lines:\n%s", data)
+	}
+}
+
+func TestImportTextDataWithoutHeadings(t *testing.T) {
+	book, err := ImportTextData("三体 - 刘慈欣.txt", []byte("只有正文。\n第二段。"), TextImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportTextData() error = %v", err)
+	}
+	if info := book.Info(); info.Title != "三体" || len(info.Creators) != 1 || info.Creators[0] != "刘慈欣" {
+		t.Errorf("Info() title = %q, creators = %q, want them parsed from the file name", info.Title, info.Creators)
+	}
+	toc, err := book.TOC()
+	if err != nil {
+		t.Fatalf("TOC() error = %v", err)
+	}
+	if got := formatTOC(toc); got != "三体(1)" {
+		t.Errorf("TOC() = %s, want a single chapter named after the book", got)
+	}
+}
+
+// formatTOC 将目录格式化为 "标题(层级)[子目录]" 的形式便于比较
+func formatTOC(entries []*TOCEntry) string {
+	var parts []string
+	for _, e := range entries {
+		part := fmt.Sprintf("%s(%d)", e.Title, e.Level)
+		if len(e.Children) > 0 {
+			part += "[" + formatTOC(e.Children) + "]"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
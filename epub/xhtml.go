@@ -0,0 +1,405 @@
+package epub
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	xhtmlNamespace = "http://www.w3.org/1999/xhtml"
+	opsNamespace   = "http://www.idpf.org/2007/ops"
+	svgNamespace   = "http://www.w3.org/2000/svg"
+	mathNamespace  = "http://www.w3.org/1998/Math/MathML"
+	xlinkNamespace = "http://www.w3.org/1999/xlink"
+
+	xhtml11Doctype = `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.1//EN" "http://www.w3.org/TR/xhtml11/DTD/xhtml11.dtd">`
+)
+
+// ChapterOptions 控制 AddChapterWithOptions 的行为
+type ChapterOptions struct {
+	// SpineIndex 为插入到 OPF spine 的位置（-1 表示追加）
+	SpineIndex int
+	// Title 为章节 <title>，为空时使用第一个标题或文件名
+	Title string
+	// Raw 为 true 时原样写入内容，不做 XHTML 规范化
+	Raw bool
+}
+
+// AddChapterWithOptions 新增章节，默认将内容规范化为格式良好的 XHTML
+func (p *Epub) AddChapterWithOptions(filePath, htmlContent string, opts ChapterOptions) error {
+	if filePath == "" {
+		return fmt.Errorf("chapter path cannot be empty")
+	}
+	if !opts.Raw {
+		converted, err := ToXHTML(htmlContent, XHTMLOptions{
+			Title:         opts.Title,
+			FallbackTitle: strings.TrimSuffix(path.Base(normalizeZipPath(filePath)), path.Ext(filePath)),
+			Doctype:       p.defaultDoctype(),
+		})
+		if err != nil {
+			return err
+		}
+		htmlContent = converted
+	}
+	return p.addChapter(filePath, htmlContent, opts.SpineIndex)
+}
+
+// RepairXHTML 检查所有 HTML 章节，将不是格式良好 XHTML 的章节重新序列化，返回被修复的路径
+func (p *Epub) RepairXHTML() ([]string, error) {
+	var repaired []string
+	for _, entry := range p.htmlEntriesInOrder() {
+		if IsWellFormedXHTML(entry.data) {
+			continue
+		}
+		converted, err := ToXHTML(string(entry.data), XHTMLOptions{
+			FallbackTitle: strings.TrimSuffix(path.Base(entry.header.Name), path.Ext(entry.header.Name)),
+			Doctype:       p.defaultDoctype(),
+		})
+		if err != nil {
+			return repaired, fmt.Errorf("failed to repair XHTML (%s): %w", entry.header.Name, err)
+		}
+		entry.data = []byte(converted)
+		repaired = append(repaired, entry.header.Name)
+	}
+	return repaired, nil
+}
+
+// defaultDoctype EPUB2 使用 XHTML 1.1 的 DOCTYPE，EPUB3 使用 HTML5 的 DOCTYPE
+func (p *Epub) defaultDoctype() string {
+	if p.opfDoc != nil && strings.HasPrefix(p.opfDoc.Version, "2") {
+		return xhtml11Doctype
+	}
+	return "<!DOCTYPE html>"
+}
+
+// XHTMLOptions 控制 ToXHTML 的输出
+type XHTMLOptions struct {
+	// Title 在文档缺少 <title> 时使用；为空时使用第一个标题
+	Title string
+	// FallbackTitle 在 Title 为空且文档中没有标题时使用
+	FallbackTitle string
+	// Doctype 在原文档没有 DOCTYPE 时使用，默认 <!DOCTYPE html>
+	Doctype string
+}
+
+// ToXHTML 使用 HTML5 解析器解析任意 HTML，输出格式良好的 XHTML：
+// 带 XML 声明与 DOCTYPE、html 元素带 XHTML 命名空间、head 中有 <title>，
+// 空元素自闭合；&nbsp; 等命名实体被解码为字符，不可见的空白类字符输出为数字实体
+func ToXHTML(htmlContent string, opts XHTMLOptions) (string, error) {
+	doc, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	root := findElement(doc, atom.Html)
+	if root == nil {
+		return "", fmt.Errorf("html element not found")
+	}
+	ensureTitle(root, opts.Title, opts.FallbackTitle)
+	return renderXHTMLDocument(doc, opts.Doctype)
+}
+
+// renderXHTMLDocument 序列化整个文档；原文档没有 DOCTYPE 时使用 doctype（为空时为 <!DOCTYPE html>）
+func renderXHTMLDocument(doc *html.Node, doctype string) (string, error) {
+	if doctype == "" {
+		doctype = "<!DOCTYPE html>"
+	}
+	for c := doc.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.DoctypeNode {
+			doctype = renderDoctype(c)
+			break
+		}
+	}
+
+	root := findElement(doc, atom.Html)
+	if root == nil {
+		return "", fmt.Errorf("html element not found")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	buf.WriteString(doctype + "\n")
+	if err := renderXHTML(&buf, root); err != nil {
+		return "", err
+	}
+	buf.WriteString("\n")
+	return buf.String(), nil
+}
+
+// IsWellFormedXHTML 判断内容是否为格式良好的 XML，且根元素为 XHTML 命名空间下的 html
+func IsWellFormedXHTML(data []byte) bool {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = true
+	decoder.CharsetReader = xmlCharsetReader
+	rootChecked := false
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return rootChecked
+		}
+		if err != nil {
+			return false
+		}
+		if start, ok := tok.(xml.StartElement); ok && !rootChecked {
+			if start.Name.Local != "html" || start.Name.Space != xhtmlNamespace {
+				return false
+			}
+			rootChecked = true
+		}
+	}
+}
+
+// ensureTitle 确保 head 存在且包含非空的 <title>，依次使用 title、第一个标题与 fallback
+func ensureTitle(root *html.Node, title, fallback string) {
+	head := findElement(root, atom.Head)
+	if head == nil {
+		head = &html.Node{Type: html.ElementNode, Data: "head", DataAtom: atom.Head}
+		root.InsertBefore(head, root.FirstChild)
+	}
+	titleNode := findElement(head, atom.Title)
+	if titleNode != nil && titleText(titleNode) != "" {
+		return
+	}
+
+	if title == "" {
+		title = chapterTitle(root)
+	}
+	if title == "" {
+		title = fallback
+	}
+	if titleNode == nil {
+		titleNode = &html.Node{Type: html.ElementNode, Data: "title", DataAtom: atom.Title}
+		head.InsertBefore(titleNode, head.FirstChild)
+	}
+	for c := titleNode.FirstChild; c != nil; c = titleNode.FirstChild {
+		titleNode.RemoveChild(c)
+	}
+	titleNode.AppendChild(&html.Node{Type: html.TextNode, Data: title})
+}
+
+// titleText 返回 <title> 的文本；nodeText 会跳过 title 元素，因此单独读取
+func titleText(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func renderDoctype(n *html.Node) string {
+	var public, system string
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "public":
+			public = attr.Val
+		case "system":
+			system = attr.Val
+		}
+	}
+	switch {
+	case public != "":
+		return fmt.Sprintf(`<!DOCTYPE %s PUBLIC "%s" "%s">`, n.Data, public, system)
+	case system != "":
+		return fmt.Sprintf(`<!DOCTYPE %s SYSTEM "%s">`, n.Data, system)
+	}
+	return "<!DOCTYPE " + n.Data + ">"
+}
+
+// renderXHTML 将节点序列化为 XHTML
+func renderXHTML(w *bytes.Buffer, n *html.Node) error {
+	switch n.Type {
+	case html.DocumentNode:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if err := renderXHTML(w, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	case html.DoctypeNode:
+		return nil
+	case html.TextNode:
+		writeEscaped(w, n.Data, false)
+		return nil
+	case html.CommentNode:
+		w.WriteString("<!--")
+		w.WriteString(strings.ReplaceAll(n.Data, "--", "- -"))
+		w.WriteString("-->")
+		return nil
+	case html.RawNode:
+		w.WriteString(n.Data)
+		return nil
+	case html.ElementNode:
+	default:
+		return fmt.Errorf("unknown node type: %d", n.Type)
+	}
+
+	name := elementName(n)
+	w.WriteString("<" + name)
+	for _, attr := range xhtmlAttributes(n) {
+		w.WriteString(" " + attr.Key + `="`)
+		writeEscaped(w, attr.Val, true)
+		w.WriteString(`"`)
+	}
+
+	if n.FirstChild == nil && (n.Namespace != "" || isVoidElement(n.DataAtom)) {
+		w.WriteString("/>")
+		return nil
+	}
+	if n.FirstChild == nil {
+		w.WriteString("></" + name + ">")
+		return nil
+	}
+	w.WriteString(">")
+
+	if n.Namespace == "" && (n.DataAtom == atom.Script || n.DataAtom == atom.Style) {
+		var raw strings.Builder
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			raw.WriteString(c.Data)
+		}
+		text := raw.String()
+		if strings.ContainsAny(text, "<&") {
+			text = "/*<![CDATA[*/" + strings.ReplaceAll(text, "]]>", "]]]]><![CDATA[>") + "/*]]>*/"
+		}
+		w.WriteString(text)
+	} else {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if err := renderXHTML(w, c); err != nil {
+				return err
+			}
+		}
+	}
+	w.WriteString("</" + name + ">")
+	return nil
+}
+
+func elementName(n *html.Node) string {
+	if n.DataAtom != 0 && n.Namespace == "" {
+		return n.DataAtom.String()
+	}
+	return n.Data
+}
+
+// xhtmlAttributes 返回需要输出的属性：补充命名空间声明、还原带前缀的属性名、去掉非法与重复属性
+func xhtmlAttributes(n *html.Node) []html.Attribute {
+	var attrs []html.Attribute
+	seen := make(map[string]bool)
+	add := func(key, val string) {
+		if key == "" || seen[key] || !isValidXMLName(key) {
+			return
+		}
+		seen[key] = true
+		attrs = append(attrs, html.Attribute{Key: key, Val: val})
+	}
+
+	switch {
+	case n.Namespace == "" && n.DataAtom == atom.Html:
+		add("xmlns", xhtmlNamespace)
+		if usesPrefix(n, "epub") {
+			add("xmlns:epub", opsNamespace)
+		}
+	case n.Namespace == "svg" && n.Data == "svg":
+		add("xmlns", svgNamespace)
+		if usesPrefix(n, "xlink") {
+			add("xmlns:xlink", xlinkNamespace)
+		}
+	case n.Namespace == "math" && n.Data == "math":
+		add("xmlns", mathNamespace)
+	}
+
+	for _, attr := range n.Attr {
+		key := attr.Key
+		if attr.Namespace != "" {
+			key = attr.Namespace + ":" + attr.Key
+		}
+		if key == "xmlns" {
+			continue
+		}
+		add(key, attr.Val)
+	}
+	return attrs
+}
+
+// usesPrefix 判断子树中是否有使用指定前缀的属性
+func usesPrefix(n *html.Node, prefix string) bool {
+	for _, attr := range n.Attr {
+		if attr.Namespace == prefix || strings.HasPrefix(attr.Key, prefix+":") {
+			return true
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if usesPrefix(c, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidXMLName(name string) bool {
+	for i, r := range name {
+		switch {
+		case r == '_' || r == ':' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r > 0x7F:
+		case i > 0 && (r == '-' || r == '.' || (r >= '0' && r <= '9')):
+		default:
+			return false
+		}
+	}
+	return name != "" && strings.Count(name, ":") <= 1
+}
+
+// writeEscaped 转义 XML 特殊字符；不换行空格等不可见字符输出为数字实体
+func writeEscaped(w *bytes.Buffer, s string, attr bool) {
+	for _, r := range s {
+		switch r {
+		case '&':
+			w.WriteString("&amp;")
+		case '<':
+			w.WriteString("&lt;")
+		case '>':
+			w.WriteString("&gt;")
+		case '"':
+			if attr {
+				w.WriteString("&quot;")
+			} else {
+				w.WriteRune(r)
+			}
+		case '\u00a0', '\u00ad', '\u200b', '\u200c', '\u200d', '\u2028', '\u2029':
+			fmt.Fprintf(w, "&#%d;", r)
+		default:
+			if r < 0x20 && r != '\n' && r != '\r' && r != '\t' {
+				// XML 1.0 不允许的控制字符
+				continue
+			}
+			w.WriteRune(r)
+		}
+	}
+}
+
+func isVoidElement(a atom.Atom) bool {
+	switch a {
+	case atom.Area, atom.Base, atom.Br, atom.Col, atom.Embed, atom.Hr, atom.Img, atom.Input,
+		atom.Link, atom.Meta, atom.Param, atom.Source, atom.Track, atom.Wbr:
+		return true
+	}
+	return false
+}
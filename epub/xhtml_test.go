@@ -0,0 +1,134 @@
+package epub
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestToXHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  XHTMLOptions
+		want  []string
+	}{
+		{
+			name:  "tag soup",
+			input: "<p>one<p>two<br>&nbsp;&amp;\x01",
+			opts:  XHTMLOptions{FallbackTitle: "ch1"},
+			want: []string{`<?xml version="1.0" encoding="utf-8"?>` + "\n<!DOCTYPE html>\n",
+				`<html xmlns="http://www.w3.org/1999/xhtml">`, `<title>ch1</title>`,
+				`<p>one</p><p>two<br/>&#160;&amp;</p>`},
+		},
+		{
+			name:  "title from heading",
+			input: "<html><head><title> </title></head><body><h2>Heading <b>Two</b></h2></body></html>",
+			want:  []string{`<title>Heading Two</title>`},
+		},
+		{
+			name:  "title option",
+			input: "<h1>Heading</h1>",
+			opts:  XHTMLOptions{Title: "Chosen"},
+			want:  []string{`<title>Chosen</title>`},
+		},
+		{
+			name:  "existing title kept",
+			input: "<title>Kept</title><h1>Heading</h1>",
+			opts:  XHTMLOptions{Title: "Chosen"},
+			want:  []string{`<title>Kept</title>`},
+		},
+		{
+			name:  "doctype",
+			input: "<p>x</p>",
+			opts:  XHTMLOptions{Doctype: xhtml11Doctype},
+			want:  []string{"\n" + xhtml11Doctype + "\n"},
+		},
+		{
+			name:  "epub namespace",
+			input: `<a epub:type="noteref" href="#n1">1</a>`,
+			want:  []string{`xmlns:epub="http://www.idpf.org/2007/ops"`, `<a epub:type="noteref" href="#n1">1</a>`},
+		},
+		{
+			name:  "svg",
+			input: `<svg viewBox="0 0 1 1"><image xlink:href="a.png"/></svg><math><mi>x</mi></math>`,
+			want: []string{`<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" viewBox="0 0 1 1">`,
+				`<image xlink:href="a.png"/>`, `<math xmlns="http://www.w3.org/1998/Math/MathML">`},
+		},
+		{
+			name:  "invalid attributes",
+			input: `<p a"b="1" class="x" class="y">x</p>`,
+			want:  []string{`<p class="x">x</p>`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToXHTML(tt.input, tt.opts)
+			if err != nil {
+				t.Fatalf("ToXHTML() error = %v", err)
+			}
+			if !IsWellFormedXHTML([]byte(got)) {
+				t.Errorf("ToXHTML() is not well-formed XHTML:\n%s", got)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("ToXHTML() missing %q:\n%s", w, got)
+				}
+			}
+		})
+	}
+}
+
+func TestIsWellFormedXHTML(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{testChapter, true},
+		{`<html xmlns="http://www.w3.org/1999/xhtml"><body><p>x<br/></p></body></html>`, true},
+		{`<html><body></body></html>`, false},
+		{`<html xmlns="http://www.w3.org/1999/xhtml"><body><p>x<br></p></body></html>`, false},
+		{`<html xmlns="http://www.w3.org/1999/xhtml"><body>&nbsp;</body></html>`, false},
+		{`<svg xmlns="http://www.w3.org/2000/svg"/>`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		if got := IsWellFormedXHTML([]byte(tt.input)); got != tt.want {
+			t.Errorf("IsWellFormedXHTML(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestAddChapterXHTML(t *testing.T) {
+	opf := strings.Replace(testOPF, `version="3.0"`, `version="2.0"`, 1)
+	book, err := Open(writeTestEPUB(t, opf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := book.AddChapter("OEBPS/Text/soup.xhtml", "<h1>Soup</h1><p>a<br>b", -1); err != nil {
+		t.Fatalf("AddChapter() error = %v", err)
+	}
+	data, _ := book.ReadFile("OEBPS/Text/soup.xhtml")
+	if !IsWellFormedXHTML(data) || !strings.Contains(string(data), xhtml11Doctype) || !strings.Contains(string(data), "<title>Soup</title>") {
+		t.Errorf("AddChapter() stored:\n%s\nwant well-formed XHTML with the EPUB2 doctype and a title", data)
+	}
+
+	raw := "<p>raw<br>"
+	if err := book.AddChapterWithOptions("OEBPS/Text/raw.xhtml", raw, ChapterOptions{SpineIndex: -1, Raw: true}); err != nil {
+		t.Fatalf("AddChapterWithOptions(Raw) error = %v", err)
+	}
+	if data, _ := book.ReadFile("OEBPS/Text/raw.xhtml"); string(data) != raw {
+		t.Errorf("raw chapter = %q, want it stored unchanged", data)
+	}
+
+	repaired, err := book.RepairXHTML()
+	if err != nil || !slices.Equal(repaired, []string{"OEBPS/Text/raw.xhtml"}) {
+		t.Fatalf("RepairXHTML() = %q, %v, want only the raw chapter", repaired, err)
+	}
+	if data, _ := book.ReadFile("OEBPS/Text/raw.xhtml"); !IsWellFormedXHTML(data) || !strings.Contains(string(data), "<title>raw</title>") {
+		t.Errorf("repaired chapter:\n%s\nwant well-formed XHTML titled after the file", data)
+	}
+	if repaired, err := book.RepairXHTML(); err != nil || len(repaired) != 0 {
+		t.Errorf("second RepairXHTML() = %q, %v, want nothing to repair", repaired, err)
+	}
+}
@@ -0,0 +1,353 @@
+package epub
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// xmlNode 是保留原始前缀、注释与处理指令的轻量 XML 树，用于无损地改写 OPF
+type xmlNode struct {
+	kind     xmlNodeKind
+	name     string // 元素名，保留原始前缀，如 "dc:title"
+	attrs    []xmlAttr
+	children []*xmlNode
+	data     string // 文本、注释、处理指令与 DOCTYPE 的内容
+}
+
+type xmlNodeKind int
+
+const (
+	xmlDocumentNode xmlNodeKind = iota
+	xmlElementNode
+	xmlTextNode
+	xmlCommentNode
+	xmlProcInstNode
+	xmlDirectiveNode
+)
+
+// xmlAttr 是保留原始前缀的属性，如 "xmlns:dc"、"rendition:layout"
+type xmlAttr struct {
+	Name  string
+	Value string
+}
+
+// parseXMLTree 解析 XML 为 xmlNode 树，不做命名空间转换
+func parseXMLTree(data []byte) (*xmlNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = xmlCharsetReader
+	decoder.Strict = false
+
+	doc := &xmlNode{kind: xmlDocumentNode}
+	stack := []*xmlNode{doc}
+	for {
+		tok, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		parent := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			node := &xmlNode{kind: xmlElementNode, name: rawName(t.Name)}
+			for _, attr := range t.Attr {
+				node.attrs = append(node.attrs, xmlAttr{Name: rawName(attr.Name), Value: attr.Value})
+			}
+			parent.children = append(parent.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("unexpected end element </%s>", rawName(t.Name))
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			parent.children = append(parent.children, &xmlNode{kind: xmlTextNode, data: string(t)})
+		case xml.Comment:
+			parent.children = append(parent.children, &xmlNode{kind: xmlCommentNode, data: string(t)})
+		case xml.ProcInst:
+			parent.children = append(parent.children, &xmlNode{kind: xmlProcInstNode, name: t.Target, data: string(t.Inst)})
+		case xml.Directive:
+			parent.children = append(parent.children, &xmlNode{kind: xmlDirectiveNode, data: string(t)})
+		}
+	}
+	if len(stack) != 1 {
+		return nil, fmt.Errorf("unclosed element <%s>", stack[len(stack)-1].name)
+	}
+	return doc, nil
+}
+
+// parseXMLFragment 解析一段可能包含多个根节点的 XML 片段
+func parseXMLFragment(data []byte) ([]*xmlNode, error) {
+	wrapped := make([]byte, 0, len(data)+32)
+	wrapped = append(wrapped, "<fragment>"...)
+	wrapped = append(wrapped, data...)
+	wrapped = append(wrapped, "</fragment>"...)
+	doc, err := parseXMLTree(wrapped)
+	if err != nil {
+		return nil, err
+	}
+	return doc.children[0].children, nil
+}
+
+func rawName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// bytes 序列化节点；XML 声明中的编码统一改写为 UTF-8
+func (n *xmlNode) bytes() []byte {
+	var buf bytes.Buffer
+	n.write(&buf)
+	return buf.Bytes()
+}
+
+// innerBytes 序列化子节点
+func (n *xmlNode) innerBytes() []byte {
+	var buf bytes.Buffer
+	for _, c := range n.children {
+		c.write(&buf)
+	}
+	return buf.Bytes()
+}
+
+func (n *xmlNode) write(buf *bytes.Buffer) {
+	switch n.kind {
+	case xmlDocumentNode:
+		for _, c := range n.children {
+			c.write(buf)
+		}
+	case xmlElementNode:
+		buf.WriteString("<" + n.name)
+		for _, attr := range n.attrs {
+			buf.WriteString(" " + attr.Name + `="`)
+			escapeXMLAttr(buf, attr.Value)
+			buf.WriteString(`"`)
+		}
+		if len(n.children) == 0 {
+			buf.WriteString("/>")
+			return
+		}
+		buf.WriteString(">")
+		for _, c := range n.children {
+			c.write(buf)
+		}
+		buf.WriteString("</" + n.name + ">")
+	case xmlTextNode:
+		escapeXMLText(buf, n.data)
+	case xmlCommentNode:
+		buf.WriteString("<!--" + n.data + "-->")
+	case xmlProcInstNode:
+		data := n.data
+		if n.name == "xml" {
+			data = xmlDeclEncodingRegex.ReplaceAllString("<?xml "+data, "${1}UTF-8${3}")[len("<?xml "):]
+		}
+		buf.WriteString("<?" + n.name)
+		if data != "" {
+			buf.WriteString(" " + data)
+		}
+		buf.WriteString("?>")
+	case xmlDirectiveNode:
+		buf.WriteString("<!" + n.data + ">")
+	}
+}
+
+func escapeXMLText(buf *bytes.Buffer, s string) {
+	for _, r := range s {
+		switch r {
+		case '&':
+			buf.WriteString("&amp;")
+		case '<':
+			buf.WriteString("&lt;")
+		case '>':
+			buf.WriteString("&gt;")
+		case '\r':
+			buf.WriteString("&#xD;")
+		default:
+			buf.WriteRune(r)
+		}
+	}
+}
+
+func escapeXMLAttr(buf *bytes.Buffer, s string) {
+	for _, r := range s {
+		switch r {
+		case '&':
+			buf.WriteString("&amp;")
+		case '<':
+			buf.WriteString("&lt;")
+		case '"':
+			buf.WriteString("&quot;")
+		case '\t':
+			buf.WriteString("&#x9;")
+		case '\n':
+			buf.WriteString("&#xA;")
+		case '\r':
+			buf.WriteString("&#xD;")
+		default:
+			buf.WriteRune(r)
+		}
+	}
+}
+
+// localName 返回去掉前缀的元素名
+func (n *xmlNode) localName() string {
+	if i := strings.IndexByte(n.name, ':'); i >= 0 {
+		return n.name[i+1:]
+	}
+	return n.name
+}
+
+// child 返回第一个本地名匹配的子元素
+func (n *xmlNode) child(local string) *xmlNode {
+	for _, c := range n.children {
+		if c.kind == xmlElementNode && c.localName() == local {
+			return c
+		}
+	}
+	return nil
+}
+
+// root 返回文档的根元素
+func (n *xmlNode) root() *xmlNode {
+	for _, c := range n.children {
+		if c.kind == xmlElementNode {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *xmlNode) attr(name string) (string, bool) {
+	for _, attr := range n.attrs {
+		if attr.Name == name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// setAttr 设置属性，value 为空时删除属性
+func (n *xmlNode) setAttr(name, value string) {
+	for i, attr := range n.attrs {
+		if attr.Name != name {
+			continue
+		}
+		if value == "" {
+			n.attrs = append(n.attrs[:i], n.attrs[i+1:]...)
+		} else {
+			n.attrs[i].Value = value
+		}
+		return
+	}
+	if value != "" {
+		n.attrs = append(n.attrs, xmlAttr{Name: name, Value: value})
+	}
+}
+
+// text 返回元素内的文本内容
+func (n *xmlNode) text() string {
+	var b strings.Builder
+	var walk func(*xmlNode)
+	walk = func(n *xmlNode) {
+		if n.kind == xmlTextNode {
+			b.WriteString(n.data)
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+// clone 深拷贝节点
+func (n *xmlNode) clone() *xmlNode {
+	if n == nil {
+		return nil
+	}
+	c := *n
+	c.attrs = append([]xmlAttr(nil), n.attrs...)
+	c.children = make([]*xmlNode, len(n.children))
+	for i, child := range n.children {
+		c.children[i] = child.clone()
+	}
+	return &c
+}
+
+// indentBefore 返回 parent 中第 i 个子节点前的空白文本，用于给新节点复用缩进
+func (n *xmlNode) indentBefore(i int) string {
+	if i > 0 && n.children[i-1].kind == xmlTextNode && strings.TrimSpace(n.children[i-1].data) == "" {
+		return n.children[i-1].data
+	}
+	return ""
+}
+
+// syncElementChildren 将 parent 中本地名为 local 的子元素替换为 nodes：
+// 复用原来的位置（保留注释与空白），多出的节点追加在最后一个同名元素之后并复用缩进，
+// 少了的节点连同其前面的空白一起删除
+func (n *xmlNode) syncElementChildren(local string, nodes []*xmlNode) {
+	var slots []int
+	for i, c := range n.children {
+		if c.kind == xmlElementNode && c.localName() == local {
+			slots = append(slots, i)
+		}
+	}
+
+	indent := "\n"
+	if len(slots) > 0 {
+		if s := n.indentBefore(slots[len(slots)-1]); s != "" {
+			indent = s
+		}
+	} else if len(n.children) > 0 && n.children[0].kind == xmlTextNode && strings.TrimSpace(n.children[0].data) == "" {
+		indent = n.children[0].data
+		if len(n.children) == 1 {
+			// 只有结束标签前的空白时，子元素比结束标签多缩进一级
+			indent += "  "
+		}
+	}
+
+	drop := make(map[int]bool)
+	for k, i := range slots {
+		if k < len(nodes) {
+			n.children[i] = nodes[k]
+			continue
+		}
+		drop[i] = true
+		if n.indentBefore(i) != "" {
+			drop[i-1] = true
+		}
+	}
+
+	var extra []*xmlNode
+	for k := len(slots); k < len(nodes); k++ {
+		extra = append(extra, &xmlNode{kind: xmlTextNode, data: indent}, nodes[k])
+	}
+
+	insertAt := len(n.children)
+	if len(slots) > 0 {
+		insertAt = slots[len(slots)-1] + 1
+	} else if last := len(n.children) - 1; last >= 0 && n.children[last].kind == xmlTextNode && strings.TrimSpace(n.children[last].data) == "" {
+		// 插入到结束标签前的空白之前
+		insertAt = last
+	}
+
+	children := make([]*xmlNode, 0, len(n.children)+len(extra))
+	for i, c := range n.children {
+		if i == insertAt {
+			children = append(children, extra...)
+		}
+		if !drop[i] {
+			children = append(children, c)
+		}
+	}
+	if insertAt == len(n.children) {
+		children = append(children, extra...)
+	}
+	n.children = children
+}
@@ -0,0 +1,412 @@
+package epubIndex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bytedance/sonic"
+	"go.etcd.io/bbolt"
+
+	"github.com/weiweimhy/go-utils/customUtils"
+	"github.com/weiweimhy/go-utils/epub"
+	"github.com/weiweimhy/go-utils/localDB"
+)
+
+const (
+	bucketBooks    = "epub_index_books"
+	bucketChapters = "epub_index_chapters"
+	bucketTerms    = "epub_index_terms"
+
+	keySep        = "\x00"
+	snippetRadius = 40
+
+	// indexVersion 为索引格式版本，分词方式变化时递增，旧版本的书籍会重新建立索引
+	indexVersion = 2
+)
+
+// Indexer 基于 localDB 的 EPUB 全文索引
+//
+// 存储结构：
+//   - epub_index_books:    书籍路径 -> bookRecord
+//   - epub_index_chapters: 书籍路径\x00章节路径 -> chapterRecord
+//   - epub_index_terms:    词\x00书籍路径\x00章节路径 -> 词频（uint32）
+type Indexer struct {
+	db *localDB.LocalDB
+}
+
+// Hit 单条查询结果
+type Hit struct {
+	Book    string  `json:"book"`
+	Title   string  `json:"title"`
+	Chapter string  `json:"chapter"`
+	Heading string  `json:"heading"`
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"`
+}
+
+type bookRecord struct {
+	Path     string   `json:"path"`
+	Hash     string   `json:"hash"`
+	Version  int      `json:"version,omitempty"`
+	Title    string   `json:"title"`
+	Chapters []string `json:"chapters"`
+}
+
+type chapterRecord struct {
+	Book    string   `json:"book"`
+	Chapter string   `json:"chapter"`
+	Heading string   `json:"heading"`
+	Text    string   `json:"text"`
+	Terms   []string `json:"terms"`
+}
+
+// NewIndexer 创建索引器，db 为空时使用 localDB.DB()
+func NewIndexer(db *localDB.LocalDB) (*Indexer, error) {
+	if db == nil {
+		db = localDB.DB()
+	}
+	if db == nil || db.DB == nil {
+		return nil, fmt.Errorf("local db is not initialized")
+	}
+	return &Indexer{db: db}, nil
+}
+
+// IndexFile 为 EPUB 文件建立索引；文件内容哈希未变化时跳过，返回是否重新建立了索引
+func (ix *Indexer) IndexFile(filePath string) (bool, error) {
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return false, fmt.Errorf("failed to read EPUB: %w", err)
+	}
+	hash := customUtils.BytesToHash(data)
+
+	var existing bookRecord
+	if err := ix.db.GetJSON(bucketBooks, abs, &existing); err != nil {
+		return false, err
+	}
+	if existing.Hash == hash && existing.Version == indexVersion {
+		return false, nil
+	}
+
+	book, err := epub.Open(abs)
+	if err != nil {
+		return false, err
+	}
+	if err := ix.IndexBook(abs, hash, book); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// IndexBook 使用指定的 key 为已打开的 Epub 建立索引，会先清除该 key 的旧索引
+func (ix *Indexer) IndexBook(key, hash string, book *epub.Epub) error {
+	if key == "" {
+		return fmt.Errorf("book key cannot be empty")
+	}
+	if book == nil {
+		return fmt.Errorf("book cannot be nil")
+	}
+
+	record := bookRecord{
+		Path:    key,
+		Hash:    hash,
+		Version: indexVersion,
+		Title:   book.Info().Title,
+	}
+
+	var chapters []chapterRecord
+	for _, chapter := range book.ChapterTexts() {
+		if chapter.Text == "" {
+			continue
+		}
+		record.Chapters = append(record.Chapters, chapter.Name)
+		chapters = append(chapters, chapterRecord{
+			Book:    key,
+			Chapter: chapter.Name,
+			Heading: chapter.Title,
+			Text:    chapter.Text,
+		})
+	}
+
+	return ix.db.Update(func(tx *bbolt.Tx) error {
+		if err := removeBook(tx, key); err != nil {
+			return err
+		}
+
+		books, err := tx.CreateBucketIfNotExists([]byte(bucketBooks))
+		if err != nil {
+			return err
+		}
+		chapterBucket, err := tx.CreateBucketIfNotExists([]byte(bucketChapters))
+		if err != nil {
+			return err
+		}
+		terms, err := tx.CreateBucketIfNotExists([]byte(bucketTerms))
+		if err != nil {
+			return err
+		}
+
+		for _, chapter := range chapters {
+			freq := make(map[string]uint32)
+			for _, token := range Tokenize(chapter.Text) {
+				freq[token]++
+			}
+			for term, tf := range freq {
+				chapter.Terms = append(chapter.Terms, term)
+				value := make([]byte, 4)
+				binary.BigEndian.PutUint32(value, tf)
+				if err := terms.Put(termKey(term, key, chapter.Chapter), value); err != nil {
+					return err
+				}
+			}
+			data, err := sonic.Marshal(chapter)
+			if err != nil {
+				return err
+			}
+			if err := chapterBucket.Put(docKey(key, chapter.Chapter), data); err != nil {
+				return err
+			}
+		}
+
+		data, err := sonic.Marshal(record)
+		if err != nil {
+			return err
+		}
+		return books.Put([]byte(key), data)
+	})
+}
+
+// Remove 删除一本书的全部索引
+func (ix *Indexer) Remove(key string) error {
+	return ix.db.Update(func(tx *bbolt.Tx) error {
+		return removeBook(tx, key)
+	})
+}
+
+// Books 返回已建立索引的书籍路径
+func (ix *Indexer) Books() ([]string, error) {
+	var books []string
+	err := ix.db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(bucketBooks))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, _ []byte) error {
+			books = append(books, string(k))
+			return nil
+		})
+	})
+	return books, err
+}
+
+// Search 查询包含 query 的章节，按相关度降序返回最多 limit 条（limit<=0 表示不限制）
+// 所有查询词都需命中；文本中出现完整短语的章节得分加倍
+func (ix *Indexer) Search(query string, limit int) ([]Hit, error) {
+	tokens := uniqueTokens(tokenizeQuery(query))
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("query has no searchable terms")
+	}
+
+	var hits []Hit
+	err := ix.db.View(func(tx *bbolt.Tx) error {
+		terms := tx.Bucket([]byte(bucketTerms))
+		chapters := tx.Bucket([]byte(bucketChapters))
+		books := tx.Bucket([]byte(bucketBooks))
+		if terms == nil || chapters == nil || books == nil {
+			return nil
+		}
+		total := float64(chapters.Stats().KeyN)
+
+		// 逐词求交集，累计 tf-idf 得分
+		var scores map[string]float64
+		for _, token := range tokens {
+			postings := readPostings(terms, token)
+			if len(postings) == 0 {
+				scores = nil
+				break
+			}
+			idf := math.Log(1 + total/float64(len(postings)))
+			next := make(map[string]float64, len(postings))
+			for doc, tf := range postings {
+				if scores != nil {
+					prev, ok := scores[doc]
+					if !ok {
+						continue
+					}
+					next[doc] = prev + (1+math.Log(float64(tf)))*idf
+					continue
+				}
+				next[doc] = (1 + math.Log(float64(tf))) * idf
+			}
+			scores = next
+		}
+
+		normalized := strings.ToLower(strings.TrimSpace(query))
+		titles := make(map[string]string)
+		for doc, score := range scores {
+			var chapter chapterRecord
+			if err := sonic.Unmarshal(chapters.Get([]byte(doc)), &chapter); err != nil {
+				return err
+			}
+			if strings.Contains(strings.ToLower(chapter.Text), normalized) {
+				score *= 2
+			}
+			title, ok := titles[chapter.Book]
+			if !ok {
+				var book bookRecord
+				if data := books.Get([]byte(chapter.Book)); data != nil {
+					_ = sonic.Unmarshal(data, &book)
+				}
+				title = book.Title
+				titles[chapter.Book] = title
+			}
+			hits = append(hits, Hit{
+				Book:    chapter.Book,
+				Title:   title,
+				Chapter: chapter.Chapter,
+				Heading: chapter.Heading,
+				Snippet: snippet(chapter.Text, query, tokens),
+				Score:   score,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hits[i].Book != hits[j].Book {
+			return hits[i].Book < hits[j].Book
+		}
+		return hits[i].Chapter < hits[j].Chapter
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// removeBook 在事务内删除书籍及其章节、倒排记录
+func removeBook(tx *bbolt.Tx, key string) error {
+	books := tx.Bucket([]byte(bucketBooks))
+	if books == nil {
+		return nil
+	}
+	data := books.Get([]byte(key))
+	if data == nil {
+		return nil
+	}
+	var record bookRecord
+	if err := sonic.Unmarshal(data, &record); err != nil {
+		return err
+	}
+
+	chapters := tx.Bucket([]byte(bucketChapters))
+	terms := tx.Bucket([]byte(bucketTerms))
+	for _, name := range record.Chapters {
+		if chapters == nil {
+			break
+		}
+		k := docKey(key, name)
+		if raw := chapters.Get(k); raw != nil && terms != nil {
+			var chapter chapterRecord
+			if err := sonic.Unmarshal(raw, &chapter); err != nil {
+				return err
+			}
+			for _, term := range chapter.Terms {
+				if err := terms.Delete(termKey(term, key, name)); err != nil {
+					return err
+				}
+			}
+		}
+		if err := chapters.Delete(k); err != nil {
+			return err
+		}
+	}
+	return books.Delete([]byte(key))
+}
+
+// readPostings 读取某个词的倒排列表：文档 key -> 词频
+func readPostings(terms *bbolt.Bucket, term string) map[string]uint32 {
+	postings := make(map[string]uint32)
+	prefix := []byte(term + keySep)
+	c := terms.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		if len(v) != 4 {
+			continue
+		}
+		postings[string(k[len(prefix):])] = binary.BigEndian.Uint32(v)
+	}
+	return postings
+}
+
+func docKey(book, chapter string) []byte {
+	return []byte(book + keySep + chapter)
+}
+
+func termKey(term, book, chapter string) []byte {
+	return []byte(term + keySep + book + keySep + chapter)
+}
+
+func uniqueTokens(tokens []string) []string {
+	seen := make(map[string]bool, len(tokens))
+	unique := tokens[:0]
+	for _, token := range tokens {
+		if !seen[token] {
+			seen[token] = true
+			unique = append(unique, token)
+		}
+	}
+	return unique
+}
+
+// snippet 截取命中位置前后的文本，优先定位完整短语，其次定位第一个查询词
+func snippet(text, query string, tokens []string) string {
+	lower := strings.ToLower(text)
+	pos := strings.Index(lower, strings.ToLower(strings.TrimSpace(query)))
+	if pos < 0 {
+		for _, token := range tokens {
+			if pos = strings.Index(lower, token); pos >= 0 {
+				break
+			}
+		}
+	}
+	if pos < 0 {
+		pos = 0
+	}
+
+	// strings.ToLower 可能改变字节长度，这里按 rune 计数换算回原文位置
+	runePos := utf8.RuneCountInString(lower[:pos])
+	runes := []rune(text)
+	start := runePos - snippetRadius
+	if start < 0 {
+		start = 0
+	}
+	end := runePos + snippetRadius
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	s := strings.ReplaceAll(string(runes[start:end]), "\n", " ")
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(runes) {
+		s += "…"
+	}
+	return s
+}
@@ -0,0 +1,171 @@
+package epubIndex
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/weiweimhy/go-utils/epub"
+	"github.com/weiweimhy/go-utils/localDB"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Hello, World 42", []string{"hello", "world", "42"}},
+		{"天下", []string{"天", "天下", "下"}},
+		{"中文abc", []string{"中", "中文", "文", "abc"}},
+		{"字", []string{"字"}},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	if got, want := tokenizeQuery("天下太平"), []string{"天下", "下太", "太平"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tokenizeQuery() = %q, want %q", got, want)
+	}
+}
+
+// newTestIndexer 创建使用临时 bbolt 文件的索引器
+func newTestIndexer(t *testing.T) *Indexer {
+	t.Helper()
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "index.db"), 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	ix, err := NewIndexer(&localDB.LocalDB{DB: db})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ix
+}
+
+// newTestBook 创建包含给定章节正文的书籍，章节依次命名为 c1.xhtml、c2.xhtml…
+func newTestBook(t *testing.T, title string, bodies ...string) *epub.Epub {
+	t.Helper()
+	book, err := epub.New(epub.BookMetadata{Title: title})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, body := range bodies {
+		name := fmt.Sprintf("OEBPS/Text/c%d.xhtml", i+1)
+		if err := book.AddChapter(name, body, -1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return book
+}
+
+func TestSearch(t *testing.T) {
+	ix := newTestIndexer(t)
+	book := newTestBook(t, "Library",
+		"<h1>Dragons</h1><p>The red dragon sleeps under the mountain.</p>",
+		"<h1>Knights</h1><p>A knight rides to the mountain. The dragon is red, the knight is tired.</p>",
+		"<h1>天下</h1><p>天下太平，百姓安居。</p>",
+	)
+	if err := ix.IndexBook("lib", "h1", book); err != nil {
+		t.Fatalf("IndexBook() error = %v", err)
+	}
+
+	hits, err := ix.Search("red dragon", 0)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(hits) != 2 {
+		t.Fatalf("Search() = %d hits, want 2", len(hits))
+	}
+	// 第一章包含完整短语，得分加倍
+	if hits[0].Chapter != "OEBPS/Text/c1.xhtml" || hits[0].Heading != "Dragons" || hits[0].Title != "Library" {
+		t.Errorf("top hit = %+v, want chapter c1 titled Dragons", hits[0])
+	}
+	if !strings.Contains(hits[0].Snippet, "red dragon") {
+		t.Errorf("snippet = %q, want the matched phrase", hits[0].Snippet)
+	}
+	if hits[0].Score <= hits[1].Score {
+		t.Errorf("scores = %v, %v, want phrase match ranked first", hits[0].Score, hits[1].Score)
+	}
+
+	if hits, _ := ix.Search("knight", 0); len(hits) != 1 || hits[0].Chapter != "OEBPS/Text/c2.xhtml" {
+		t.Errorf("Search(knight) = %+v, want only c2", hits)
+	}
+	if hits, _ := ix.Search("dragon unicorn", 0); len(hits) != 0 {
+		t.Errorf("Search() with an unmatched term = %+v, want no hits", hits)
+	}
+	if hits, _ := ix.Search("太平", 0); len(hits) != 1 || hits[0].Chapter != "OEBPS/Text/c3.xhtml" {
+		t.Errorf("Search(太平) = %+v, want c3", hits)
+	}
+	if hits, _ := ix.Search("姓", 0); len(hits) != 1 {
+		t.Errorf("single-character query = %+v, want 1 hit", hits)
+	}
+	if hits, _ := ix.Search("mountain", 1); len(hits) != 1 {
+		t.Errorf("Search() with limit 1 = %d hits", len(hits))
+	}
+	if _, err := ix.Search(" ,. ", 0); err == nil {
+		t.Error("Search() with no searchable terms error = nil")
+	}
+}
+
+func TestIndexBookReplacesAndRemove(t *testing.T) {
+	ix := newTestIndexer(t)
+	if err := ix.IndexBook("a", "h1", newTestBook(t, "A", "<p>alpha beta</p>")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ix.IndexBook("b", "h1", newTestBook(t, "B", "<p>beta gamma</p>")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新索引同一个 key 时旧章节的词不能残留
+	if err := ix.IndexBook("a", "h2", newTestBook(t, "A", "<p>delta</p>")); err != nil {
+		t.Fatal(err)
+	}
+	if hits, _ := ix.Search("alpha", 0); len(hits) != 0 {
+		t.Errorf("stale term still matches after reindex: %+v", hits)
+	}
+	if hits, _ := ix.Search("delta", 0); len(hits) != 1 || hits[0].Book != "a" {
+		t.Errorf("Search(delta) = %+v, want book a", hits)
+	}
+
+	if err := ix.Remove("b"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if hits, _ := ix.Search("gamma", 0); len(hits) != 0 {
+		t.Errorf("removed book still matches: %+v", hits)
+	}
+	books, err := ix.Books()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(books, []string{"a"}) {
+		t.Errorf("Books() = %q, want [a]", books)
+	}
+}
+
+func TestIndexFileSkipsUnchanged(t *testing.T) {
+	ix := newTestIndexer(t)
+	path := filepath.Join(t.TempDir(), "book.epub")
+	book := newTestBook(t, "File", "<p>lighthouse keeper</p>")
+	if err := book.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	indexed, err := ix.IndexFile(path)
+	if err != nil || !indexed {
+		t.Fatalf("first IndexFile() = %v, %v, want true", indexed, err)
+	}
+	indexed, err = ix.IndexFile(path)
+	if err != nil || indexed {
+		t.Fatalf("second IndexFile() = %v, %v, want false for unchanged file", indexed, err)
+	}
+	if hits, _ := ix.Search("lighthouse", 0); len(hits) != 1 {
+		t.Errorf("Search() after IndexFile = %+v, want 1 hit", hits)
+	}
+}
@@ -0,0 +1,72 @@
+package epubIndex
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenize 将文本切分为索引词：
+//   - 拉丁字母与数字按连续片段切分并转为小写
+//   - 中日韩文字按相邻两字组成 bigram，并且每个字单独作为一个词，使单字查询也能命中
+func Tokenize(text string) []string {
+	return tokenize(text, true)
+}
+
+// tokenizeQuery 切分查询词：中日韩文字只取 bigram，只有单独的一个字时才按单字查询
+func tokenizeQuery(text string) []string {
+	return tokenize(text, false)
+}
+
+func tokenize(text string, unigrams bool) []string {
+	var tokens []string
+	var word []rune
+	var cjk []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		switch len(cjk) {
+		case 0:
+		case 1:
+			tokens = append(tokens, string(cjk))
+		default:
+			for i := range cjk {
+				if unigrams {
+					tokens = append(tokens, string(cjk[i]))
+				}
+				if i+1 < len(cjk) {
+					tokens = append(tokens, string(cjk[i:i+2]))
+				}
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
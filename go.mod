@@ -6,12 +6,15 @@ require (
 	github.com/bytedance/sonic v1.11.0
 	github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common v1.3.5
 	github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/ocr v1.3.1
+	github.com/yuin/goldmark v1.7.8
 	go.etcd.io/bbolt v1.4.3
 	go.mongodb.org/mongo-driver/v2 v2.4.0
 	go.uber.org/zap v1.27.1
 	golang.org/x/net v0.35.0
 	golang.org/x/sync v0.11.0
+	golang.org/x/text v0.22.0
 	gopkg.in/natefinch/lumberjack.v2 v2.2.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -29,5 +32,4 @@ require (
 	golang.org/x/arch v0.0.0-20210923205945-b76863e36670 // indirect
 	golang.org/x/crypto v0.33.0 // indirect
 	golang.org/x/sys v0.30.0 // indirect
-	golang.org/x/text v0.22.0 // indirect
 )
@@ -4,7 +4,7 @@ import (
 	"strconv"
 	"time"
 
-	"invoiceClient/pkg/customUtils"
+	"github.com/weiweimhy/go-utils/customUtils"
 	"path/filepath"
 	"sync"
 